@@ -0,0 +1,48 @@
+package homework_delete
+
+import (
+	"database/sql"
+
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/model"
+)
+
+type DBOption func(*DB)
+
+// DB 是 sql.DB 的装饰器，带有元数据和数据库方言
+type DB struct {
+	r       model.Registry
+	db      *sql.DB
+	dialect Dialect
+}
+
+// Open 打开数据库，数据库方言根据 driver 推断，也可以通过 DBWithDialect 指定
+func Open(driver string, dsn string, opts ...DBOption) (*DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]DBOption{DBWithDialect(dialectOf(driver))}, opts...)
+	return OpenDB(db, opts...)
+}
+
+func OpenDB(db *sql.DB, opts ...DBOption) (*DB, error) {
+	res := &DB{
+		r:       model.NewRegistry(),
+		db:      db,
+		dialect: MySQL,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res, nil
+}
+
+func DBWithRegistry(r model.Registry) DBOption {
+	return func(db *DB) {
+		db.r = r
+	}
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
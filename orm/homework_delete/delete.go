@@ -1,22 +1,51 @@
 package homework_delete
 
 import (
+	"context"
+	"database/sql"
 	"reflect"
+
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/internal/errs"
 )
 
+// Deleter 用于构造 DELETE 语句，表名和列名都来自元数据，例如
+//
+//	NewDeleter[User](db).Where(C("Age").LT(18)).Exec(ctx)
 type Deleter[T any] struct {
 	whereBuilder
+	db    *DB
 	table string
 	where []Predicate
+
+	orderBys []OrderBy
+	limit    int
+
+	// returning 为 nil 说明没有调用 Returning
+	returning []Column
+}
+
+var _ Executor = &Deleter[any]{}
+
+func NewDeleter[T any](db *DB) *Deleter[T] {
+	return &Deleter[T]{db: db}
 }
 
 func (d *Deleter[T]) Build() (*Query, error) {
+	if d.db == nil {
+		return nil, errs.ErrNoDB
+	}
+	m, err := d.db.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	d.reset()
+	d.model = m
+	dialect := d.db.dialect
+	d.dialect = dialect
+
 	d.sb.WriteString(`DELETE FROM `)
 	if d.table == "" {
-		var t T
-		d.sb.WriteByte('`')
-		d.sb.WriteString(reflect.TypeOf(t).Name())
-		d.sb.WriteByte('`')
+		d.quote(m.TableName)
 	} else {
 		d.sb.WriteString(d.table)
 	}
@@ -32,6 +61,47 @@ func (d *Deleter[T]) Build() (*Query, error) {
 			return nil, err
 		}
 	}
+
+	if len(d.orderBys) > 0 || d.limit > 0 {
+		if !dialect.deleteOrderLimit() {
+			return nil, errs.NewErrUnsupportedClause(dialect.Name(), "ORDER BY 和 LIMIT")
+		}
+	}
+	if len(d.orderBys) > 0 {
+		d.sb.WriteString(" ORDER BY ")
+		for i, ob := range d.orderBys {
+			if i > 0 {
+				d.sb.WriteByte(',')
+			}
+			if err := d.buildColumn(ob.col); err != nil {
+				return nil, err
+			}
+			d.sb.WriteByte(' ')
+			d.sb.WriteString(ob.order)
+		}
+	}
+	if d.limit > 0 {
+		d.sb.WriteString(" LIMIT ")
+		d.addArg(d.limit)
+	}
+
+	if d.returning != nil {
+		if !dialect.returning() {
+			return nil, errs.NewErrUnsupportedClause(dialect.Name(), "RETURNING")
+		}
+		d.sb.WriteString(" RETURNING ")
+		if len(d.returning) == 0 {
+			d.sb.WriteByte('*')
+		}
+		for i, col := range d.returning {
+			if i > 0 {
+				d.sb.WriteByte(',')
+			}
+			if err := d.buildColumn(col.name); err != nil {
+				return nil, err
+			}
+		}
+	}
 	d.sb.WriteByte(';')
 	return &Query{
 		SQL:  d.sb.String(),
@@ -40,13 +110,84 @@ func (d *Deleter[T]) Build() (*Query, error) {
 }
 
 // From accepts model definition
+// 传入的表名会原样使用，不会加上引号
 func (d *Deleter[T]) From(table string) *Deleter[T] {
 	d.table = table
 	return d
 }
 
 // Where accepts predicates
+// 多次调用的时候条件会用 AND 连接起来
 func (d *Deleter[T]) Where(predicates ...Predicate) *Deleter[T] {
-	d.where = predicates
+	d.where = append(d.where, predicates...)
 	return d
 }
+
+// OrderBy 和 Limit 一起使用，例如只删除最早的 100 条数据
+// 只有 MySQL 支持，其它数据库会在 Build 的时候返回错误
+func (d *Deleter[T]) OrderBy(orderBys ...OrderBy) *Deleter[T] {
+	d.orderBys = orderBys
+	return d
+}
+
+func (d *Deleter[T]) Limit(limit int) *Deleter[T] {
+	d.limit = limit
+	return d
+}
+
+// Returning 返回被删除的数据，cols 为空的时候是 RETURNING *
+// 只有 PostgreSQL 和 SQLite 支持，需要使用 ExecReturning 读取
+func (d *Deleter[T]) Returning(cols ...Column) *Deleter[T] {
+	if cols == nil {
+		cols = []Column{}
+	}
+	d.returning = cols
+	return d
+}
+
+// Exec 执行删除，设置了 Returning 的时候返回的数据会被丢弃
+func (d *Deleter[T]) Exec(ctx context.Context) (sql.Result, error) {
+	q, err := d.Build()
+	if err != nil {
+		return nil, err
+	}
+	return d.db.db.ExecContext(ctx, q.SQL, q.Args...)
+}
+
+// ExecReturning 执行删除，并且返回 RETURNING 的数据，没有返回的列保持零值
+func (d *Deleter[T]) ExecReturning(ctx context.Context) ([]*T, error) {
+	if d.returning == nil {
+		return nil, errs.ErrNoReturning
+	}
+	q, err := d.Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.db.db.QueryContext(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var res []*T
+	for rows.Next() {
+		t := new(T)
+		val := reflect.ValueOf(t).Elem()
+		dst := make([]any, 0, len(cols))
+		for _, col := range cols {
+			fd, ok := d.model.ColumnMap[col]
+			if !ok {
+				return nil, errs.NewErrUnknownColumn(col)
+			}
+			dst = append(dst, val.FieldByName(fd.GoName).Addr().Interface())
+		}
+		if err = rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
@@ -1,12 +1,25 @@
 package homework_delete
 
 import (
+	"context"
+	"database/sql"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/internal/errs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDeleter_Build(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	pg, err := OpenDB(mockDB, DBWithDialect(PostgreSQL))
+	require.NoError(t, err)
+
 	testCases := []struct {
 		name      string
 		builder   QueryBuilder
@@ -15,39 +28,118 @@ func TestDeleter_Build(t *testing.T) {
 	}{
 		{
 			name:    "no where",
-			builder: (&Deleter[TestModel]{}).From("`test_model`"),
+			builder: NewDeleter[TestModel](db),
 			wantQuery: &Query{
 				SQL: "DELETE FROM `test_model`;",
 			},
 		},
 		{
 			name:    "where",
-			builder: (&Deleter[TestModel]{}).Where(C("Id").EQ(16)),
+			builder: NewDeleter[TestModel](db).Where(C("Id").EQ(16)),
 			wantQuery: &Query{
-				SQL:  "DELETE FROM `TestModel` WHERE `Id` = ?;",
+				SQL:  "DELETE FROM `test_model` WHERE `id` = ?;",
 				Args: []any{16},
 			},
 		},
 		{
 			name:    "from",
-			builder: (&Deleter[TestModel]{}).From("`test_model`").Where(C("Id").EQ(16)),
+			builder: NewDeleter[TestModel](db).From("`test_db`.`test_model`").Where(C("Id").EQ(16)),
 			wantQuery: &Query{
-				SQL:  "DELETE FROM `test_model` WHERE `Id` = ?;",
+				SQL:  "DELETE FROM `test_db`.`test_model` WHERE `id` = ?;",
 				Args: []any{16},
 			},
 		},
 		{
 			name: "multi where clauses",
-			builder: (&Deleter[TestModel]{}).
+			builder: NewDeleter[TestModel](db).
 				Where(
-					C("enabled").EQ(true).And(
-						C("sex").EQ("male").
-							Or(C("age").GT(22)))),
+					C("FirstName").EQ("Tom").And(
+						C("LastName").EQ("Jerry").
+							Or(C("Age").GT(22)))),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE (`first_name` = ?) AND ((`last_name` = ?) OR (`age` > ?));",
+				Args: []any{"Tom", "Jerry", 22},
+			},
+		},
+		{
+			name:    "table name and column tag",
+			builder: NewDeleter[CustomModel](db).Where(C("Name").EQ("Tom")),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `custom_table` WHERE `user_name` = ?;",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			// 和 Selector 一样，多次调用 Where 用 AND 连接
+			name:    "where twice",
+			builder: NewDeleter[TestModel](db).Where(C("Id").EQ(16)).Where(C("Age").GT(18)),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE (`id` = ?) AND (`age` > ?);",
+				Args: []any{16, 18},
+			},
+		},
+		{
+			name:    "zero value",
+			builder: &Deleter[TestModel]{},
+			wantErr: errs.ErrNoDB,
+		},
+		{
+			name:    "invalid tag",
+			builder: NewDeleter[InvalidTagModel](db),
+			wantErr: errs.NewErrInvalidTagContent("user_name"),
+		},
+		{
+			// 使用了列名，而不是字段名
+			name:    "unknown field",
+			builder: NewDeleter[TestModel](db).Where(C("first_name").EQ("Tom")),
+			wantErr: errs.NewErrUnknownField("first_name"),
+		},
+		{
+			name:    "order by limit",
+			builder: NewDeleter[TestModel](db).Where(C("Age").LT(18)).OrderBy(Asc("Age"), Desc("Id")).Limit(10),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE `age` < ? ORDER BY `age` ASC,`id` DESC LIMIT ?;",
+				Args: []any{18, 10},
+			},
+		},
+		{
+			name:    "order by unknown field",
+			builder: NewDeleter[TestModel](db).OrderBy(Asc("age")),
+			wantErr: errs.NewErrUnknownField("age"),
+		},
+		{
+			name:    "postgres where",
+			builder: NewDeleter[TestModel](pg).Where(C("FirstName").EQ("Tom"), C("Age").GT(18)),
+			wantQuery: &Query{
+				SQL:  `DELETE FROM "test_model" WHERE ("first_name" = $1) AND ("age" > $2);`,
+				Args: []any{"Tom", 18},
+			},
+		},
+		{
+			name:    "postgres limit",
+			builder: NewDeleter[TestModel](pg).Limit(10),
+			wantErr: errs.NewErrUnsupportedClause("postgres", "ORDER BY 和 LIMIT"),
+		},
+		{
+			name:    "returning",
+			builder: NewDeleter[TestModel](pg).Where(C("Id").EQ(1)).Returning(C("Id"), C("FirstName")),
+			wantQuery: &Query{
+				SQL:  `DELETE FROM "test_model" WHERE "id" = $1 RETURNING "id","first_name";`,
+				Args: []any{1},
+			},
+		},
+		{
+			name:    "returning all",
+			builder: NewDeleter[TestModel](pg).Returning(),
 			wantQuery: &Query{
-				SQL:  "DELETE FROM `TestModel` WHERE (`enabled` = ?) AND ((`sex` = ?) OR (`age` > ?));",
-				Args: []any{true, "male", 22},
+				SQL: `DELETE FROM "test_model" RETURNING *;`,
 			},
 		},
+		{
+			name:    "mysql returning",
+			builder: NewDeleter[TestModel](db).Returning(),
+			wantErr: errs.NewErrUnsupportedClause("mysql", "RETURNING"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -62,3 +154,50 @@ func TestDeleter_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleter_Exec(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open("sqlite3", "file:delete.db?cache=shared&mode=memory")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS test_model(
+    id INTEGER PRIMARY KEY,
+    first_name TEXT NOT NULL,
+    age INTEGER,
+    last_name TEXT
+)`)
+	require.NoError(t, err)
+	_, err = db.db.ExecContext(ctx, "INSERT INTO `test_model`(`id`, `first_name`, `age`, `last_name`) "+
+		"VALUES (1, 'Tom', 18, 'Jerry'), (2, 'Tom', 20, NULL), (3, 'Spike', 30, NULL)")
+	require.NoError(t, err)
+
+	res, err := NewDeleter[TestModel](db).Where(C("Id").EQ(3)).Exec(ctx)
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	_, err = NewDeleter[TestModel](db).ExecReturning(ctx)
+	assert.Equal(t, errs.ErrNoReturning, err)
+
+	deleted, err := NewDeleter[TestModel](db).Where(C("FirstName").EQ("Tom")).Returning().ExecReturning(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*TestModel{
+		{Id: 1, FirstName: "Tom", Age: 18, LastName: &sql.NullString{String: "Jerry", Valid: true}},
+		{Id: 2, FirstName: "Tom", Age: 20},
+	}, deleted)
+}
+
+type CustomModel struct {
+	Id   int64
+	Name string `orm:"column=user_name"`
+}
+
+func (CustomModel) TableName() string {
+	return "custom_table"
+}
+
+type InvalidTagModel struct {
+	Name string `orm:"user_name"`
+}
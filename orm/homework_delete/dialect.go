@@ -0,0 +1,112 @@
+package homework_delete
+
+import "strconv"
+
+// Dialect 数据库方言，影响标识符的引号、参数的占位符和 DELETE 支持哪些子句
+type Dialect interface {
+	Name() string
+	// quote 标识符的引号
+	quote() byte
+	// placeholder 第 n 个参数的占位符，n 从 1 开始
+	placeholder(n int) string
+	// deleteOrderLimit DELETE 是否支持 ORDER BY 和 LIMIT
+	deleteOrderLimit() bool
+	// returning 是否支持 RETURNING
+	returning() bool
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	SQLite     Dialect = sqliteDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+)
+
+// DBWithDialect 设置数据库方言
+// Open 会根据驱动的名字推断，OpenDB 默认是 MySQL
+func DBWithDialect(d Dialect) DBOption {
+	return func(db *DB) {
+		db.dialect = d
+	}
+}
+
+func dialectOf(driver string) Dialect {
+	switch driver {
+	case "sqlite3", "sqlite":
+		return SQLite
+	case "postgres", "pgx":
+		return PostgreSQL
+	default:
+		return MySQL
+	}
+}
+
+// mysqlDialect MySQL 不支持 RETURNING，MariaDB 10.0 开始支持，但是这里不区分
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) quote() byte {
+	return '`'
+}
+
+func (mysqlDialect) placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) deleteOrderLimit() bool {
+	return true
+}
+
+func (mysqlDialect) returning() bool {
+	return false
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+// quote PostgreSQL 使用标准的双引号
+func (postgresDialect) quote() byte {
+	return '"'
+}
+
+func (postgresDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) deleteOrderLimit() bool {
+	return false
+}
+
+func (postgresDialect) returning() bool {
+	return true
+}
+
+// sqliteDialect RETURNING 需要 3.35.0
+// DELETE 的 ORDER BY 和 LIMIT 需要编译的时候开启 SQLITE_ENABLE_UPDATE_DELETE_LIMIT，默认是没有的
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+// quote SQLite 兼容 MySQL 的反引号
+func (sqliteDialect) quote() byte {
+	return '`'
+}
+
+func (sqliteDialect) placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) deleteOrderLimit() bool {
+	return false
+}
+
+func (sqliteDialect) returning() bool {
+	return true
+}
@@ -0,0 +1,44 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPointerOnly 只支持一级指针作为输入
+// 看到这个 error 说明你输入了其它的东西
+// 我们并不希望用户能够直接使用 err == ErrPointerOnly
+// 所以放在我们的 internal 包里
+var ErrPointerOnly = errors.New("orm: 只支持一级指针作为输入，例如 *User")
+
+// ErrNoDB Deleter 需要通过 NewDeleter 创建，零值没有元数据和方言
+var ErrNoDB = errors.New("orm: 没有设置 DB，请使用 NewDeleter 创建 Deleter")
+
+// ErrNoReturning 只有设置了 Returning 才能读取被删除的数据
+var ErrNoReturning = errors.New("orm: 没有设置 RETURNING")
+
+// NewErrUnknownField 返回代表未知字段的错误
+// 一般意味着你可能输入的是列名，或者输入了错误的字段名
+func NewErrUnknownField(fd string) error {
+	return fmt.Errorf("orm: 未知字段 %s", fd)
+}
+
+// NewErrUnknownColumn 返回代表未知列的错误，一般是 RETURNING 返回了模型里面没有的列
+func NewErrUnknownColumn(col string) error {
+	return fmt.Errorf("orm: 未知列 %s", col)
+}
+
+// NewErrInvalidTagContent 标签的格式不对，应该是 orm:"key1=value1,key2=value2"
+func NewErrInvalidTagContent(pair string) error {
+	return fmt.Errorf("orm: 错误的标签设置: %s", pair)
+}
+
+func NewErrUnsupportedExpression(exp any) error {
+	return fmt.Errorf("orm: 不支持的表达式 %v", exp)
+}
+
+// NewErrUnsupportedClause 数据库方言不支持该子句
+// 例如 PostgreSQL 和 SQLite 的 DELETE 不支持 ORDER BY 和 LIMIT，MySQL 不支持 RETURNING
+func NewErrUnsupportedClause(dialect string, clause string) error {
+	return fmt.Errorf("orm: %s 的 DELETE 不支持 %s", dialect, clause)
+}
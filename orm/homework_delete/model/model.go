@@ -0,0 +1,29 @@
+package model
+
+import "reflect"
+
+// Model 元数据，Deleter 只需要表名和字段、列名的映射
+// 和 howework_select 的 model 分开，作业之间互不依赖
+type Model struct {
+	// TableName 结构体对应的表名
+	TableName string
+	FieldMap  map[string]*Field
+	ColumnMap map[string]*Field
+}
+
+// Field 字段
+type Field struct {
+	ColName string
+	GoName  string
+	Type    reflect.Type
+}
+
+// 我们支持的全部标签上的 key 都放在这里
+const (
+	tagKeyColumn = "column"
+)
+
+// TableName 用户实现这个接口来返回自定义的表名
+type TableName interface {
+	TableName() string
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/internal/errs"
+)
+
+// Registry 元数据注册中心的抽象
+type Registry interface {
+	// Get 查找元数据，没有的时候解析并且缓存
+	Get(val any) (*Model, error)
+}
+
+// registry 基于标签和接口的实现
+type registry struct {
+	models sync.Map
+}
+
+func NewRegistry() Registry {
+	return &registry{}
+}
+
+// Get 查找元数据模型
+func (r *registry) Get(val any) (*Model, error) {
+	typ := reflect.TypeOf(val)
+	m, ok := r.models.Load(typ)
+	if ok {
+		return m.(*Model), nil
+	}
+	res, err := r.parseModel(val)
+	if err != nil {
+		return nil, err
+	}
+	r.models.Store(typ, res)
+	return res, nil
+}
+
+// parseModel 支持从标签中提取自定义设置
+// 标签形式 orm:"key1=value1,key2=value2"
+func (r *registry) parseModel(val any) (*Model, error) {
+	typ := reflect.TypeOf(val)
+	if typ == nil || typ.Kind() != reflect.Ptr ||
+		typ.Elem().Kind() != reflect.Struct {
+		return nil, errs.ErrPointerOnly
+	}
+	typ = typ.Elem()
+
+	numField := typ.NumField()
+	fds := make(map[string]*Field, numField)
+	colMap := make(map[string]*Field, numField)
+	for i := 0; i < numField; i++ {
+		fdType := typ.Field(i)
+		tags, err := r.parseTag(fdType.Tag)
+		if err != nil {
+			return nil, err
+		}
+		colName := tags[tagKeyColumn]
+		if colName == "" {
+			colName = underscoreName(fdType.Name)
+		}
+		f := &Field{
+			ColName: colName,
+			GoName:  fdType.Name,
+			Type:    fdType.Type,
+		}
+		fds[fdType.Name] = f
+		colMap[colName] = f
+	}
+
+	var tableName string
+	if tn, ok := val.(TableName); ok {
+		tableName = tn.TableName()
+	}
+	if tableName == "" {
+		tableName = underscoreName(typ.Name())
+	}
+	return &Model{
+		TableName: tableName,
+		FieldMap:  fds,
+		ColumnMap: colMap,
+	}, nil
+}
+
+func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
+	ormTag := tag.Get("orm")
+	if ormTag == "" {
+		// 返回一个空的 map，这样调用者就不需要判断 nil 了
+		return map[string]string{}, nil
+	}
+	// 现在只支持 column 一个 key
+	res := make(map[string]string, 1)
+	pairs := strings.Split(ormTag, ",")
+	for _, pair := range pairs {
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, errs.NewErrInvalidTagContent(pair)
+		}
+		res[kv[0]] = kv[1]
+	}
+	return res, nil
+}
+
+// underscoreName 驼峰转字符串命名
+func underscoreName(name string) string {
+	var buf []byte
+	for i, v := range name {
+		if unicode.IsUpper(v) {
+			if i != 0 {
+				buf = append(buf, '_')
+			}
+			buf = append(buf, byte(unicode.ToLower(v)))
+		} else {
+			buf = append(buf, byte(v))
+		}
+	}
+	return string(buf)
+}
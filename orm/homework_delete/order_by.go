@@ -0,0 +1,15 @@
+package homework_delete
+
+// OrderBy 排序，只能用于 MySQL 的 DELETE
+type OrderBy struct {
+	col   string
+	order string
+}
+
+func Asc(col string) OrderBy {
+	return OrderBy{col: col, order: "ASC"}
+}
+
+func Desc(col string) OrderBy {
+	return OrderBy{col: col, order: "DESC"}
+}
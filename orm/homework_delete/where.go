@@ -1,13 +1,57 @@
 package homework_delete
 
 import (
-	"fmt"
 	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/homework_delete/model"
 )
 
 type whereBuilder struct {
 	sb   strings.Builder
 	args []any
+	// model 为 nil 的时候，列名就是 C 里面的名字，Selector 还是这样
+	model *model.Model
+	// dialect 为 nil 的时候使用反引号和 ?，Selector 还是这样
+	dialect Dialect
+}
+
+func (b *whereBuilder) reset() {
+	b.sb.Reset()
+	b.args = nil
+}
+
+// buildColumn 把字段名转换成列名
+func (b *whereBuilder) buildColumn(name string) error {
+	if b.model != nil {
+		fd, ok := b.model.FieldMap[name]
+		if !ok {
+			return errs.NewErrUnknownField(name)
+		}
+		name = fd.ColName
+	}
+	b.quote(name)
+	return nil
+}
+
+func (b *whereBuilder) quote(name string) {
+	q := byte('`')
+	if b.dialect != nil {
+		q = b.dialect.quote()
+	}
+	b.sb.WriteByte(q)
+	b.sb.WriteString(name)
+	b.sb.WriteByte(q)
+}
+
+// addArg 写入参数的占位符
+func (b *whereBuilder) addArg(val any) {
+	b.args = append(b.args, val)
+	if b.dialect == nil {
+		b.sb.WriteByte('?')
+		return
+	}
+	b.sb.WriteString(b.dialect.placeholder(len(b.args)))
 }
 
 func (b *whereBuilder) buildExpression(e Expression) error {
@@ -16,12 +60,11 @@ func (b *whereBuilder) buildExpression(e Expression) error {
 	}
 	switch exp := e.(type) {
 	case Column:
-		b.sb.WriteByte('`')
-		b.sb.WriteString(exp.name)
-		b.sb.WriteByte('`')
+		if err := b.buildColumn(exp.name); err != nil {
+			return err
+		}
 	case value:
-		b.sb.WriteByte('?')
-		b.args = append(b.args, exp.val)
+		b.addArg(exp.val)
 	case Predicate:
 		_, lp := exp.left.(Predicate)
 		if lp {
@@ -49,7 +92,7 @@ func (b *whereBuilder) buildExpression(e Expression) error {
 			b.sb.WriteByte(')')
 		}
 	default:
-		return fmt.Errorf("orm: unsupported expression %v", exp)
+		return errs.NewErrUnsupportedExpression(exp)
 	}
 	return nil
 }
@@ -2,9 +2,11 @@ package orm
 
 // Aggregate 代表聚合函数，例如 AVG, MAX, MIN 等
 type Aggregate struct {
-	fn    string
-	arg   string
-	alias string
+	fn string
+	// arg 聚合函数的参数，为 nil 的时候代表 *
+	arg      Expression
+	distinct bool
+	alias    string
 }
 
 func (a Aggregate) selectable() {}
@@ -12,11 +14,14 @@ func (a Aggregate) selectable() {}
 func (a Aggregate) expr() {}
 
 func (a Aggregate) As(alias string) Aggregate {
-	return Aggregate{
-		fn:    a.fn,
-		arg:   a.arg,
-		alias: alias,
-	}
+	a.alias = alias
+	return a
+}
+
+// Distinct 例如 Count("Age").Distinct() 代表 COUNT(DISTINCT `age`)
+func (a Aggregate) Distinct() Aggregate {
+	a.distinct = true
+	return a
 }
 
 // EQ 例如 C("id").EQ(12)
@@ -44,38 +49,84 @@ func (a Aggregate) GT(arg any) Predicate {
 	}
 }
 
+// Add 例如 Sum("Price").Add(Sum("Fee"))
+func (a Aggregate) Add(arg any) MathExpr {
+	return newMathExpr(a, opAdd, arg)
+}
+
+func (a Aggregate) Sub(arg any) MathExpr {
+	return newMathExpr(a, opSub, arg)
+}
+
+func (a Aggregate) Mul(arg any) MathExpr {
+	return newMathExpr(a, opMul, arg)
+}
+
+// Div 例如 Sum("Price").Div(Count("Id")) 代表 SUM(`price`) / COUNT(`id`)
+func (a Aggregate) Div(arg any) MathExpr {
+	return newMathExpr(a, opDiv, arg)
+}
 
 func Avg(c string) Aggregate {
+	return AvgOf(C(c))
+}
+
+func Max(c string) Aggregate {
+	return MaxOf(C(c))
+}
+
+func Min(c string) Aggregate {
+	return MinOf(C(c))
+}
+
+func Count(c string) Aggregate {
+	return CountOf(C(c))
+}
+
+// CountAll 代表 COUNT(*)
+func CountAll() Aggregate {
+	return Aggregate{
+		fn: "COUNT",
+	}
+}
+
+func Sum(c string) Aggregate {
+	return SumOf(C(c))
+}
+
+// 下面的 XxxOf 接收任意的表达式，例如 SumOf(C("Price").Mul(C("Qty")))
+
+func AvgOf(e Expression) Aggregate {
 	return Aggregate{
 		fn:  "AVG",
-		arg: c,
+		arg: e,
 	}
 }
 
-func Max(c string) Aggregate {
+func MaxOf(e Expression) Aggregate {
 	return Aggregate{
 		fn:  "MAX",
-		arg: c,
+		arg: e,
 	}
 }
 
-func Min(c string) Aggregate {
+func MinOf(e Expression) Aggregate {
 	return Aggregate{
 		fn:  "MIN",
-		arg: c,
+		arg: e,
 	}
 }
 
-func Count(c string) Aggregate {
+func CountOf(e Expression) Aggregate {
 	return Aggregate{
 		fn:  "COUNT",
-		arg: c,
+		arg: e,
 	}
 }
 
-func Sum(c string) Aggregate {
+func SumOf(e Expression) Aggregate {
 	return Aggregate{
 		fn:  "SUM",
-		arg: c,
+		arg: e,
 	}
-}
\ No newline at end of file
+}
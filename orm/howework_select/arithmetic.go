@@ -0,0 +1,84 @@
+package orm
+
+// MathExpr 代表算术表达式，例如 `price` * `qty`
+// 嵌套的算术表达式会加上括号，所以 C("A").Add(1).Mul(2) 代表 (`a` + ?) * ?
+type MathExpr struct {
+	left  Expression
+	op    op
+	right Expression
+	alias string
+}
+
+func (MathExpr) expr() {}
+
+func (MathExpr) selectable() {}
+
+func newMathExpr(left Expression, o op, right any) MathExpr {
+	return MathExpr{
+		left:  left,
+		op:    o,
+		right: exprOf(right),
+	}
+}
+
+func (m MathExpr) As(alias string) MathExpr {
+	m.alias = alias
+	return m
+}
+
+func (m MathExpr) Add(arg any) MathExpr {
+	return newMathExpr(m, opAdd, arg)
+}
+
+func (m MathExpr) Sub(arg any) MathExpr {
+	return newMathExpr(m, opSub, arg)
+}
+
+func (m MathExpr) Mul(arg any) MathExpr {
+	return newMathExpr(m, opMul, arg)
+}
+
+func (m MathExpr) Div(arg any) MathExpr {
+	return newMathExpr(m, opDiv, arg)
+}
+
+func (m MathExpr) EQ(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opEQ,
+		right: exprOf(arg),
+	}
+}
+
+func (m MathExpr) LT(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opLT,
+		right: exprOf(arg),
+	}
+}
+
+func (m MathExpr) GT(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opGT,
+		right: exprOf(arg),
+	}
+}
+
+// Add 例如 C("Price").Add(C("Fee"))，或者 C("Age").Add(1)
+func (c Column) Add(arg any) MathExpr {
+	return newMathExpr(c, opAdd, arg)
+}
+
+func (c Column) Sub(arg any) MathExpr {
+	return newMathExpr(c, opSub, arg)
+}
+
+func (c Column) Mul(arg any) MathExpr {
+	return newMathExpr(c, opMul, arg)
+}
+
+func (c Column) Div(arg any) MathExpr {
+	return newMathExpr(c, opDiv, arg)
+}
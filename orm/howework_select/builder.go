@@ -0,0 +1,286 @@
+package orm
+
+import (
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// builder 是 Selector 和 Deleter 共享的，构造表达式的部分
+type builder struct {
+	core
+	sb    strings.Builder
+	args  []any
+	model *model.Model
+	// aliases SELECT 里面定义的别名，HAVING 和 ORDER BY 可以通过 C("alias") 引用它们
+	aliases map[string]struct{}
+}
+
+// reset 清空上一次构造的结果，这样 Build 可以重复调用
+func (b *builder) reset() {
+	b.sb.Reset()
+	b.args = nil
+	b.aliases = nil
+}
+
+func (b *builder) buildTable(table string) {
+	if table == "" {
+		b.quote(b.model.TableName)
+		return
+	}
+	b.sb.WriteString(table)
+}
+
+// buildPredicates 把多个 Predicate 用 AND 连接起来
+func (b *builder) buildPredicates(ps []Predicate) error {
+	p := ps[0]
+	for i := 1; i < len(ps); i++ {
+		p = p.And(ps[i])
+	}
+	return b.buildExpression(p)
+}
+
+func (b *builder) buildExpression(e Expression) error {
+	if e == nil {
+		return nil
+	}
+	switch exp := e.(type) {
+	case Column:
+		if err := b.buildColumn(exp); err != nil {
+			return err
+		}
+	case value:
+		b.sb.WriteByte('?')
+		b.args = append(b.args, exp.val)
+	case escapedPattern:
+		b.sb.WriteString("? ESCAPE ")
+		if b.dialect.backslashEscapes() {
+			b.sb.WriteString(`'\\'`)
+		} else {
+			b.sb.WriteString(`'\'`)
+		}
+		b.args = append(b.args, exp.val)
+	case NamedParam, tenantArg:
+		// 先把 NamedParam 和租户本身作为参数占位，执行的时候再替换
+		b.sb.WriteByte('?')
+		b.args = append(b.args, exp)
+	case values:
+		b.sb.WriteByte('(')
+		if len(exp.vals) == 0 {
+			// IN () 是非法的语法，用 NULL 代替，它不会匹配任何数据
+			b.sb.WriteString("NULL")
+		}
+		for i, val := range exp.vals {
+			if i > 0 {
+				b.sb.WriteByte(',')
+			}
+			b.sb.WriteByte('?')
+			b.args = append(b.args, val)
+		}
+		b.sb.WriteByte(')')
+	case Predicate:
+		_, lp := exp.left.(Predicate)
+		if lp {
+			b.sb.WriteByte('(')
+		}
+		if err := b.buildExpression(exp.left); err != nil {
+			return err
+		}
+		if lp {
+			b.sb.WriteByte(')')
+		}
+
+		b.sb.WriteByte(' ')
+		b.sb.WriteString(exp.op.String())
+		b.sb.WriteByte(' ')
+
+		_, rp := exp.right.(Predicate)
+		if rp {
+			b.sb.WriteByte('(')
+		}
+		if err := b.buildExpression(exp.right); err != nil {
+			return err
+		}
+		if rp {
+			b.sb.WriteByte(')')
+		}
+	case RawExpr:
+		b.sb.WriteString(exp.raw)
+		b.args = append(b.args, exp.args...)
+	case Aggregate:
+		if err := b.buildAggregate(exp); err != nil {
+			return err
+		}
+	case MathExpr:
+		if err := b.buildSubExpression(exp.left); err != nil {
+			return err
+		}
+		b.sb.WriteByte(' ')
+		b.sb.WriteString(exp.op.String())
+		b.sb.WriteByte(' ')
+		if err := b.buildSubExpression(exp.right); err != nil {
+			return err
+		}
+	case WindowFunc:
+		b.sb.WriteString(exp.fn)
+		b.sb.WriteByte('(')
+		for i, arg := range exp.args {
+			if i > 0 {
+				b.sb.WriteString(", ")
+			}
+			if err := b.buildExpression(arg); err != nil {
+				return err
+			}
+		}
+		b.sb.WriteByte(')')
+	case OverExpr:
+		if err := b.buildOver(exp); err != nil {
+			return err
+		}
+	case CaseExpr:
+		if err := b.buildCase(exp); err != nil {
+			return err
+		}
+	default:
+		return errs.NewErrUnsupportedExpressionType(exp)
+	}
+	return nil
+}
+
+// buildSubExpression 构造算术表达式的操作数，嵌套的算术表达式加上括号
+func (b *builder) buildSubExpression(e Expression) error {
+	if _, ok := e.(MathExpr); !ok {
+		return b.buildExpression(e)
+	}
+	b.sb.WriteByte('(')
+	if err := b.buildExpression(e); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+// buildColumn 优先把 col 当做字段名，找不到的话再看看是不是 SELECT 里面的别名
+func (b *builder) buildColumn(col Column) error {
+	fd, ok := b.model.FieldMap[col.name]
+	if ok {
+		b.quote(fd.ColName)
+		return nil
+	}
+	if _, ok = b.aliases[col.name]; ok {
+		b.quote(col.name)
+		return nil
+	}
+	return errs.NewErrUnknownField(col.name)
+}
+
+func (b *builder) buildAggregate(exp Aggregate) error {
+	b.sb.WriteString(exp.fn)
+	b.sb.WriteByte('(')
+	if exp.distinct {
+		b.sb.WriteString("DISTINCT ")
+	}
+	if exp.arg == nil {
+		b.sb.WriteByte('*')
+	} else if err := b.buildExpression(exp.arg); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+func (b *builder) buildOver(exp OverExpr) error {
+	if err := b.buildExpression(exp.fn); err != nil {
+		return err
+	}
+	b.sb.WriteString(" OVER (")
+	if len(exp.window.partitionBy) > 0 {
+		b.sb.WriteString("PARTITION BY ")
+		for i, e := range exp.window.partitionBy {
+			if i > 0 {
+				b.sb.WriteByte(',')
+			}
+			if err := b.buildExpression(e); err != nil {
+				return err
+			}
+		}
+	}
+	if len(exp.window.orderBys) > 0 {
+		if len(exp.window.partitionBy) > 0 {
+			b.sb.WriteByte(' ')
+		}
+		if err := b.buildOrderBys(exp.window.orderBys); err != nil {
+			return err
+		}
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+func (b *builder) buildCase(exp CaseExpr) error {
+	b.sb.WriteString("CASE")
+	for _, w := range exp.whens {
+		b.sb.WriteString(" WHEN ")
+		if err := b.buildExpression(w.cond); err != nil {
+			return err
+		}
+		b.sb.WriteString(" THEN ")
+		if err := b.buildExpression(w.then); err != nil {
+			return err
+		}
+	}
+	if exp.els != nil {
+		b.sb.WriteString(" ELSE ")
+		if err := b.buildExpression(exp.els); err != nil {
+			return err
+		}
+	}
+	b.sb.WriteString(" END")
+	return nil
+}
+
+// buildOrderBys 构造 ORDER BY 子句，包括 ORDER BY 关键字
+func (b *builder) buildOrderBys(orderBys []OrderBy) error {
+	b.sb.WriteString("ORDER BY ")
+	for i, ob := range orderBys {
+		if i > 0 {
+			b.sb.WriteByte(',')
+		}
+		if ob.nulls != "" && !b.dialect.nullsOrdering() {
+			// 不支持 NULLS FIRST 的数据库先按照 expr IS NULL 排序，true 比 false 大
+			if err := b.buildSubExpression(ob.expr); err != nil {
+				return err
+			}
+			b.sb.WriteString(" IS NULL ")
+			if ob.nulls == nullsFirst {
+				b.sb.WriteString(orderDesc)
+			} else {
+				b.sb.WriteString(orderAsc)
+			}
+			b.sb.WriteByte(',')
+		}
+		if err := b.buildExpression(ob.expr); err != nil {
+			return err
+		}
+		b.sb.WriteByte(' ')
+		b.sb.WriteString(ob.order)
+		if ob.nulls != "" && b.dialect.nullsOrdering() {
+			b.sb.WriteByte(' ')
+			b.sb.WriteString(ob.nulls)
+		}
+	}
+	return nil
+}
+
+func (b *builder) buildAlias(a string) {
+	b.sb.WriteString(" AS ")
+	b.quote(a)
+}
+
+func (b *builder) quote(name string) {
+	q := b.dialect.quote()
+	b.sb.WriteByte(q)
+	b.sb.WriteString(name)
+	b.sb.WriteByte(q)
+}
@@ -0,0 +1,67 @@
+package orm
+
+// CaseExpr 代表 CASE WHEN ... THEN ... ELSE ... END，例如
+//
+//	SumOf(Case().When(C("Status").EQ(1), 1).Else(0))
+//
+// 对应 SUM(CASE WHEN `status` = ? THEN ? ELSE ? END)，参数的顺序和它们在 SQL 里面出现的顺序一致
+type CaseExpr struct {
+	whens []caseWhen
+	els   Expression
+	alias string
+}
+
+type caseWhen struct {
+	cond Predicate
+	then Expression
+}
+
+func Case() CaseExpr {
+	return CaseExpr{}
+}
+
+func (CaseExpr) expr() {}
+
+func (CaseExpr) selectable() {}
+
+func (c CaseExpr) When(cond Predicate, then any) CaseExpr {
+	// 复制一份，避免多个 CaseExpr 共享底层数组
+	whens := make([]caseWhen, len(c.whens), len(c.whens)+1)
+	copy(whens, c.whens)
+	c.whens = append(whens, caseWhen{cond: cond, then: exprOf(then)})
+	return c
+}
+
+func (c CaseExpr) Else(val any) CaseExpr {
+	c.els = exprOf(val)
+	return c
+}
+
+func (c CaseExpr) As(alias string) CaseExpr {
+	c.alias = alias
+	return c
+}
+
+func (c CaseExpr) EQ(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opEQ,
+		right: exprOf(arg),
+	}
+}
+
+func (c CaseExpr) LT(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLT,
+		right: exprOf(arg),
+	}
+}
+
+func (c CaseExpr) GT(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opGT,
+		right: exprOf(arg),
+	}
+}
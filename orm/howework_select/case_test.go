@@ -0,0 +1,89 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Case(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "conditional aggregate",
+			q: NewSelector[TestModel](db).Select(C("Age"),
+				SumOf(Case().When(C("FirstName").EQ("Tom"), 1).Else(0)).As("toms")).
+				Where(C("Id").GT(10)).GroupBy(C("Age")),
+			wantQuery: &Query{
+				SQL: "SELECT `age`,SUM(CASE WHEN `first_name` = ? THEN ? ELSE ? END) AS `toms` " +
+					"FROM `test_model` WHERE `id` > ? GROUP BY `age`;",
+				Args: []any{"Tom", 1, 0, 10},
+			},
+		},
+		{
+			name: "multiple when",
+			q: NewSelector[TestModel](db).Select(Case().
+				When(C("Age").LT(18), "child").
+				When(C("Age").LT(60), "adult").As("stage")).OrderBy(Asc("stage")),
+			wantQuery: &Query{
+				SQL:  "SELECT CASE WHEN `age` < ? THEN ? WHEN `age` < ? THEN ? END AS `stage` FROM `test_model` ORDER BY `stage` ASC;",
+				Args: []any{18, "child", 60, "adult"},
+			},
+		},
+		{
+			name: "then column",
+			q: NewSelector[TestModel](db).Where(
+				Case().When(C("Age").GT(18), C("Age")).Else(C("Id")).GT(20)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE CASE WHEN `age` > ? THEN `age` ELSE `id` END > ?;",
+				Args: []any{18, 20},
+			},
+		},
+		{
+			name:    "invalid column",
+			q:       NewSelector[TestModel](db).Select(Case().When(C("Invalid").EQ(1), 1)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+// TestSelector_CaseWindowSQLite 在 SQLite 上执行，确认参数的顺序是正确的
+func TestSelector_CaseWindowSQLite(t *testing.T) {
+	db := memoryDBWithDB("case_window", t)
+	ctx := context.Background()
+	_, err := RawQuery[any](db, TestModel{}.CreateSQL()).Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "INSERT INTO `test_model`(`id`, `first_name`, `age`, `last_name`) VALUES (1, 'a', 10, 'x'), (2, 'b', 20, 'y'), (3, 'c', 30, 'z')").Exec(ctx)
+	require.NoError(t, err)
+
+	ms, err := NewSelector[TestModel](db).Select(C("Id"),
+		Case().When(C("Age").GT(15), "old").Else("young").As("first_name")).
+		Where(C("Id").LT(3)).OrderBy(Desc("Id")).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 2, FirstName: "old"}, {Id: 1, FirstName: "young"}}, ms)
+
+	ms, err = NewSelector[TestModel](db).Select(C("Id"),
+		RowNumber().Over(NewWindow().OrderBy(Desc("Age"))).As("age")).
+		OrderBy(Asc("Id")).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 1, Age: 3}, {Id: 2, Age: 2}, {Id: 3, Age: 1}}, ms)
+}
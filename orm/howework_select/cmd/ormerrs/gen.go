@@ -0,0 +1,105 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed errors.tmpl
+var errorsTmpl string
+
+var tpl = template.Must(template.New("errors").Parse(errorsTmpl))
+
+// annotation 形如 @ErrUnsupportedExpressionType 40001
+var annotation = regexp.MustCompile(`^@(\w+)\s+(\d{5})$`)
+
+type errorCode struct {
+	// Code 完整的错误码，例如 orm-40001
+	Code string
+	Name string
+	// Desc 注释里面除了 @ 那一行之外的部分，一行一段
+	Desc []string
+}
+
+// parseDir 解析 dir 下面所有带有 @ 注释的常量，按照错误码排序
+// 注释里面的错误码必须和常量的值一致，避免改了一个忘了另一个
+func parseDir(dir string) ([]errorCode, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var res []errorCode
+	seen := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ec, ok, err := parseSpec(spec.(*ast.ValueSpec))
+					if err != nil {
+						return nil, err
+					}
+					if !ok {
+						continue
+					}
+					if name, ok := seen[ec.Code]; ok {
+						return nil, fmt.Errorf("ormerrs: %s 和 %s 使用了相同的错误码 %s", name, ec.Name, ec.Code)
+					}
+					seen[ec.Code] = ec.Name
+					res = append(res, ec)
+				}
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Code < res[j].Code
+	})
+	return res, nil
+}
+
+func parseSpec(spec *ast.ValueSpec) (errorCode, bool, error) {
+	if spec.Doc == nil {
+		return errorCode{}, false, nil
+	}
+	lines := strings.Split(strings.TrimSpace(spec.Doc.Text()), "\n")
+	if !strings.HasPrefix(lines[0], "@") {
+		return errorCode{}, false, nil
+	}
+	matches := annotation.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if matches == nil {
+		return errorCode{}, false, fmt.Errorf("ormerrs: 错误的注释 %s，格式应该是 @错误名 错误码", lines[0])
+	}
+	ec := errorCode{Code: "orm-" + matches[2], Name: matches[1], Desc: lines[1:]}
+	if len(spec.Values) != 1 {
+		return errorCode{}, false, fmt.Errorf("ormerrs: %s 必须是一个字符串常量", ec.Name)
+	}
+	lit, ok := spec.Values[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return errorCode{}, false, fmt.Errorf("ormerrs: %s 必须是一个字符串常量", ec.Name)
+	}
+	val, _ := strconv.Unquote(lit.Value)
+	if val != ec.Code {
+		return errorCode{}, false, fmt.Errorf("ormerrs: %s 注释里面的错误码 %s 和常量的值 %s 不一致", ec.Name, ec.Code, val)
+	}
+	return ec, true, nil
+}
+
+func generate(w io.Writer, codes []errorCode) error {
+	return tpl.Execute(w, codes)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 确保提交的 ERRORS.md 和错误码保持一致
+func TestGenerate(t *testing.T) {
+	codes, err := parseDir("../../internal/errs")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, generate(&buf, codes))
+	want, err := os.ReadFile("../../ERRORS.md")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+}
+
+func TestParseDir(t *testing.T) {
+	testCases := []struct {
+		name      string
+		dir       string
+		wantCodes []errorCode
+		wantErr   error
+	}{
+		{
+			name:    "mismatch",
+			dir:     "testdata/mismatch",
+			wantErr: errors.New("ormerrs: ErrUnknownField 注释里面的错误码 orm-40003 和常量的值 orm-40004 不一致"),
+		},
+		{
+			name:    "duplicate",
+			dir:     "testdata/duplicate",
+			wantErr: errors.New("ormerrs: ErrUnknownField 和 ErrUnknownColumn 使用了相同的错误码 orm-40003"),
+		},
+		{
+			name:    "invalid",
+			dir:     "testdata/invalid",
+			wantErr: errors.New("ormerrs: 错误的注释 @ErrUnknownField orm-40003，格式应该是 @错误名 错误码"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			codes, err := parseDir(tc.dir)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantCodes, codes)
+		})
+	}
+}
+
+func TestParseDir_Errs(t *testing.T) {
+	codes, err := parseDir("../../internal/errs")
+	require.NoError(t, err)
+	assert.Equal(t, errorCode{
+		Code: "orm-40001",
+		Name: "ErrUnsupportedExpressionType",
+		Desc: []string{
+			"发生该错误，主要是因为传入了不支持的 Expression 的实际类型",
+			"一般来说，这是因为中间件或者用户自己实现了 Expression 接口，ORM 不知道怎么构造它",
+		},
+	}, codes[0])
+}
@@ -0,0 +1,40 @@
+// ormerrs 根据 internal/errs 里面错误码上的注释生成错误排除手册
+//
+// 错误码的注释格式是
+//
+//	// @ErrUnsupportedExpressionType 40001
+//	// 发生该错误，主要是因为传入了不支持的 Expression 的实际类型
+//	codeUnsupportedExpressionType = "orm-40001"
+//
+// 在 internal/errs 里面执行 go generate 会重新生成 ERRORS.md
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "错误码所在的目录")
+	output := flag.String("output", "ERRORS.md", "输出文件")
+	flag.Parse()
+
+	if err := run(*dir, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output string) error {
+	codes, err := parseDir(dir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return generate(f, codes)
+}
@@ -0,0 +1,10 @@
+package errs
+
+const (
+	// @ErrUnknownField 40003
+	// 使用了模型里面不存在的字段
+	codeUnknownField = "orm-40003"
+	// @ErrUnknownColumn 40003
+	// 查询返回了模型里面不存在的列
+	codeUnknownColumn = "orm-40003"
+)
@@ -0,0 +1,7 @@
+package errs
+
+const (
+	// @ErrUnknownField orm-40003
+	// 使用了模型里面不存在的字段
+	codeUnknownField = "orm-40003"
+)
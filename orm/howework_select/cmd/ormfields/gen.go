@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+//go:embed fields.tmpl
+var fieldsTmpl string
+
+var tpl = template.Must(template.New("fields").Parse(fieldsTmpl))
+
+// 生成的代码固定会用到的包，用户文件里面的同名 import 会被去掉
+var fixedImports = map[string]struct{}{
+	`"database/sql"`: {},
+	`"reflect"`:      {},
+	`"unsafe"`:       {},
+}
+
+type fileMeta struct {
+	Package string
+	// Imports 字段类型用到的 import，形如 "database/sql" 或者 null "database/sql"
+	Imports []string
+	Types   []typeMeta
+}
+
+type typeMeta struct {
+	Name string
+	// TableName 按照命名策略计算出来的表名
+	TableName string
+	// HasTableName 结构体实现了 TableName 接口
+	HasTableName bool
+	Fields       []fieldMeta
+}
+
+type fieldMeta struct {
+	GoName  string
+	ColName string
+	// Type 字段类型的源码形式，例如 *sql.NullString
+	Type string
+	// PrimaryKey 是否主键，规则和 model.Registry 一致
+	PrimaryKey bool
+	// Tenant 是否租户字段，也就是打上了 orm:"tenant=true" 标签
+	Tenant bool
+}
+
+// parseFile 解析 dir 下面的 file 文件，提取 typeNames 对应的结构体
+// 会扫描整个目录来确定结构体有没有实现 TableName 接口
+func parseFile(dir, file string, typeNames []string, naming model.NamingStrategy) (*fileMeta, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(dir, file), nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	tableNames, err := findTableNameMethods(fset, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := make(map[string]*ast.StructType, len(typeNames))
+	ast.Inspect(f, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		if st, ok := ts.Type.(*ast.StructType); ok {
+			structs[ts.Name.Name] = st
+		}
+		return false
+	})
+
+	res := &fileMeta{Package: f.Name.Name}
+	usedPkgs := make(map[string]struct{})
+	for _, name := range typeNames {
+		st, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("ormfields: %s 中找不到结构体 %s", file, name)
+		}
+		tm := typeMeta{
+			Name:         name,
+			TableName:    naming.TableName(name),
+			HasTableName: tableNames[name],
+		}
+		var tenantField string
+		for _, fd := range st.Fields.List {
+			if len(fd.Names) == 0 {
+				return nil, fmt.Errorf("ormfields: %s 不支持组合字段 %s", name, types.ExprString(fd.Type))
+			}
+			tags, err := parseTag(fd.Tag)
+			if err != nil {
+				return nil, err
+			}
+			if tags["serializer"] != "" {
+				return nil, fmt.Errorf("ormfields: %s 使用了 serializer，请使用默认的 valuer", name)
+			}
+			collectPkgs(fd.Type, usedPkgs)
+			for _, ident := range fd.Names {
+				colName := tags["column"]
+				if colName == "" {
+					colName = naming.ColumnName(ident.Name)
+				}
+				tenant := tags["tenant"] == "true"
+				if tenant {
+					if tenantField != "" {
+						return nil, fmt.Errorf("ormfields: %s 有多个租户字段 %s 和 %s", name, tenantField, ident.Name)
+					}
+					tenantField = ident.Name
+				}
+				tm.Fields = append(tm.Fields, fieldMeta{
+					GoName:     ident.Name,
+					ColName:    colName,
+					Type:       types.ExprString(fd.Type),
+					PrimaryKey: tags["primary_key"] == "true",
+					Tenant:     tenant,
+				})
+			}
+		}
+		if len(tm.Fields) == 0 {
+			return nil, fmt.Errorf("ormfields: %s 没有任何字段", name)
+		}
+		markDefaultPrimaryKey(tm.Fields)
+		res.Types = append(res.Types, tm)
+	}
+
+	for _, spec := range f.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if _, ok := usedPkgs[name]; !ok {
+			continue
+		}
+		imp := spec.Path.Value
+		if spec.Name != nil {
+			imp = spec.Name.Name + " " + imp
+		}
+		if _, ok := fixedImports[imp]; ok {
+			continue
+		}
+		res.Imports = append(res.Imports, imp)
+	}
+	return res, nil
+}
+
+// markDefaultPrimaryKey 没有声明主键的时候，Id 字段就是主键
+func markDefaultPrimaryKey(fields []fieldMeta) {
+	for _, fd := range fields {
+		if fd.PrimaryKey {
+			return
+		}
+	}
+	for i := range fields {
+		if fields[i].GoName == "Id" {
+			fields[i].PrimaryKey = true
+		}
+	}
+}
+
+// findTableNameMethods 找出目录下所有定义了 TableName 方法的类型
+func findTableNameMethods(fset *token.FileSet, dir string) (map[string]bool, error) {
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv == nil || fn.Name.Name != "TableName" {
+					continue
+				}
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok {
+					res[ident.Name] = true
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// collectPkgs 找出类型表达式里面引用的包，例如 *sql.NullString 引用了 sql
+func collectPkgs(expr ast.Expr, pkgs map[string]struct{}) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				pkgs[ident.Name] = struct{}{}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// parseTag 和 model 里面的解析规则保持一致
+func parseTag(lit *ast.BasicLit) (map[string]string, error) {
+	if lit == nil {
+		return map[string]string{}, nil
+	}
+	raw, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil, err
+	}
+	ormTag := reflect.StructTag(raw).Get("orm")
+	res := make(map[string]string, 2)
+	if ormTag == "" {
+		return res, nil
+	}
+	for _, pair := range strings.Split(ormTag, ",") {
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, errors.New("ormfields: 错误的标签设置: " + pair)
+		}
+		res[kv[0]] = kv[1]
+	}
+	return res, nil
+}
+
+// generate 生成代码，并且用 gofmt 格式化
+func generate(w io.Writer, meta *fileMeta) error {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, meta); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
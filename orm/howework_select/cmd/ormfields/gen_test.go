@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保提交的生成代码和生成器保持一致
+func TestGenerate(t *testing.T) {
+	meta, err := parseFile("../../internal/test/gen", "user.go",
+		[]string{"User", "Order"}, model.UnderscoreNaming{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = generate(&buf, meta); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../../internal/test/gen/user_orm_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(want), buf.String())
+}
+
+func TestParseFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     string
+		typ      string
+		naming   model.NamingStrategy
+		wantMeta *fileMeta
+		wantErr  error
+	}{
+		{
+			name:   "snake",
+			file:   "user.go",
+			typ:    "Order",
+			naming: model.SnakeCaseNaming{},
+			wantMeta: &fileMeta{
+				Package: "gen",
+				Types: []typeMeta{
+					{
+						Name:         "Order",
+						TableName:    "order",
+						HasTableName: true,
+						Fields: []fieldMeta{
+							{GoName: "Id", ColName: "id", Type: "uint64", PrimaryKey: true},
+							{GoName: "UserId", ColName: "user_id", Type: "uint64"},
+							{GoName: "Amount", ColName: "amount", Type: "int64"},
+							{GoName: "TenantId", ColName: "tenant_id", Type: "uint64", Tenant: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "not found",
+			file:    "user.go",
+			typ:     "Product",
+			wantErr: errors.New("ormfields: user.go 中找不到结构体 Product"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			naming := tc.naming
+			if naming == nil {
+				naming = model.UnderscoreNaming{}
+			}
+			meta, err := parseFile("../../internal/test/gen", tc.file, []string{tc.typ}, naming)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantMeta, meta)
+		})
+	}
+}
+
+func TestParseFile_Invalid(t *testing.T) {
+	testCases := []struct {
+		typ     string
+		wantErr error
+	}{
+		{
+			typ:     "Embedded",
+			wantErr: errors.New("ormfields: Embedded 不支持组合字段 Base"),
+		},
+		{
+			typ:     "Serialized",
+			wantErr: errors.New("ormfields: Serialized 使用了 serializer，请使用默认的 valuer"),
+		},
+		{
+			typ:     "InvalidTag",
+			wantErr: errors.New("ormfields: 错误的标签设置: column"),
+		},
+		{
+			typ:     "MultiTenant",
+			wantErr: errors.New("ormfields: MultiTenant 有多个租户字段 TenantId 和 OrgId"),
+		},
+		{
+			typ:     "Empty",
+			wantErr: errors.New("ormfields: Empty 没有任何字段"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.typ, func(t *testing.T) {
+			_, err := parseFile("testdata", "invalid.go", []string{tc.typ}, model.UnderscoreNaming{})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// ormfields 为模型生成带类型的字段、预先计算好的元数据以及不依赖反射的 valuer
+//
+// 在模型所在的文件里面加上
+//
+//	//go:generate go run github.com/oreo0725/geektime-go-camp/orm/howework_select/cmd/ormfields -type=User
+//
+// 执行 go generate 之后会生成 xxx_orm_gen.go 文件
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "结构体名字，多个用逗号分隔")
+	file := flag.String("file", os.Getenv("GOFILE"), "结构体所在的文件，go generate 的时候默认是当前文件")
+	output := flag.String("output", "", "输出文件，默认是 <file>_orm_gen.go")
+	naming := flag.String("naming", "underscore", "命名策略，underscore 或者 snake")
+	flag.Parse()
+
+	if err := run(*typeNames, *file, *output, *naming); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(typeNames, file, output, naming string) error {
+	if typeNames == "" || file == "" {
+		return fmt.Errorf("ormfields: 必须指定 -type 和 -file")
+	}
+	var ns model.NamingStrategy
+	switch naming {
+	case "underscore":
+		ns = model.UnderscoreNaming{}
+	case "snake":
+		ns = model.SnakeCaseNaming{}
+	default:
+		return fmt.Errorf("ormfields: 未知命名策略 %s", naming)
+	}
+
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	meta, err := parseFile(dir, name, strings.Split(typeNames, ","), ns)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = filepath.Join(dir, strings.TrimSuffix(name, ".go")+"_orm_gen.go")
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return generate(f, meta)
+}
@@ -0,0 +1,25 @@
+package testdata
+
+type Embedded struct {
+	Base
+	Name string
+}
+
+type Base struct {
+	Id uint64
+}
+
+type Serialized struct {
+	Tags []string `orm:"serializer=json"`
+}
+
+type InvalidTag struct {
+	Name string `orm:"column"`
+}
+
+type MultiTenant struct {
+	TenantId int64 `orm:"tenant=true"`
+	OrgId    int64 `orm:"tenant=true"`
+}
+
+type Empty struct{}
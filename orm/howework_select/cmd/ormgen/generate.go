@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"go/format"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed models.tmpl
+var modelsTmpl string
+
+var tpl = template.Must(template.New("models").
+	Funcs(template.FuncMap{"join": strings.Join}).
+	Parse(modelsTmpl))
+
+type fileMeta struct {
+	Package string
+	Imports []string
+	Structs []structMeta
+}
+
+type structMeta struct {
+	Name  string
+	Table *Table
+	// Fields 和 Table.Columns 一一对应
+	Fields []fieldMeta
+}
+
+type fieldMeta struct {
+	Name   string
+	Type   string
+	Column Column
+}
+
+// filter 按照 include 和 exclude 过滤表，两者都支持 path.Match 的通配符
+// include 为空的时候表示全部
+func filter(tables []string, include, exclude []string) []string {
+	res := make([]string, 0, len(tables))
+	for _, t := range tables {
+		if len(include) > 0 && !match(t, include) {
+			continue
+		}
+		if match(t, exclude) {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+func match(table string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, table); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func generate(w io.Writer, pkg string, tables []*Table) error {
+	meta := fileMeta{Package: pkg}
+	var useSQL, useTime bool
+	for _, t := range tables {
+		sm := structMeta{
+			Name:   camelName(t.Name),
+			Table:  t,
+			Fields: make([]fieldMeta, 0, len(t.Columns)),
+		}
+		for _, c := range t.Columns {
+			typ := goType(c)
+			useSQL = useSQL || strings.HasPrefix(typ, "*sql.")
+			useTime = useTime || typ == "time.Time"
+			sm.Fields = append(sm.Fields, fieldMeta{
+				Name:   camelName(c.Name),
+				Type:   typ,
+				Column: c,
+			})
+		}
+		meta.Structs = append(meta.Structs, sm)
+	}
+	if useSQL {
+		meta.Imports = append(meta.Imports, `"database/sql"`)
+	}
+	if useTime {
+		meta.Imports = append(meta.Imports, `"time"`)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, meta); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goType 按照 SQLite 的类型亲和性规则把声明的类型映射到 Go 类型
+// 可以为 NULL 的列使用 sql.NullXXX 的指针
+func goType(c Column) string {
+	typ := strings.ToUpper(c.Type)
+	var val, null string
+	switch {
+	case strings.Contains(typ, "INT"):
+		val, null = "int64", "*sql.NullInt64"
+	case strings.Contains(typ, "BOOL"):
+		val, null = "bool", "*sql.NullBool"
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"),
+		strings.Contains(typ, "TEXT"):
+		val, null = "string", "*sql.NullString"
+	case typ == "", strings.Contains(typ, "BLOB"):
+		// NULL 直接对应 nil
+		val, null = "[]byte", "[]byte"
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"),
+		strings.Contains(typ, "DOUB"), strings.Contains(typ, "DECIMAL"),
+		strings.Contains(typ, "NUMERIC"):
+		val, null = "float64", "*sql.NullFloat64"
+	case strings.Contains(typ, "DATE"), strings.Contains(typ, "TIME"):
+		val, null = "time.Time", "*sql.NullTime"
+	default:
+		val, null = "string", "*sql.NullString"
+	}
+	if c.Nullable {
+		return null
+	}
+	return val
+}
+
+// camelName 下划线转驼峰，例如 first_name 转化为 FirstName
+// 转化之后不是合法的标识符的话，会加上 X 前缀
+func camelName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	res := sb.String()
+	if res == "" || unicode.IsDigit([]rune(res)[0]) {
+		res = "X" + res
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+// Table 表的元数据
+type Table struct {
+	Name    string
+	Columns []Column
+	Indexes []Index
+}
+
+// Column 列的元数据
+type Column struct {
+	Name string
+	// Type 建表语句里面声明的类型，例如 VARCHAR(64)
+	Type       string
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// Index 索引的元数据，不包含主键
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// Inspector 读取数据库的元数据，不同的数据库有不同的实现
+type Inspector interface {
+	// Tables 返回所有的表名
+	Tables(ctx context.Context) ([]string, error)
+	// Table 返回表的元数据
+	Table(ctx context.Context, name string) (*Table, error)
+}
+
+func newInspector(driver string, db *orm.DB) (Inspector, error) {
+	switch driver {
+	case "sqlite3":
+		return sqliteInspector{db: db}, nil
+	default:
+		return nil, fmt.Errorf("ormgen: 暂不支持 driver %s", driver)
+	}
+}
+
+type sqliteInspector struct {
+	db *orm.DB
+}
+
+type sqliteTable struct {
+	Name string
+}
+
+type sqliteColumn struct {
+	Cid       int
+	Name      string
+	Type      string
+	Notnull   bool
+	DfltValue sql.NullString `orm:"column=dflt_value"`
+	// Pk 是列在主键里面的位置，从 1 开始，0 表示不是主键
+	Pk int
+}
+
+type sqliteIndex struct {
+	Name   string
+	Unique bool
+	// Origin c 是 CREATE INDEX 创建的，u 是 UNIQUE 约束，pk 是主键
+	Origin string
+}
+
+type sqliteIndexColumn struct {
+	Name string
+}
+
+func (s sqliteInspector) Tables(ctx context.Context) ([]string, error) {
+	tbls, err := orm.RawQuery[sqliteTable](s.db,
+		"SELECT `name` FROM `sqlite_master` WHERE `type` = 'table' AND `name` NOT LIKE 'sqlite_%' ORDER BY `name`").
+		GetMulti(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(tbls))
+	for _, t := range tbls {
+		res = append(res, t.Name)
+	}
+	return res, nil
+}
+
+func (s sqliteInspector) Table(ctx context.Context, name string) (*Table, error) {
+	cols, err := orm.RawQuery[sqliteColumn](s.db,
+		"SELECT * FROM pragma_table_info(?) ORDER BY `cid`", name).GetMulti(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &Table{
+		Name:    name,
+		Columns: make([]Column, 0, len(cols)),
+	}
+	for _, c := range cols {
+		res.Columns = append(res.Columns, Column{
+			Name: c.Name,
+			Type: c.Type,
+			// INTEGER PRIMARY KEY 实际上是 rowid，不可能是 NULL
+			Nullable:   !c.Notnull && c.Pk == 0,
+			PrimaryKey: c.Pk > 0,
+		})
+	}
+
+	idxs, err := orm.RawQuery[sqliteIndex](s.db,
+		"SELECT `name`, `unique`, `origin` FROM pragma_index_list(?) ORDER BY `name`", name).GetMulti(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range idxs {
+		if idx.Origin == "pk" {
+			continue
+		}
+		idxCols, err := orm.RawQuery[sqliteIndexColumn](s.db,
+			"SELECT `name` FROM pragma_index_info(?) ORDER BY `seqno`", idx.Name).GetMulti(ctx)
+		if err != nil {
+			return nil, err
+		}
+		index := Index{Name: idx.Name, Unique: idx.Unique}
+		for _, c := range idxCols {
+			index.Columns = append(index.Columns, c.Name)
+		}
+		res.Indexes = append(res.Indexes, index)
+	}
+	return res, nil
+}
@@ -0,0 +1,103 @@
+// ormgen 读取已有数据库的表结构，生成对应的 Go 模型
+//
+//	go run ./cmd/ormgen -dsn "file:test.db" -pkg model -include "order_*" -out model/models_gen.go
+//
+// 目前只支持 SQLite
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+type config struct {
+	driver  string
+	dsn     string
+	pkg     string
+	out     string
+	include []string
+	exclude []string
+}
+
+func main() {
+	var cfg config
+	var include, exclude string
+	flag.StringVar(&cfg.driver, "driver", "sqlite3", "数据库驱动")
+	flag.StringVar(&cfg.dsn, "dsn", "", "数据库连接")
+	flag.StringVar(&cfg.pkg, "pkg", "model", "生成代码的包名")
+	flag.StringVar(&cfg.out, "out", "", "输出文件，默认输出到标准输出")
+	flag.StringVar(&include, "include", "", "只生成这些表，逗号分隔，支持通配符")
+	flag.StringVar(&exclude, "exclude", "", "不生成这些表，逗号分隔，支持通配符")
+	flag.Parse()
+	cfg.include = splitList(include)
+	cfg.exclude = splitList(exclude)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := run(ctx, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg config) error {
+	if cfg.dsn == "" {
+		return fmt.Errorf("ormgen: 必须指定 -dsn")
+	}
+	db, err := orm.Open(cfg.driver, cfg.dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	var w io.Writer = os.Stdout
+	if cfg.out != "" {
+		f, err := os.Create(cfg.out)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+	return gen(ctx, db, cfg, w)
+}
+
+func gen(ctx context.Context, db *orm.DB, cfg config, w io.Writer) error {
+	ins, err := newInspector(cfg.driver, db)
+	if err != nil {
+		return err
+	}
+	names, err := ins.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	names = filter(names, cfg.include, cfg.exclude)
+	tables := make([]*Table, 0, len(names))
+	for _, name := range names {
+		t, err := ins.Table(ctx, name)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, t)
+	}
+	return generate(w, cfg.pkg, tables)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	res := strings.Split(s, ",")
+	for i := range res {
+		res[i] = strings.TrimSpace(res[i])
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "更新 golden 文件")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS user(
+    id INTEGER PRIMARY KEY,
+    first_name VARCHAR(64) NOT NULL,
+    last_name TEXT,
+    age INTEGER,
+    score REAL,
+    avatar BLOB,
+    deleted BOOLEAN NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_name ON user(first_name, last_name);
+CREATE INDEX IF NOT EXISTS idx_user_age ON user(age);
+CREATE TABLE IF NOT EXISTS order_item(
+    order_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    remark TEXT UNIQUE,
+    PRIMARY KEY(order_id, item_id)
+);
+CREATE TABLE IF NOT EXISTS audit_log(
+    id INTEGER PRIMARY KEY,
+    content TEXT
+);
+`
+
+const dsn = "file:ormgen.db?cache=shared&mode=memory"
+
+func memoryDB(t *testing.T) *orm.DB {
+	// 共享缓存模式下，建表用的连接要一直保持打开
+	sqlDB, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err = sqlDB.ExecContext(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := orm.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestGen(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name   string
+		cfg    config
+		golden string
+	}{
+		{
+			name:   "all",
+			cfg:    config{driver: "sqlite3", pkg: "model"},
+			golden: "testdata/all.golden",
+		},
+		{
+			name: "include and exclude",
+			cfg: config{
+				driver:  "sqlite3",
+				pkg:     "dao",
+				include: []string{"order_*", "user"},
+				exclude: []string{"user"},
+			},
+			golden: "testdata/order.golden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := gen(context.Background(), db, tc.cfg, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *update {
+				if err = os.WriteFile(tc.golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(tc.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestGen_UnsupportedDriver(t *testing.T) {
+	db := memoryDB(t)
+	err := gen(context.Background(), db, config{driver: "mysql"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "ormgen: 暂不支持 driver mysql")
+}
+
+func Test_camelName(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want string
+	}{
+		{src: "first_name", want: "FirstName"},
+		{src: "id", want: "Id"},
+		{src: "order-item", want: "OrderItem"},
+		{src: "1st", want: "X1st"},
+		{src: "_", want: "X"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			assert.Equal(t, tc.want, camelName(tc.src))
+		})
+	}
+}
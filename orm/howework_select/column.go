@@ -1,5 +1,7 @@
 package orm
 
+import "strings"
+
 type Column struct {
 	name  string
 	alias string
@@ -10,7 +12,7 @@ func (c Column) expr() {}
 func (c Column) selectable() {}
 
 func (c Column) As(alias string) Column {
-	return Column {
+	return Column{
 		name:  c.name,
 		alias: alias,
 	}
@@ -28,6 +30,13 @@ func valueOf(val any) value {
 	}
 }
 
+// values 代表 IN 后面的一组值
+type values struct {
+	vals []any
+}
+
+func (values) expr() {}
+
 func C(name string) Column {
 	return Column{name: name}
 }
@@ -56,3 +65,94 @@ func (c Column) GT(arg any) Predicate {
 		right: exprOf(arg),
 	}
 }
+
+// In 例如 C("id").In(1, 2, 3)
+func (c Column) In(vals ...any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opIN,
+		right: values{vals: vals},
+	}
+}
+
+// Like 例如 C("Name").Like("%tom%")，pattern 会作为参数传递
+func (c Column) Like(pattern string) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLike,
+		right: valueOf(pattern),
+	}
+}
+
+// Contains 例如 C("Name").Contains("50%")，翻译成 `name` LIKE ? ESCAPE '\'
+// s 里面的 %, _ 和 \ 会被转义，所以只会匹配包含 s 的数据
+func (c Column) Contains(s string) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLike,
+		right: escapedPattern{val: "%" + likeEscaper.Replace(s) + "%"},
+	}
+}
+
+// likeEscaper 转义 LIKE 的通配符，转义字符是 \
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapedPattern 已经转义过的 LIKE 参数，构造的时候会加上 ESCAPE 子句
+type escapedPattern struct {
+	val string
+}
+
+func (escapedPattern) expr() {}
+
+// TypedColumn 带有类型信息的列，一般是代码生成的，例如 UserFields.FirstName
+// 和 Column 相比，它的参数类型会在编译期被检查
+type TypedColumn[V any] struct {
+	col Column
+}
+
+// TC 创建一个 TypedColumn，name 是字段名
+func TC[V any](name string) TypedColumn[V] {
+	return TypedColumn[V]{col: C(name)}
+}
+
+// Column 返回对应的 Column，用于 Select, GroupBy 等只接收 Column 的地方
+func (c TypedColumn[V]) Column() Column {
+	return c.col
+}
+
+// Name 返回字段名
+func (c TypedColumn[V]) Name() string {
+	return c.col.name
+}
+
+func (c TypedColumn[V]) As(alias string) Column {
+	return c.col.As(alias)
+}
+
+func (c TypedColumn[V]) EQ(arg V) Predicate {
+	return c.col.EQ(arg)
+}
+
+func (c TypedColumn[V]) LT(arg V) Predicate {
+	return c.col.LT(arg)
+}
+
+func (c TypedColumn[V]) GT(arg V) Predicate {
+	return c.col.GT(arg)
+}
+
+func (c TypedColumn[V]) In(vals ...V) Predicate {
+	args := make([]any, 0, len(vals))
+	for _, val := range vals {
+		args = append(args, val)
+	}
+	return c.col.In(args...)
+}
+
+func (c TypedColumn[V]) Like(pattern string) Predicate {
+	return c.col.Like(pattern)
+}
+
+func (c TypedColumn[V]) Contains(s string) Predicate {
+	return c.col.Contains(s)
+}
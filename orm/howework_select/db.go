@@ -1,37 +1,92 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
+	"reflect"
+	"time"
 
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/valuer"
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
 )
 
 type DBOption func(*DB)
 
 type DB struct {
-	r          model.Registry
-	db         *sql.DB
-	valCreator valuer.Creator
+	core
+	// db 是主库，所有的写操作和事务都发送到主库
+	db *sql.DB
+
+	// replicas 从库，为空的时候读操作也发送到主库
+	replicas  []Replica
+	lbBuilder LoadBalancerBuilder
+	lb        LoadBalancer
+
+	// dataSources 分库分表时候的数据源，key 是分片算法计算出来的库名
+	dataSources map[string]*sql.DB
+	// strictSharding 为 true 的时候，不允许需要广播到所有分片的查询
+	strictSharding bool
+
+	// stmts 预编译语句的缓存，为 nil 说明没有开启
+	stmts *stmtCache
+	// retry DoTx 的重试策略，零值不重试
+	retry RetryPolicy
+	// recorder 不为 nil 的时候，所有的语句都只是被记录下来，不会发送到数据库
+	recorder *Recorder
+
+	// poolOpts 连接池的设置，OpenDB 的时候应用到所有的数据源
+	poolOpts []func(sdb *sql.DB)
+	// pingTimeout 大于 0 的时候 OpenDB 会 Ping 所有的数据源
+	pingTimeout time.Duration
+	// healthInterval 大于 0 的时候开启后台健康检查，stopHealth 用于在 Close 的时候停止
+	healthInterval time.Duration
+	healthFunc     func(source string, err error)
+	stopHealth     context.CancelFunc
 }
 
+var _ Session = &DB{}
+
+// Open 打开数据库，数据库方言根据 driver 推断，也可以通过 DBWithDialect 指定
 func Open(driver string, dsn string, opts ...DBOption) (*DB, error) {
 	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
-	return OpenDB(db, opts...)
+	opts = append([]DBOption{DBWithDialect(dialectOf(driver))}, opts...)
+	res, err := OpenDB(db, opts...)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return res, nil
 }
 
+// OpenDB 使用已经打开的 sql.DB，开启了 DBWithPing 的时候 Ping 失败会返回错误，
+// 这个时候传入的 sql.DB 需要调用者自己关闭
 func OpenDB(db *sql.DB, opts ...DBOption) (*DB, error) {
 	res := &DB{
-		r:          model.NewRegistry(),
-		db:         db,
-		valCreator: valuer.NewUnsafeValue,
+		core: core{
+			r:          model.NewRegistry(),
+			valCreator: valuer.NewUnsafeValue,
+			dialect:    MySQL,
+		},
+		db:        db,
+		lbBuilder: RoundRobin,
 	}
 	for _, opt := range opts {
 		opt(res)
 	}
+	if res.recorder != nil {
+		res.useRecorder()
+	}
+	if len(res.replicas) > 0 {
+		res.lb = res.lbBuilder(res.replicas)
+	}
+	if err := res.initPool(); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
@@ -41,12 +96,154 @@ func DBWithRegistry(r model.Registry) DBOption {
 	}
 }
 
+// DBWithSerializer 注册一个序列化器，之后字段可以通过标签 orm:"serializer=name" 使用它
+// 它会注册到 DB 使用的 Registry 上，所以如果同时使用了 DBWithRegistry，要放在它的后面
+func DBWithSerializer(name string, s serializer.Serializer) DBOption {
+	return func(db *DB) {
+		db.r.RegisterSerializer(name, s)
+	}
+}
+
 func DBUseReflectValuer() DBOption {
 	return func(db *DB) {
 		db.valCreator = valuer.NewReflectValue
 	}
 }
 
+// Valuer 把结果集映射到结构体上，以及读取结构体的字段，ormfields 生成的 XValue 实现了它
+type Valuer = valuer.Value
+
+// DBWithValuer 让 T 使用 create 创建的 Valuer，而不是 unsafe 或者反射的实现，例如
+//
+//	r := model.NewRegistry()
+//	_ = r.RegisterModel(&User{}, UserModel)
+//	db, err := orm.Open(driver, dsn, orm.DBWithRegistry(r), orm.DBWithValuer(NewUserValue))
+//
+// create 拿到的元数据来自 Registry，所以一般要同时用 RegisterModel 注册生成的元数据
+func DBWithValuer[T any, V Valuer](create func(t *T) V) DBOption {
+	return func(db *DB) {
+		if db.valuers == nil {
+			db.valuers = make(map[reflect.Type]valuer.Creator, 4)
+		}
+		db.valuers[reflect.TypeOf((*T)(nil))] = func(val any, _ *model.Model) valuer.Value {
+			return create(val.(*T))
+		}
+	}
+}
+
+// DBWithReplicas 设置从库，之后不在事务中的读操作都会发送到从库
+func DBWithReplicas(replicas ...Replica) DBOption {
+	return func(db *DB) {
+		db.replicas = replicas
+	}
+}
+
+// DBWithLoadBalancer 设置从库的负载均衡策略，默认是轮询
+func DBWithLoadBalancer(b LoadBalancerBuilder) DBOption {
+	return func(db *DB) {
+		db.lbBuilder = b
+	}
+}
+
+// DBWithDataSources 设置分库分表的数据源，key 是分片算法计算出来的库名
+func DBWithDataSources(dataSources map[string]*sql.DB) DBOption {
+	return func(db *DB) {
+		db.dataSources = dataSources
+	}
+}
+
+// DBWithStrictSharding 开启严格模式
+// 查询条件里面没有分片键，需要查询全部分片的时候会返回 ErrShardingScatter
+func DBWithStrictSharding() DBOption {
+	return func(db *DB) {
+		db.strictSharding = true
+	}
+}
+
+// DBWithRetryPolicy 设置 DoTx 的重试策略
+// 事务之外的查询需要重试的话，使用 middleware/retry
+func DBWithRetryPolicy(p RetryPolicy) DBOption {
+	return func(db *DB) {
+		db.retry = p
+	}
+}
+
+// DBWithMaxPlaceholders 设置一条语句最多使用多少个参数，DeleteByIDs 会根据它拆分语句
+// 默认使用数据库的上限，例如 MySQL 是 65535
+func DBWithMaxPlaceholders(n int) DBOption {
+	return func(db *DB) {
+		db.maxPlaceholders = n
+	}
+}
+
+// BeginTx 在主库上开启事务
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, errs.WrapDriverError(err)
+	}
+	return &Tx{core: db.core, tx: tx, db: db.db, stmts: db.stmts}, nil
+}
+
+// Close 关闭主库，所有的从库和分库分表的数据源
+func (db *DB) Close() error {
+	if db.stopHealth != nil {
+		db.stopHealth()
+	}
+	if db.stmts != nil {
+		db.stmts.close()
+	}
+	return db.closeSources()
+}
+
+// closeSources 关闭主库、从库和分库分表的数据源，DBWithRecorder 替换之前的数据源可能是 nil
+func (db *DB) closeSources() error {
+	var err error
+	if db.db != nil {
+		err = db.db.Close()
+	}
+	for _, r := range db.replicas {
+		if r.DB == nil {
+			continue
+		}
+		if rErr := r.DB.Close(); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+	for _, ds := range db.dataSources {
+		if ds == nil {
+			continue
+		}
+		if dErr := ds.Close(); dErr != nil && err == nil {
+			err = dErr
+		}
+	}
+	return err
+}
+
+func (db *DB) getCore() core {
+	return db.core
+}
+
+// queryContext 是所有查询的统一出口
+// 没有从库，或者 ctx 被 UseMaster 标记过的时候，查询发送到主库
+func (db *DB) queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query = db.dialect.rebind(query)
+	if db.lb == nil || isUseMaster(ctx) {
+		return db.stmts.queryContext(ctx, db.db, query, args...)
+	}
+	idx := db.lb.Pick()
+	start := time.Now()
+	rows, err := db.stmts.queryContext(ctx, db.replicas[idx].DB, query, args...)
+	db.lb.Done(idx, time.Since(start), err)
+	return rows, err
+}
+
+// execContext 是所有写操作的统一出口，总是发送到主库
+func (db *DB) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.stmts.execContext(ctx, db.db, db.dialect.rebind(query), args...)
+}
+
 // MustNewDB 创建一个 DB，如果失败则会 panic
 // 我个人不太喜欢这种
 func MustNewDB(driver string, dsn string, opts ...DBOption) *DB {
@@ -0,0 +1,184 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Deleter 用于构造 DELETE 语句
+type Deleter[T any] struct {
+	builder
+	sess Session
+
+	table string
+	where []Predicate
+}
+
+func NewDeleter[T any](sess Session) *Deleter[T] {
+	return &Deleter[T]{
+		builder: builder{core: sess.getCore()},
+		sess:    sess,
+	}
+}
+
+// From 指定表名，如果是空字符串，那么将会使用默认表名
+func (d *Deleter[T]) From(tbl string) *Deleter[T] {
+	d.table = tbl
+	return d
+}
+
+// Where 用于构造 WHERE 查询条件。如果 ps 长度为 0，那么不会构造 WHERE 部分
+// 多次调用的条件会用 AND 连接起来
+func (d *Deleter[T]) Where(ps ...Predicate) *Deleter[T] {
+	d.where = append(d.where, ps...)
+	return d
+}
+
+// WhereIf 只有 cond 为 true 的时候才加上 ps
+func (d *Deleter[T]) WhereIf(cond bool, ps ...Predicate) *Deleter[T] {
+	if !cond {
+		return d
+	}
+	return d.Where(ps...)
+}
+
+func (d *Deleter[T]) Build() (*Query, error) {
+	m, err := d.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	d.model = m
+	return d.build(d.table)
+}
+
+func (d *Deleter[T]) build(table string) (*Query, error) {
+	d.reset()
+	d.sb.WriteString("DELETE FROM ")
+	d.buildTable(table)
+	if where := d.tenantWhere(d.where); len(where) > 0 {
+		d.sb.WriteString(" WHERE ")
+		if err := d.buildPredicates(where); err != nil {
+			return nil, err
+		}
+	}
+	d.sb.WriteByte(';')
+	return &Query{
+		SQL:  d.sb.String(),
+		Args: d.args,
+	}, nil
+}
+
+// Exec 执行 DELETE 语句
+// 分库分表的模型会发送到所有命中的分片，返回的 RowsAffected 是所有分片的总和
+func (d *Deleter[T]) Exec(ctx context.Context) (sql.Result, error) {
+	q, err := d.Build()
+	if err != nil {
+		return nil, err
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	qc := &QueryContext{
+		Type:    QueryTypeDelete,
+		Builder: d,
+		Model:   d.model,
+		Query:   q,
+		InTx:    inTx(d.sess),
+		Session: d.sess,
+	}
+	qr := d.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		var (
+			res sql.Result
+			err error
+		)
+		if d.model.Sharding == nil || d.table != "" {
+			res, err = d.sess.execContext(ctx, qc.Query.SQL, qc.Query.Args...)
+		} else {
+			res, err = d.execSharding(ctx)
+		}
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
+	}
+	return queryResult[sql.Result](qr)
+}
+
+func (d *Deleter[T]) execSharding(ctx context.Context) (sql.Result, error) {
+	db, ok := d.sess.(*DB)
+	if !ok {
+		return nil, errs.ErrShardingInTx
+	}
+	dsts, err := db.findDsts(d.model, d.where)
+	if err != nil {
+		return nil, err
+	}
+	qs := make([]shardingQuery, 0, len(dsts))
+	for _, dst := range dsts {
+		q, err := d.build(d.quoteTable(dst.Table))
+		if err != nil {
+			return nil, err
+		}
+		if q, err = bindTenant(ctx, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, shardingQuery{dst: dst, q: q})
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		res   shardingResult
+	)
+	for _, sq := range qs {
+		wg.Add(1)
+		go func(sq shardingQuery) {
+			defer wg.Done()
+			affected, err := execDataSource(ctx, db, sq)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if res.err == nil {
+					res.err = err
+				}
+				return
+			}
+			res.affected += affected
+		}(sq)
+	}
+	wg.Wait()
+	if res.err != nil {
+		return nil, res.err
+	}
+	return res, nil
+}
+
+func execDataSource(ctx context.Context, db *DB, sq shardingQuery) (int64, error) {
+	ds, err := db.dataSource(sq.dst.DB)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.stmts.execContext(ctx, ds, db.dialect.rebind(sq.q.SQL), sq.q.Args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// shardingResult 合并多个分片的执行结果
+// 跨分片的 LastInsertId 没有意义，所以总是返回 0
+type shardingResult struct {
+	affected int64
+	err      error
+}
+
+func (s shardingResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (s shardingResult) RowsAffected() (int64, error) {
+	return s.affected, nil
+}
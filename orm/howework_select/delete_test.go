@@ -0,0 +1,138 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleter_Build(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "no where",
+			q:    NewDeleter[TestModel](db),
+			wantQuery: &Query{
+				SQL: "DELETE FROM `test_model`;",
+			},
+		},
+		{
+			name: "from",
+			q:    NewDeleter[TestModel](db).From("`test_model_t`"),
+			wantQuery: &Query{
+				SQL: "DELETE FROM `test_model_t`;",
+			},
+		},
+		{
+			name: "where",
+			q:    NewDeleter[TestModel](db).Where(C("Id").EQ(16), C("Age").In(18, 19)),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE (`id` = ?) AND (`age` IN (?,?));",
+				Args: []any{16, 18, 19},
+			},
+		},
+		{
+			name:    "invalid column",
+			q:       NewDeleter[TestModel](db).Where(C("Invalid").EQ(1)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestDeleter_Exec(t *testing.T) {
+	master, mock := newMock(t)
+	db, err := OpenDB(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("DELETE FROM `test_model` WHERE `id` = \\?;").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	res, err := NewDeleter[TestModel](db).Where(C("Id").EQ(1)).Exec(context.Background())
+	assert.NoError(t, err)
+	affected, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleter_ExecSharding(t *testing.T) {
+	testCases := []struct {
+		name         string
+		d            func(db *DB) *Deleter[ShardingOrder]
+		mock         func(mock0, mock1 sqlmock.Sqlmock)
+		wantAffected int64
+		wantErr      error
+	}{
+		{
+			name: "single shard",
+			d: func(db *DB) *Deleter[ShardingOrder] {
+				return NewDeleter[ShardingOrder](db).Where(C("UserId").EQ(5))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectExec("DELETE FROM `order_01` WHERE `user_id` = \\?;").
+					WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			wantAffected: 2,
+		},
+		{
+			name: "multiple shards",
+			d: func(db *DB) *Deleter[ShardingOrder] {
+				return NewDeleter[ShardingOrder](db).Where(C("UserId").In(1, 2))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectExec("DELETE FROM `order_01` WHERE `user_id` IN \\(\\?,\\?\\);").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock0.ExpectExec("DELETE FROM `order_02` WHERE `user_id` IN \\(\\?,\\?\\);").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			wantAffected: 5,
+		},
+		{
+			name: "exec error",
+			d: func(db *DB) *Deleter[ShardingOrder] {
+				return NewDeleter[ShardingOrder](db).Where(C("UserId").EQ(2))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock0.ExpectExec("DELETE .*").WillReturnError(errors.New("exec error"))
+			},
+			wantErr: errors.New("exec error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock0, mock1 := newShardingDB(t)
+			tc.mock(mock0, mock1)
+			res, err := tc.d(db).Exec(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			affected, err := res.RowsAffected()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantAffected, affected)
+			assert.NoError(t, mock0.ExpectationsWereMet())
+			assert.NoError(t, mock1.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package orm
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Dialect 数据库方言
+// 构造出来的 Query 总是使用 ? 作为参数，发送到数据库之前才由 rebind 换成数据库的写法
+type Dialect interface {
+	Name() string
+	// quote 标识符的引号
+	quote() byte
+	// rebind 把 ? 换成数据库使用的参数占位符
+	rebind(query string) string
+	// buildLock 在 SQL 的最后加上锁子句
+	buildLock(b *builder, l lock) error
+	// nullsOrdering 是否支持 NULLS FIRST 和 NULLS LAST
+	nullsOrdering() bool
+	// backslashEscapes 字符串字面量里面的 \ 是不是转义字符
+	backslashEscapes() bool
+	// explain 把查询包装成 EXPLAIN 语句
+	explain(q *Query) *Query
+	// parsePlan 解析 EXPLAIN 的结果
+	parsePlan(rows *sql.Rows) ([]PlanStep, error)
+	// maxPlaceholders 一条语句最多能使用的参数个数
+	maxPlaceholders() int
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	SQLite     Dialect = sqliteDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+)
+
+// DBWithDialect 设置数据库方言
+// Open 会根据驱动的名字推断，OpenDB 默认是 MySQL
+func DBWithDialect(d Dialect) DBOption {
+	return func(db *DB) {
+		db.dialect = d
+	}
+}
+
+func dialectOf(driver string) Dialect {
+	switch driver {
+	case "sqlite3", "sqlite":
+		return SQLite
+	case "postgres", "pgx":
+		return PostgreSQL
+	default:
+		return MySQL
+	}
+}
+
+// 锁的模式和等待策略
+const (
+	lockForUpdate = "FOR UPDATE"
+	lockForShare  = "FOR SHARE"
+
+	lockSkipLocked = "SKIP LOCKED"
+	lockNoWait     = "NOWAIT"
+)
+
+type lock struct {
+	// mode 为空说明不加锁
+	mode string
+	// wait 为空说明等待锁释放
+	wait string
+	// conflict 同时设置了 SKIP LOCKED 和 NOWAIT
+	conflict bool
+}
+
+// buildStandardLock MySQL 8.0 和 PostgreSQL 的写法是一样的
+func buildStandardLock(b *builder, l lock) error {
+	b.sb.WriteByte(' ')
+	b.sb.WriteString(l.mode)
+	if l.wait != "" {
+		b.sb.WriteByte(' ')
+		b.sb.WriteString(l.wait)
+	}
+	return nil
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) quote() byte {
+	return '`'
+}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) buildLock(b *builder, l lock) error {
+	return buildStandardLock(b, l)
+}
+
+// nullsOrdering MySQL 不支持 NULLS FIRST，需要模拟
+func (mysqlDialect) nullsOrdering() bool {
+	return false
+}
+
+// backslashEscapes MySQL 默认没有开启 NO_BACKSLASH_ESCAPES，'\' 要写成 '\\'
+func (mysqlDialect) backslashEscapes() bool {
+	return true
+}
+
+func (mysqlDialect) explain(q *Query) *Query {
+	return &Query{SQL: "EXPLAIN " + q.SQL, Args: q.Args}
+}
+
+func (mysqlDialect) parsePlan(rows *sql.Rows) ([]PlanStep, error) {
+	return parseMySQLPlan(rows)
+}
+
+// maxPlaceholders 预编译语句的参数个数是用两个字节表示的
+func (mysqlDialect) maxPlaceholders() int {
+	return 65535
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+// quote PostgreSQL 使用标准的双引号
+func (postgresDialect) quote() byte {
+	return '"'
+}
+
+// rebind 把 ? 依次换成 $1, $2...，字符串和标识符里面的 ? 不会替换
+// 所以 JSONB 的 ? 操作符需要改写成 jsonb_exists 之类的函数
+func (postgresDialect) rebind(query string) string {
+	if strings.IndexByte(query, '?') < 0 {
+		return query
+	}
+	var (
+		sb    strings.Builder
+		n     int
+		quote byte
+	)
+	sb.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			// '' 和 "" 是转义，相当于结束之后马上又开始，不需要特殊处理
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func (postgresDialect) buildLock(b *builder, l lock) error {
+	return buildStandardLock(b, l)
+}
+
+func (postgresDialect) nullsOrdering() bool {
+	return true
+}
+
+func (postgresDialect) backslashEscapes() bool {
+	return false
+}
+
+// explain 使用 JSON 格式，文本格式不好解析
+func (postgresDialect) explain(q *Query) *Query {
+	return &Query{SQL: "EXPLAIN (FORMAT JSON) " + q.SQL, Args: q.Args}
+}
+
+func (postgresDialect) parsePlan(rows *sql.Rows) ([]PlanStep, error) {
+	return parsePostgresPlan(rows)
+}
+
+func (postgresDialect) maxPlaceholders() int {
+	return 65535
+}
+
+// sqliteDialect SQLite 没有行锁，写事务本身就是串行的，所以 FOR UPDATE 和 FOR SHARE 什么也不做
+// 但是 SKIP LOCKED 和 NOWAIT 的语义没办法模拟，所以返回错误
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+// quote SQLite 兼容 MySQL 的反引号
+func (sqliteDialect) quote() byte {
+	return '`'
+}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (d sqliteDialect) buildLock(b *builder, l lock) error {
+	if l.wait != "" {
+		return errs.NewErrUnsupportedLock(d.Name(), l.wait)
+	}
+	return nil
+}
+
+// nullsOrdering SQLite 3.30.0 开始支持 NULLS FIRST
+func (sqliteDialect) nullsOrdering() bool {
+	return true
+}
+
+func (sqliteDialect) backslashEscapes() bool {
+	return false
+}
+
+// explain EXPLAIN 返回的是虚拟机指令，EXPLAIN QUERY PLAN 才是执行计划
+func (sqliteDialect) explain(q *Query) *Query {
+	return &Query{SQL: "EXPLAIN QUERY PLAN " + q.SQL, Args: q.Args}
+}
+
+func (sqliteDialect) parsePlan(rows *sql.Rows) ([]PlanStep, error) {
+	return parseSQLitePlan(rows)
+}
+
+// maxPlaceholders SQLITE_MAX_VARIABLE_NUMBER 在 3.32.0 之前是 999
+func (sqliteDialect) maxPlaceholders() int {
+	return 32766
+}
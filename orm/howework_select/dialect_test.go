@@ -0,0 +1,276 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Lock(t *testing.T) {
+	mockDB, _ := newMock(t)
+	mysql, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	pg, err := OpenDB(mockDB, DBWithDialect(PostgreSQL))
+	require.NoError(t, err)
+	sqlite := memoryDB(t)
+
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "mysql for update",
+			q:    NewSelector[TestModel](mysql).Where(C("Id").EQ(1)).ForUpdate(),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` = ? FOR UPDATE;",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "mysql skip locked",
+			q:    NewSelector[TestModel](mysql).Limit(10).ForUpdate().SkipLocked(),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` LIMIT ? FOR UPDATE SKIP LOCKED;",
+				Args: []any{10},
+			},
+		},
+		{
+			name: "postgres for share nowait",
+			q:    NewSelector[TestModel](pg).ForShare().NoWait(),
+			wantQuery: &Query{
+				SQL: `SELECT * FROM "test_model" FOR SHARE NOWAIT;`,
+			},
+		},
+		{
+			// SQLite 没有行锁，直接忽略
+			name: "sqlite for update",
+			q:    NewSelector[TestModel](sqlite).ForUpdate(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model`;",
+			},
+		},
+		{
+			name:    "sqlite skip locked",
+			q:       NewSelector[TestModel](sqlite).ForUpdate().SkipLocked(),
+			wantErr: errs.NewErrUnsupportedLock("sqlite", "SKIP LOCKED"),
+		},
+		{
+			name:    "skip locked only",
+			q:       NewSelector[TestModel](mysql).SkipLocked(),
+			wantErr: errs.ErrInvalidLockOption,
+		},
+		{
+			name:    "skip locked and nowait",
+			q:       NewSelector[TestModel](mysql).ForUpdate().SkipLocked().NoWait(),
+			wantErr: errs.ErrInvalidLockOption,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestSelector_LockInTx(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	_, err = NewSelector[TestModel](db).ForUpdate().Get(ctx)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `test_model` WHERE `id` = \\? LIMIT \\? FOR UPDATE NOWAIT;").
+		WithArgs(1, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	m, err := NewSelector[TestModel](tx).Where(C("Id").EQ(1)).ForUpdate().NoWait().Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1}, m)
+	require.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgreSQL_rebind(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "no args",
+			query: `SELECT * FROM "user";`,
+			want:  `SELECT * FROM "user";`,
+		},
+		{
+			name:  "args",
+			query: `SELECT * FROM "user" WHERE ("id" IN (?,?)) AND ("age" > ?) LIMIT ?;`,
+			want:  `SELECT * FROM "user" WHERE ("id" IN ($1,$2)) AND ("age" > $3) LIMIT $4;`,
+		},
+		{
+			name:  "quoted",
+			query: `SELECT 'it''s ?', "a?b" FROM "user" WHERE "id" = ?;`,
+			want:  `SELECT 'it''s ?', "a?b" FROM "user" WHERE "id" = $1;`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, PostgreSQL.rebind(tc.query))
+		})
+	}
+}
+
+func TestPostgreSQL_Exec(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB, DBWithDialect(PostgreSQL))
+	require.NoError(t, err)
+
+	// Build 仍然使用 ?，发送到数据库的时候才换成 $n
+	q, err := NewSelector[TestModel](db).Where(C("Id").EQ(1)).Limit(1).Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{SQL: `SELECT * FROM "test_model" WHERE "id" = ? LIMIT ?;`, Args: []any{1, 1}}, q)
+
+	mock.ExpectQuery(`SELECT \* FROM "test_model" WHERE "id" = \$1 LIMIT \$2;`).
+		WithArgs(1, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "test_model" WHERE "id" = \$1;`).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	m, err := NewSelector[TestModel](db).Where(C("Id").EQ(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1}, m)
+	err = db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+		_, err := NewDeleter[TestModel](tx).Where(C("Id").EQ(2)).Exec(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_dialectOf(t *testing.T) {
+	assert.Equal(t, SQLite, dialectOf("sqlite3"))
+	assert.Equal(t, PostgreSQL, dialectOf("pgx"))
+	assert.Equal(t, MySQL, dialectOf("mysql"))
+}
+
+func TestSelector_OrderByNulls(t *testing.T) {
+	mockDB, _ := newMock(t)
+	mysql, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	pg, err := OpenDB(mockDB, DBWithDialect(PostgreSQL))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			// MySQL 默认 NULL 最小，用 IS NULL 模拟
+			name: "mysql nulls first",
+			q:    NewSelector[TestModel](mysql).OrderBy(Desc("LastName").NullsFirst()),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` ORDER BY `last_name` IS NULL DESC,`last_name` DESC;",
+			},
+		},
+		{
+			name: "mysql nulls last expression",
+			q:    NewSelector[TestModel](mysql).OrderBy(AscOf(C("Age").Add(1)).NullsLast()),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` ORDER BY (`age` + ?) IS NULL ASC,`age` + ? ASC;",
+				Args: []any{1, 1},
+			},
+		},
+		{
+			name: "postgres nulls last",
+			q:    NewSelector[TestModel](pg).OrderBy(Asc("LastName").NullsLast()),
+			wantQuery: &Query{
+				SQL: `SELECT * FROM "test_model" ORDER BY "last_name" ASC NULLS LAST;`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestColumn_Contains(t *testing.T) {
+	mockDB, _ := newMock(t)
+	mysql, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	pg, err := OpenDB(mockDB, DBWithDialect(PostgreSQL))
+	require.NoError(t, err)
+
+	// MySQL 的字符串字面量里面 \ 需要转义
+	q, err := NewSelector[TestModel](mysql).Where(C("FirstName").Contains("50%")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  "SELECT * FROM `test_model` WHERE `first_name` LIKE ? ESCAPE '\\\\';",
+		Args: []any{`%50\%%`},
+	}, q)
+	q, err = NewSelector[TestModel](pg).Where(C("FirstName").Contains("50%")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  `SELECT * FROM "test_model" WHERE "first_name" LIKE ? ESCAPE '\';`,
+		Args: []any{`%50\%%`},
+	}, q)
+
+	// 在 SQLite 上确认只匹配字面量
+	db, err := Open("sqlite3", "file:contains.db?cache=shared&mode=memory")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	_, err = RawQuery[any](db, "CREATE TABLE `test_model` (`id` INTEGER PRIMARY KEY, "+
+		"`first_name` TEXT, `age` INTEGER, `last_name` TEXT)").Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "INSERT INTO `test_model` (`id`, `first_name`) "+
+		"VALUES (1, '50% off'), (2, '500 off'), (3, 'a_b'), (4, 'axb'), (5, 'a\\b')").Exec(ctx)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		sub     string
+		wantIds []int64
+	}{
+		{sub: "50%", wantIds: []int64{1}},
+		{sub: "a_b", wantIds: []int64{3}},
+		{sub: `a\b`, wantIds: []int64{5}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.sub, func(t *testing.T) {
+			res, err := NewSelector[TestModel](db).Select(C("Id")).
+				Where(C("FirstName").Contains(tc.sub)).OrderBy(Asc("Id")).GetMulti(ctx)
+			require.NoError(t, err)
+			ids := make([]int64, 0, len(res))
+			for _, r := range res {
+				ids = append(ids, r.Id)
+			}
+			assert.Equal(t, tc.wantIds, ids)
+		})
+	}
+}
@@ -0,0 +1,490 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/valuer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// DeleteEntity 根据主键删除 entity 对应的数据，复合主键的条件用 AND 连接
+// 分库分表的模型会带上分片键，所以只会发送到一个分片
+func DeleteEntity[T any](ctx context.Context, sess Session, entity *T) (sql.Result, error) {
+	c := sess.getCore()
+	m, err := c.r.Get(entity)
+	if err != nil {
+		return nil, err
+	}
+	where, err := entityWhere(m, c.newValue(entity, m))
+	if err != nil {
+		return nil, err
+	}
+	return NewDeleter[T](sess).Where(where...).Exec(ctx)
+}
+
+// UpdateEntity 根据主键更新 entity 对应的数据，fields 是要更新的字段名，为空的时候更新全部字段
+// 主键、租户字段和分片键只会出现在 WHERE 里面，fields 里面有它们的时候返回错误
+// 模型除了它们没有别的字段的时候也返回错误
+func UpdateEntity[T any](ctx context.Context, sess Session, entity *T, fields ...string) (sql.Result, error) {
+	u := &entityUpdater[T]{
+		builder: builder{core: sess.getCore()},
+		sess:    sess,
+		entity:  entity,
+		fields:  fields,
+	}
+	return u.Exec(ctx)
+}
+
+// InsertEntity 插入 entity，fields 是要插入的字段名，为空的时候插入全部字段，零值的自增主键除外
+// 租户字段和分片键总是会插入，多租户的模型使用 ctx 里面的租户 ID，而不是 entity 上的值
+func InsertEntity[T any](ctx context.Context, sess Session, entity *T, fields ...string) (sql.Result, error) {
+	i := &entityInserter[T]{
+		builder: builder{core: sess.getCore()},
+		sess:    sess,
+		entity:  entity,
+		fields:  fields,
+	}
+	return i.Exec(ctx)
+}
+
+// DeleteByIDs 根据主键批量删除，返回删除的行数，只支持单一主键
+// ids 太多的时候会拆分成多条 IN 语句，每条语句的参数个数不超过 DBWithMaxPlaceholders 的限制
+// 多条语句不是原子的，出错的时候返回已经删除的行数，需要原子性的话请传入 Tx
+func DeleteByIDs[T any, ID any](ctx context.Context, sess Session, ids ...ID) (int64, error) {
+	c := sess.getCore()
+	m, err := c.r.Get(new(T))
+	if err != nil {
+		return 0, err
+	}
+	switch len(m.PrimaryKeys) {
+	case 0:
+		return 0, errs.NewErrNoPrimaryKey(m.TableName)
+	case 1:
+	default:
+		return 0, errs.NewErrCompositePrimaryKey(m.TableName)
+	}
+	size := c.placeholders()
+	if m.TenantField != nil {
+		// 租户条件也要占用一个参数
+		size--
+	}
+	if size <= 0 {
+		size = 1
+	}
+
+	pk := C(m.PrimaryKeys[0].GoName)
+	var total int64
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		vals := make([]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			vals = append(vals, id)
+		}
+		res, err := NewDeleter[T](sess).Where(pk.In(vals...)).Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += affected
+	}
+	return total, nil
+}
+
+// entityWhere 主键上的条件，分库分表的时候还要加上分片键
+func entityWhere(m *model.Model, val valuer.Value) ([]Predicate, error) {
+	if len(m.PrimaryKeys) == 0 {
+		return nil, errs.NewErrNoPrimaryKey(m.TableName)
+	}
+	fields := make([]string, 0, len(m.PrimaryKeys)+1)
+	for _, pk := range m.PrimaryKeys {
+		fields = append(fields, pk.GoName)
+	}
+	if m.Sharding != nil {
+		if key := m.Sharding.ShardingKey(); !isPrimaryKey(m, key) {
+			fields = append(fields, key)
+		}
+	}
+	res := make([]Predicate, 0, len(fields))
+	for _, name := range fields {
+		arg, err := val.Field(name)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, C(name).EQ(arg))
+	}
+	return res, nil
+}
+
+func isPrimaryKey(m *model.Model, field string) bool {
+	for _, pk := range m.PrimaryKeys {
+		if pk.GoName == field {
+			return true
+		}
+	}
+	return false
+}
+
+// entityUpdater 构造 UPDATE ... SET ... WHERE 主键 = ?
+type entityUpdater[T any] struct {
+	builder
+	sess   Session
+	entity *T
+	fields []string
+	val    valuer.Value
+	where  []Predicate
+}
+
+func (u *entityUpdater[T]) Build() (*Query, error) {
+	m, err := u.r.Get(u.entity)
+	if err != nil {
+		return nil, err
+	}
+	u.model = m
+	u.val = u.newValue(u.entity, m)
+	if u.where, err = entityWhere(m, u.val); err != nil {
+		return nil, err
+	}
+	return u.build("")
+}
+
+func (u *entityUpdater[T]) build(table string) (*Query, error) {
+	fds, err := u.updateFields()
+	if err != nil {
+		return nil, err
+	}
+	u.reset()
+	u.sb.WriteString("UPDATE ")
+	u.buildTable(table)
+	u.sb.WriteString(" SET ")
+	for i, fd := range fds {
+		if i > 0 {
+			u.sb.WriteByte(',')
+		}
+		u.quote(fd.ColName)
+		u.sb.WriteString(" = ?")
+		arg, err := u.val.Field(fd.GoName)
+		if err != nil {
+			return nil, err
+		}
+		if fd.Serializer != nil {
+			if arg, err = fd.Serializer.Serialize(arg); err != nil {
+				return nil, err
+			}
+		}
+		u.args = append(u.args, arg)
+	}
+	u.sb.WriteString(" WHERE ")
+	if err = u.buildPredicates(u.tenantWhere(u.where)); err != nil {
+		return nil, err
+	}
+	u.sb.WriteByte(';')
+	return &Query{
+		SQL:  u.sb.String(),
+		Args: u.args,
+	}, nil
+}
+
+// updateFields 要放在 SET 里面的字段
+func (u *entityUpdater[T]) updateFields() ([]*model.Field, error) {
+	m := u.model
+	if len(u.fields) > 0 {
+		res := make([]*model.Field, 0, len(u.fields))
+		for _, name := range u.fields {
+			fd, ok := m.FieldMap[name]
+			if !ok {
+				return nil, errs.NewErrUnknownField(name)
+			}
+			if reason := nonUpdatable(m, fd); reason != "" {
+				return nil, errs.NewErrNonUpdatableField(name, reason)
+			}
+			res = append(res, fd)
+		}
+		return res, nil
+	}
+	res := make([]*model.Field, 0, len(m.Fields))
+	for _, fd := range m.Fields {
+		if nonUpdatable(m, fd) != "" {
+			continue
+		}
+		res = append(res, fd)
+	}
+	if len(res) == 0 {
+		// 否则会构造出 UPDATE ... SET  WHERE ...
+		return nil, errs.NewErrNoUpdatableField(m.TableName)
+	}
+	return res, nil
+}
+
+// nonUpdatable 返回字段不能更新的原因，可以更新的时候返回空字符串
+func nonUpdatable(m *model.Model, fd *model.Field) string {
+	switch {
+	case isPrimaryKey(m, fd.GoName):
+		return "主键"
+	case fd == m.TenantField:
+		return "租户字段"
+	case m.Sharding != nil && m.Sharding.ShardingKey() == fd.GoName:
+		return "分片键"
+	default:
+		return ""
+	}
+}
+
+func (u *entityUpdater[T]) Exec(ctx context.Context) (sql.Result, error) {
+	q, err := u.Build()
+	if err != nil {
+		return nil, err
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	qc := &QueryContext{
+		Type:    QueryTypeUpdate,
+		Builder: u,
+		Model:   u.model,
+		Query:   q,
+		InTx:    inTx(u.sess),
+		Session: u.sess,
+	}
+	qr := u.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		var (
+			res sql.Result
+			err error
+		)
+		if u.model.Sharding == nil {
+			res, err = u.sess.execContext(ctx, qc.Query.SQL, qc.Query.Args...)
+		} else {
+			res, err = u.execSharding(ctx)
+		}
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
+	}
+	return queryResult[sql.Result](qr)
+}
+
+// execSharding WHERE 里面有分片键，所以只会命中一个分片
+func (u *entityUpdater[T]) execSharding(ctx context.Context) (sql.Result, error) {
+	db, ok := u.sess.(*DB)
+	if !ok {
+		return nil, errs.ErrShardingInTx
+	}
+	dsts, err := db.findDsts(u.model, u.where)
+	if err != nil {
+		return nil, err
+	}
+	var res shardingResult
+	for _, dst := range dsts {
+		q, err := u.build(u.quoteTable(dst.Table))
+		if err != nil {
+			return nil, err
+		}
+		if q, err = bindTenant(ctx, q); err != nil {
+			return nil, err
+		}
+		affected, err := execDataSource(ctx, db, shardingQuery{dst: dst, q: q})
+		if err != nil {
+			return nil, err
+		}
+		res.affected += affected
+	}
+	return res, nil
+}
+
+// entityInserter 构造 INSERT INTO ... VALUES ...
+type entityInserter[T any] struct {
+	builder
+	sess   Session
+	entity *T
+	fields []string
+	val    valuer.Value
+}
+
+func (i *entityInserter[T]) Build() (*Query, error) {
+	m, err := i.r.Get(i.entity)
+	if err != nil {
+		return nil, err
+	}
+	i.model = m
+	i.val = i.newValue(i.entity, m)
+	return i.build("")
+}
+
+func (i *entityInserter[T]) build(table string) (*Query, error) {
+	fds, err := i.insertFields()
+	if err != nil {
+		return nil, err
+	}
+	i.reset()
+	i.sb.WriteString("INSERT INTO ")
+	i.buildTable(table)
+	i.sb.WriteString(" (")
+	for idx, fd := range fds {
+		if idx > 0 {
+			i.sb.WriteByte(',')
+		}
+		i.quote(fd.ColName)
+	}
+	i.sb.WriteString(") VALUES (")
+	for idx, fd := range fds {
+		if idx > 0 {
+			i.sb.WriteByte(',')
+		}
+		i.sb.WriteByte('?')
+		if fd == i.model.TenantField {
+			// 和 WHERE 一样，执行的时候再从 ctx 里面取
+			i.args = append(i.args, tenantArg{})
+			continue
+		}
+		arg, err := i.val.Field(fd.GoName)
+		if err != nil {
+			return nil, err
+		}
+		if fd.Serializer != nil {
+			if arg, err = fd.Serializer.Serialize(arg); err != nil {
+				return nil, err
+			}
+		}
+		i.args = append(i.args, arg)
+	}
+	i.sb.WriteString(");")
+	return &Query{
+		SQL:  i.sb.String(),
+		Args: i.args,
+	}, nil
+}
+
+// insertFields 要插入的字段，指定了 fields 的时候也会加上租户字段和分片键
+func (i *entityInserter[T]) insertFields() ([]*model.Field, error) {
+	m := i.model
+	if len(i.fields) == 0 {
+		return i.defaultFields()
+	}
+	res := make([]*model.Field, 0, len(i.fields)+2)
+	seen := make(map[*model.Field]struct{}, len(i.fields)+2)
+	for _, name := range i.fields {
+		fd, ok := m.FieldMap[name]
+		if !ok {
+			return nil, errs.NewErrUnknownField(name)
+		}
+		if _, ok = seen[fd]; ok {
+			continue
+		}
+		seen[fd] = struct{}{}
+		res = append(res, fd)
+	}
+	required := make([]*model.Field, 0, 2)
+	if m.TenantField != nil {
+		required = append(required, m.TenantField)
+	}
+	if m.Sharding != nil {
+		required = append(required, m.FieldMap[m.Sharding.ShardingKey()])
+	}
+	for _, fd := range required {
+		if _, ok := seen[fd]; !ok {
+			seen[fd] = struct{}{}
+			res = append(res, fd)
+		}
+	}
+	return res, nil
+}
+
+// defaultFields 没有指定 fields 的时候插入全部字段
+// 但是单一主键是零值的时候跳过它，交给数据库自增
+func (i *entityInserter[T]) defaultFields() ([]*model.Field, error) {
+	m := i.model
+	if len(m.PrimaryKeys) != 1 {
+		return m.Fields, nil
+	}
+	pk := m.PrimaryKeys[0]
+	arg, err := i.val.Field(pk.GoName)
+	if err != nil {
+		return nil, err
+	}
+	if arg != nil && !reflect.ValueOf(arg).IsZero() {
+		return m.Fields, nil
+	}
+	res := make([]*model.Field, 0, len(m.Fields)-1)
+	for _, fd := range m.Fields {
+		if fd != pk {
+			res = append(res, fd)
+		}
+	}
+	return res, nil
+}
+
+func (i *entityInserter[T]) Exec(ctx context.Context) (sql.Result, error) {
+	q, err := i.Build()
+	if err != nil {
+		return nil, err
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	qc := &QueryContext{
+		Type:    QueryTypeInsert,
+		Builder: i,
+		Model:   i.model,
+		Query:   q,
+		InTx:    inTx(i.sess),
+		Session: i.sess,
+	}
+	qr := i.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		var (
+			res sql.Result
+			err error
+		)
+		if i.model.Sharding == nil {
+			res, err = i.sess.execContext(ctx, qc.Query.SQL, qc.Query.Args...)
+		} else {
+			res, err = i.execSharding(ctx)
+		}
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
+	}
+	return queryResult[sql.Result](qr)
+}
+
+// execSharding 根据分片键的值找到分片，只会命中一个
+func (i *entityInserter[T]) execSharding(ctx context.Context) (sql.Result, error) {
+	db, ok := i.sess.(*DB)
+	if !ok {
+		return nil, errs.ErrShardingInTx
+	}
+	key := i.model.Sharding.ShardingKey()
+	arg, err := i.val.Field(key)
+	if err != nil {
+		return nil, err
+	}
+	dsts, err := db.findDsts(i.model, []Predicate{C(key).EQ(arg)})
+	if err != nil {
+		return nil, err
+	}
+	var res shardingResult
+	for _, dst := range dsts {
+		q, err := i.build(i.quoteTable(dst.Table))
+		if err != nil {
+			return nil, err
+		}
+		if q, err = bindTenant(ctx, q); err != nil {
+			return nil, err
+		}
+		affected, err := execDataSource(ctx, db, shardingQuery{dst: dst, q: q})
+		if err != nil {
+			return nil, err
+		}
+		res.affected += affected
+	}
+	return res, nil
+}
@@ -0,0 +1,403 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type EntityTag struct {
+	UserId int64 `orm:"primary_key=true"`
+	TagId  int64 `orm:"primary_key=true"`
+	Note   string
+}
+
+type EntityLog struct {
+	Msg string
+}
+
+// EntityTenantKey 只有主键和租户字段
+type EntityTenantKey struct {
+	Id       int64
+	TenantId int64 `orm:"tenant=true"`
+}
+
+func TestDeleteEntity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		delete   func(ctx context.Context, db *DB) error
+		wantStmt []Statement
+		wantErr  error
+	}{
+		{
+			name: "id",
+			delete: func(ctx context.Context, db *DB) error {
+				_, err := DeleteEntity(ctx, db, &TestModel{Id: 1, FirstName: "Tom"})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `id` = ?;", Args: []any{int64(1)}},
+			},
+		},
+		{
+			name: "composite primary key",
+			delete: func(ctx context.Context, db *DB) error {
+				_, err := DeleteEntity(ctx, db, &EntityTag{UserId: 1, TagId: 2})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "DELETE FROM `entity_tag` WHERE (`user_id` = ?) AND (`tag_id` = ?);",
+					Args: []any{int64(1), int64(2)}},
+			},
+		},
+		{
+			name: "tenant",
+			delete: func(ctx context.Context, db *DB) error {
+				_, err := DeleteEntity(WithTenant(ctx, 7), db, &TenantOrder{Id: 1, TenantId: 8})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "DELETE FROM `tenant_order` WHERE (`id` = ?) AND (`tenant_id` = ?);",
+					Args: []any{int64(1), 7}},
+			},
+		},
+		{
+			name: "sharding",
+			delete: func(ctx context.Context, db *DB) error {
+				_, err := DeleteEntity(ctx, db, &ShardingOrder{Id: 1, UserId: 3})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: "order_db_1", SQL: "DELETE FROM `order_03` WHERE (`id` = ?) AND (`user_id` = ?);",
+					Args: []any{int64(1), int64(3)}},
+			},
+		},
+		{
+			name: "no primary key",
+			delete: func(ctx context.Context, db *DB) error {
+				_, err := DeleteEntity(ctx, db, &EntityLog{Msg: "hello"})
+				return err
+			},
+			wantErr: errs.NewErrNoPrimaryKey("entity_log"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRecorder()
+			db, _, _ := newShardingDB(t, DBWithRecorder(r))
+			err := tc.delete(context.Background(), db)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantStmt, r.Statements())
+		})
+	}
+}
+
+func TestUpdateEntity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		update   func(ctx context.Context, db *DB) error
+		wantStmt []Statement
+		wantErr  error
+	}{
+		{
+			name: "all fields",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &TestModel{Id: 1, FirstName: "Tom", Age: 18})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "UPDATE `test_model` SET `first_name` = ?,`age` = ?,`last_name` = ? WHERE `id` = ?;",
+					Args: []any{"Tom", int8(18), nil, int64(1)}},
+			},
+		},
+		{
+			name: "fields",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &TestModel{Id: 1, FirstName: "Tom", Age: 18}, "Age")
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "UPDATE `test_model` SET `age` = ? WHERE `id` = ?;",
+					Args: []any{int8(18), int64(1)}},
+			},
+		},
+		{
+			name: "composite primary key",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &EntityTag{UserId: 1, TagId: 2, Note: "go"})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "UPDATE `entity_tag` SET `note` = ? WHERE (`user_id` = ?) AND (`tag_id` = ?);",
+					Args: []any{"go", int64(1), int64(2)}},
+			},
+		},
+		{
+			name: "tenant",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(WithTenant(ctx, 7), db, &TenantOrder{Id: 1, TenantId: 8, Amount: 20})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "UPDATE `tenant_order` SET `amount` = ? WHERE (`id` = ?) AND (`tenant_id` = ?);",
+					Args: []any{int64(20), int64(1), 7}},
+			},
+		},
+		{
+			name: "sharding",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &ShardingOrder{Id: 1, UserId: 3})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: "order_db_1", SQL: "UPDATE `order_03` SET `amount` = ? WHERE (`id` = ?) AND (`user_id` = ?);",
+					Args: []any{nil, int64(1), int64(3)}},
+			},
+		},
+		{
+			name: "unknown field",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &TestModel{Id: 1}, "first_name")
+				return err
+			},
+			wantErr: errs.NewErrUnknownField("first_name"),
+		},
+		{
+			name: "update primary key",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &TestModel{Id: 1, Age: 18}, "Age", "Id")
+				return err
+			},
+			wantErr: errs.NewErrNonUpdatableField("Id", "主键"),
+		},
+		{
+			name: "update tenant field",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(WithTenant(ctx, 7), db, &TenantOrder{Id: 1, TenantId: 8}, "TenantId")
+				return err
+			},
+			wantErr: errs.NewErrNonUpdatableField("TenantId", "租户字段"),
+		},
+		{
+			name: "update sharding key",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &ShardingOrder{Id: 1, UserId: 3}, "UserId")
+				return err
+			},
+			wantErr: errs.NewErrNonUpdatableField("UserId", "分片键"),
+		},
+		{
+			name: "no updatable field",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(WithTenant(ctx, 7), db, &EntityTenantKey{Id: 1})
+				return err
+			},
+			wantErr: errs.NewErrNoUpdatableField("entity_tenant_key"),
+		},
+		{
+			name: "no primary key",
+			update: func(ctx context.Context, db *DB) error {
+				_, err := UpdateEntity(ctx, db, &EntityLog{Msg: "hello"})
+				return err
+			},
+			wantErr: errs.NewErrNoPrimaryKey("entity_log"),
+		},
+		{
+			name: "sharding in tx",
+			update: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+					_, err := UpdateEntity(ctx, tx, &ShardingOrder{Id: 1, UserId: 3})
+					return err
+				})
+			},
+			wantErr: errs.ErrShardingInTx,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRecorder()
+			db, _, _ := newShardingDB(t, DBWithRecorder(r))
+			err := tc.update(context.Background(), db)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantStmt, r.Statements())
+		})
+	}
+}
+
+func TestInsertEntity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		insert   func(ctx context.Context, db *DB) error
+		wantStmt []Statement
+		wantErr  error
+	}{
+		{
+			name: "all fields",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &TestModel{Id: 1, FirstName: "Tom", Age: 18})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "INSERT INTO `test_model` (`id`,`first_name`,`age`,`last_name`) VALUES (?,?,?,?);",
+					Args: []any{int64(1), "Tom", int8(18), nil}},
+			},
+		},
+		{
+			// 主键是零值的时候交给数据库自增
+			name: "zero primary key",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &TestModel{FirstName: "Tom", Age: 18})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "INSERT INTO `test_model` (`first_name`,`age`,`last_name`) VALUES (?,?,?);",
+					Args: []any{"Tom", int8(18), nil}},
+			},
+		},
+		{
+			// 复合主键不是自增的，零值也要插入
+			name: "zero composite primary key",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &EntityTag{TagId: 2, Note: "go"})
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "INSERT INTO `entity_tag` (`user_id`,`tag_id`,`note`) VALUES (?,?,?);",
+					Args: []any{int64(0), int64(2), "go"}},
+			},
+		},
+		{
+			name: "fields",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &TestModel{Id: 1, FirstName: "Tom", Age: 18}, "FirstName", "Age", "Age")
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "INSERT INTO `test_model` (`first_name`,`age`) VALUES (?,?);",
+					Args: []any{"Tom", int8(18)}},
+			},
+		},
+		{
+			// 使用 ctx 里面的租户，而不是 entity 上的值
+			name: "tenant",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(WithTenant(ctx, 7), db, &TenantOrder{Id: 1, TenantId: 8, Amount: 20}, "Amount")
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: SourceMaster, SQL: "INSERT INTO `tenant_order` (`amount`,`tenant_id`) VALUES (?,?);",
+					Args: []any{int64(20), 7}},
+			},
+		},
+		{
+			name: "missing tenant",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &TenantOrder{Id: 1, TenantId: 8})
+				return err
+			},
+			wantErr: errs.ErrMissingTenant,
+		},
+		{
+			name: "sharding",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &ShardingOrder{Id: 1, UserId: 3}, "Id")
+				return err
+			},
+			wantStmt: []Statement{
+				{Source: "order_db_1", SQL: "INSERT INTO `order_03` (`id`,`user_id`) VALUES (?,?);",
+					Args: []any{int64(1), int64(3)}},
+			},
+		},
+		{
+			name: "unknown field",
+			insert: func(ctx context.Context, db *DB) error {
+				_, err := InsertEntity(ctx, db, &TestModel{Id: 1}, "first_name")
+				return err
+			},
+			wantErr: errs.NewErrUnknownField("first_name"),
+		},
+		{
+			name: "sharding in tx",
+			insert: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+					_, err := InsertEntity(ctx, tx, &ShardingOrder{Id: 1, UserId: 3})
+					return err
+				})
+			},
+			wantErr: errs.ErrShardingInTx,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRecorder()
+			db, _, _ := newShardingDB(t, DBWithRecorder(r))
+			err := tc.insert(context.Background(), db)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantStmt, r.Statements())
+		})
+	}
+}
+
+func TestDeleteByIDs(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder().
+		WillReturnResult(0, 2).
+		WillReturnResult(0, 2).
+		WillReturnResult(0, 1)
+	db, err := OpenDB(nil, DBWithRecorder(r), DBWithMaxPlaceholders(2))
+	require.NoError(t, err)
+
+	affected, err := DeleteByIDs[TestModel](ctx, db, 1, 2, 3, 4, 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), affected)
+	assert.Equal(t, []Statement{
+		{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `id` IN (?,?);", Args: []any{1, 2}},
+		{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `id` IN (?,?);", Args: []any{3, 4}},
+		{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `id` IN (?);", Args: []any{5}},
+	}, r.Statements())
+
+	// 租户条件占用一个参数，所以每条语句只能放一个 id
+	r.Reset()
+	affected, err = DeleteByIDs[TenantOrder](WithTenant(ctx, 7), db, []int64{1, 2}...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+	assert.Equal(t, []Statement{
+		{Source: SourceMaster, SQL: "DELETE FROM `tenant_order` WHERE (`id` IN (?)) AND (`tenant_id` = ?);", Args: []any{int64(1), 7}},
+		{Source: SourceMaster, SQL: "DELETE FROM `tenant_order` WHERE (`id` IN (?)) AND (`tenant_id` = ?);", Args: []any{int64(2), 7}},
+	}, r.Statements())
+
+	// 出错的时候返回已经删除的行数
+	r.Reset()
+	r.WillReturnResult(0, 2).WillExecError(errors.New("mock error"))
+	affected, err = DeleteByIDs[TestModel](ctx, db, 1, 2, 3)
+	assert.Equal(t, errors.New("mock error"), err)
+	assert.Equal(t, int64(2), affected)
+
+	_, err = DeleteByIDs[EntityTag](ctx, db, 1)
+	assert.Equal(t, errs.NewErrCompositePrimaryKey("entity_tag"), err)
+	_, err = DeleteByIDs[EntityLog](ctx, db, 1)
+	assert.Equal(t, errs.NewErrNoPrimaryKey("entity_log"), err)
+
+	// 没有 id 的时候不执行语句
+	r.Reset()
+	affected, err = DeleteByIDs[TestModel, int64](ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+	assert.Empty(t, r.Statements())
+}
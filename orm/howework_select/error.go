@@ -2,8 +2,36 @@ package orm
 
 import "github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
 
+// Error 带有错误码的错误，ORM 返回的错误都可以通过 errors.As 转化为 *Error
+// 错误码的含义见 ERRORS.md
+type Error = errs.Error
+
 // 将内部的 sentinel error 暴露出去
 var (
 	// ErrNoRows 代表没有找到数据
 	ErrNoRows = errs.ErrNoRows
+	// ErrInvalidFilter 过滤或者排序参数不合法，一般返回 400，详情见 filter.ValidationError
+	ErrInvalidFilter = errs.ErrInvalidFilter
+
+	// 下面是驱动错误的分类，不管是 MySQL, PostgreSQL 还是 SQLite，都可以用 errors.Is 判断
+	// 原本的驱动错误仍然可以通过 errors.As 拿到
+
+	// ErrDuplicateKey 违反主键或者唯一索引
+	ErrDuplicateKey = errs.ErrDuplicateKey
+	// ErrForeignKey 违反外键约束
+	ErrForeignKey = errs.ErrForeignKey
+	// ErrDeadlock 死锁或者等待锁超时，一般重试整个事务就可以
+	ErrDeadlock = errs.ErrDeadlock
+	// ErrConnection 连接出了问题，不确定语句有没有执行
+	ErrConnection = errs.ErrConnection
 )
+
+// NewErrUnknownField 给 ormfields 生成的代码使用，和内置的 valuer 返回同样的错误
+func NewErrUnknownField(fd string) error {
+	return errs.NewErrUnknownField(fd)
+}
+
+// NewErrUnknownColumn 给 ormfields 生成的代码使用，和内置的 valuer 返回同样的错误
+func NewErrUnknownColumn(col string) error {
+	return errs.NewErrUnknownColumn(col)
+}
@@ -0,0 +1,41 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverError(t *testing.T) {
+	// _foreign_keys 对连接池里面的每个连接都生效
+	db, err := Open("sqlite3", "file:driver_error.db?cache=shared&mode=memory&_foreign_keys=on")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	_, err = RawQuery[any](db, "CREATE TABLE `parent` (`id` INTEGER PRIMARY KEY)").Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "CREATE TABLE `child` (`id` INTEGER PRIMARY KEY, "+
+		"`parent_id` INTEGER REFERENCES `parent`(`id`))").Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "INSERT INTO `parent` (`id`) VALUES (1)").Exec(ctx)
+	require.NoError(t, err)
+
+	_, err = RawQuery[any](db, "INSERT INTO `parent` (`id`) VALUES (1)").Exec(ctx)
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	var sqliteErr sqlite3.Error
+	assert.True(t, errors.As(err, &sqliteErr))
+	assert.Equal(t, sqlite3.ErrConstraintPrimaryKey, sqliteErr.ExtendedCode)
+	var ormErr *Error
+	assert.True(t, errors.As(err, &ormErr))
+	assert.Equal(t, "orm-50101", ormErr.Code())
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = RawQuery[any](tx, "INSERT INTO `child` (`id`, `parent_id`) VALUES (1, 2)").Exec(ctx)
+	assert.True(t, errors.Is(err, ErrForeignKey))
+	require.NoError(t, tx.Rollback())
+}
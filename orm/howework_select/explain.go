@@ -0,0 +1,240 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Plan 解析之后的执行计划
+type Plan struct {
+	// Dialect 数据库方言的名字，不同数据库的执行计划粒度不一样
+	Dialect string
+	Steps   []PlanStep
+}
+
+// PlanStep 执行计划里面的一步
+// MySQL 是 EXPLAIN 的一行，SQLite 是 EXPLAIN QUERY PLAN 的一行，PostgreSQL 是一个节点
+type PlanStep struct {
+	// Table 访问的表，排序之类的步骤为空
+	Table string
+	// Index 使用的索引，为空说明没有使用索引
+	Index string
+	// FullScan 全表扫描
+	FullScan bool
+	// Filesort 排序或者分组没有用上索引，需要额外排序或者使用临时表
+	Filesort bool
+	// Rows 估计扫描的行数，SQLite 没有这个信息，为 0
+	Rows int64
+	// Detail 数据库原本的描述
+	Detail string
+}
+
+// Warnings 执行计划里面值得注意的问题，例如全表扫描
+func (p *Plan) Warnings() []string {
+	var res []string
+	for _, step := range p.Steps {
+		if step.FullScan {
+			res = append(res, fmt.Sprintf("表 %s 全表扫描，可能缺少索引", step.Table))
+		}
+		if step.Filesort {
+			if step.Table == "" {
+				res = append(res, "排序或者分组没有使用索引")
+			} else {
+				res = append(res, fmt.Sprintf("表 %s 的排序或者分组没有使用索引", step.Table))
+			}
+		}
+	}
+	return res
+}
+
+// Explain 返回查询的执行计划，查询本身不会被执行，也不会经过 middleware
+// 分库分表的模型要用 From 指定一张物理表
+func (s *Selector[T]) Explain(ctx context.Context) (*Plan, error) {
+	q, err := s.Build()
+	if err != nil {
+		return nil, err
+	}
+	if s.model.Sharding != nil && s.table == "" {
+		return nil, errs.ErrExplainSharding
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	return ExplainQuery(ctx, s.sess, q)
+}
+
+// ExplainQuery 用 sess 的数据库方言解释一个已经构造好的查询
+func ExplainQuery(ctx context.Context, sess Session, q *Query) (*Plan, error) {
+	d := sess.getCore().dialect
+	eq := d.explain(q)
+	rows, err := sess.queryContext(ctx, eq.SQL, eq.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	steps, err := d.parsePlan(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return &Plan{Dialect: d.Name(), Steps: steps}, nil
+}
+
+// parseMySQLPlan 解析 MySQL 的 EXPLAIN，列的数量在不同版本里面不一样，所以按照列名读取
+// type 为 ALL 是全表扫描，Extra 里面的 Using filesort 和 Using temporary 是额外的排序
+func parseMySQLPlan(rows *sql.Rows) ([]PlanStep, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var res []PlanStep
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(cols))
+		for i, col := range cols {
+			row[strings.ToLower(col)] = vals[i].String
+		}
+		step := PlanStep{
+			Table:    row["table"],
+			Index:    row["key"],
+			FullScan: row["type"] == "ALL",
+			Filesort: strings.Contains(row["extra"], "Using filesort") ||
+				strings.Contains(row["extra"], "Using temporary"),
+			Detail: row["extra"],
+		}
+		if r, err := strconv.ParseInt(row["rows"], 10, 64); err == nil {
+			step.Rows = r
+		}
+		res = append(res, step)
+	}
+	return res, nil
+}
+
+// parseSQLitePlan 解析 SQLite 的 EXPLAIN QUERY PLAN，只有 detail 列是有用的，例如
+//
+//	SCAN user
+//	SEARCH user USING INDEX idx_age (age>?)
+//	USE TEMP B-TREE FOR ORDER BY
+//
+// 3.36 之前的版本是 SCAN TABLE user
+func parseSQLitePlan(rows *sql.Rows) ([]PlanStep, error) {
+	var res []PlanStep
+	for rows.Next() {
+		var (
+			id, parent, notUsed int
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return nil, err
+		}
+		step := PlanStep{Detail: detail}
+		words := strings.Fields(detail)
+		switch {
+		case len(words) >= 2 && (words[0] == "SCAN" || words[0] == "SEARCH"):
+			table := words[1]
+			if table == "TABLE" && len(words) >= 3 {
+				table = words[2]
+			}
+			// SCAN CONSTANT ROW 和子查询不是真的表
+			if table != "CONSTANT" && !strings.HasPrefix(table, "(") {
+				step.Table = table
+			}
+			step.Index = sqliteIndex(detail)
+			step.FullScan = words[0] == "SCAN" && step.Table != "" && step.Index == ""
+		case strings.HasPrefix(detail, "USE TEMP B-TREE"):
+			step.Filesort = true
+		}
+		res = append(res, step)
+	}
+	return res, nil
+}
+
+func sqliteIndex(detail string) string {
+	for _, prefix := range []string{"USING COVERING INDEX ", "USING INDEX "} {
+		if idx := strings.Index(detail, prefix); idx >= 0 {
+			name := detail[idx+len(prefix):]
+			if end := strings.IndexByte(name, ' '); end >= 0 {
+				name = name[:end]
+			}
+			return name
+		}
+	}
+	if strings.Contains(detail, "PRIMARY KEY") {
+		return "PRIMARY KEY"
+	}
+	return ""
+}
+
+// postgresNode EXPLAIN (FORMAT JSON) 的一个节点，只解析需要的字段
+type postgresNode struct {
+	NodeType     string         `json:"Node Type"`
+	RelationName string         `json:"Relation Name"`
+	IndexName    string         `json:"Index Name"`
+	PlanRows     float64        `json:"Plan Rows"`
+	Plans        []postgresNode `json:"Plans"`
+}
+
+// parsePostgresPlan 解析 PostgreSQL 的 EXPLAIN (FORMAT JSON)，结果只有一行一列
+// 只保留访问表的节点和排序节点，Seq Scan 是全表扫描
+func parsePostgresPlan(rows *sql.Rows) ([]PlanStep, error) {
+	var res []PlanStep
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var plans []struct {
+			Plan postgresNode `json:"Plan"`
+		}
+		if err := json.Unmarshal(raw, &plans); err != nil {
+			return nil, err
+		}
+		for _, p := range plans {
+			res = appendPostgresNode(res, p.Plan)
+		}
+	}
+	return res, nil
+}
+
+func appendPostgresNode(res []PlanStep, n postgresNode) []PlanStep {
+	switch {
+	case n.RelationName != "":
+		index := n.IndexName
+		// Bitmap Heap Scan 的索引在子节点 Bitmap Index Scan 上面
+		if index == "" && n.NodeType == "Bitmap Heap Scan" && len(n.Plans) > 0 {
+			index = n.Plans[0].IndexName
+		}
+		res = append(res, PlanStep{
+			Table:    n.RelationName,
+			Index:    index,
+			FullScan: n.NodeType == "Seq Scan",
+			Rows:     int64(n.PlanRows),
+			Detail:   n.NodeType,
+		})
+	case n.NodeType == "Sort":
+		res = append(res, PlanStep{
+			Filesort: true,
+			Rows:     int64(n.PlanRows),
+			Detail:   n.NodeType,
+		})
+	}
+	for _, child := range n.Plans {
+		res = appendPostgresNode(res, child)
+	}
+	return res
+}
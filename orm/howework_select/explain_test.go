@@ -0,0 +1,190 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ExplainUser struct {
+	Id   int64
+	Name string
+	Age  int8
+}
+
+func TestSelector_Explain_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := memoryDBWithDB("explain", t)
+	_, err := RawQuery[any](db, `CREATE TABLE IF NOT EXISTS explain_user(
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    age INTEGER NOT NULL
+)`).Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "CREATE INDEX IF NOT EXISTS idx_age ON explain_user(age)").Exec(ctx)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		s            *Selector[ExplainUser]
+		wantSteps    []PlanStep
+		wantWarnings []string
+	}{
+		{
+			name: "primary key",
+			s:    NewSelector[ExplainUser](db).Where(C("Id").EQ(1)),
+			wantSteps: []PlanStep{
+				{Table: "explain_user", Index: "PRIMARY KEY", Detail: "SEARCH explain_user USING INTEGER PRIMARY KEY (rowid=?)"},
+			},
+		},
+		{
+			name: "index",
+			s:    NewSelector[ExplainUser](db).Where(C("Age").GT(18)),
+			wantSteps: []PlanStep{
+				{Table: "explain_user", Index: "idx_age", Detail: "SEARCH explain_user USING INDEX idx_age (age>?)"},
+			},
+		},
+		{
+			name: "full scan",
+			s:    NewSelector[ExplainUser](db).OrderBy(Asc("Name")),
+			wantSteps: []PlanStep{
+				{Table: "explain_user", FullScan: true, Detail: "SCAN explain_user"},
+				{Filesort: true, Detail: "USE TEMP B-TREE FOR ORDER BY"},
+			},
+			wantWarnings: []string{"表 explain_user 全表扫描，可能缺少索引", "排序或者分组没有使用索引"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := tc.s.Explain(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "sqlite", plan.Dialect)
+			assert.Equal(t, tc.wantSteps, plan.Steps)
+			assert.Equal(t, tc.wantWarnings, plan.Warnings())
+		})
+	}
+}
+
+func TestSelector_Explain(t *testing.T) {
+	ctx := context.Background()
+	mysqlCols := []string{"id", "select_type", "table", "partitions", "type", "possible_keys",
+		"key", "key_len", "ref", "rows", "filtered", "Extra"}
+	testCases := []struct {
+		name    string
+		dialect Dialect
+		mock    func(mock sqlmock.Sqlmock)
+		s       func(db *DB) *Selector[ExplainUser]
+
+		wantPlan     *Plan
+		wantWarnings []string
+		wantErr      error
+	}{
+		{
+			name:    "mysql index",
+			dialect: MySQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("EXPLAIN SELECT \\* FROM `explain_user` WHERE `age` > \\?;").
+					WithArgs(18).
+					WillReturnRows(sqlmock.NewRows(mysqlCols).
+						AddRow(1, "SIMPLE", "explain_user", nil, "range", "idx_age", "idx_age", "1", nil, 12, 100.0, "Using index condition"))
+			},
+			s: func(db *DB) *Selector[ExplainUser] {
+				return NewSelector[ExplainUser](db).Where(C("Age").GT(18))
+			},
+			wantPlan: &Plan{Dialect: "mysql", Steps: []PlanStep{
+				{Table: "explain_user", Index: "idx_age", Rows: 12, Detail: "Using index condition"},
+			}},
+		},
+		{
+			name:    "mysql full scan",
+			dialect: MySQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("EXPLAIN SELECT \\* FROM `explain_user` WHERE `name` = \\? ORDER BY `age` ASC;").
+					WithArgs("Tom").
+					WillReturnRows(sqlmock.NewRows(mysqlCols).
+						AddRow(1, "SIMPLE", "explain_user", nil, "ALL", nil, nil, nil, nil, 1000, 10.0, "Using where; Using filesort"))
+			},
+			s: func(db *DB) *Selector[ExplainUser] {
+				return NewSelector[ExplainUser](db).Where(C("Name").EQ("Tom")).OrderBy(Asc("Age"))
+			},
+			wantPlan: &Plan{Dialect: "mysql", Steps: []PlanStep{
+				{Table: "explain_user", FullScan: true, Filesort: true, Rows: 1000, Detail: "Using where; Using filesort"},
+			}},
+			wantWarnings: []string{"表 explain_user 全表扫描，可能缺少索引", "表 explain_user 的排序或者分组没有使用索引"},
+		},
+		{
+			name:    "postgres",
+			dialect: PostgreSQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT \* FROM "explain_user" ORDER BY "name" ASC;`).
+					WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(`[{"Plan": {
+  "Node Type": "Sort", "Plan Rows": 1200,
+  "Plans": [{"Node Type": "Seq Scan", "Relation Name": "explain_user", "Plan Rows": 1200}]
+}}]`))
+			},
+			s: func(db *DB) *Selector[ExplainUser] {
+				return NewSelector[ExplainUser](db).OrderBy(Asc("Name"))
+			},
+			wantPlan: &Plan{Dialect: "postgres", Steps: []PlanStep{
+				{Filesort: true, Rows: 1200, Detail: "Sort"},
+				{Table: "explain_user", FullScan: true, Rows: 1200, Detail: "Seq Scan"},
+			}},
+			wantWarnings: []string{"排序或者分组没有使用索引", "表 explain_user 全表扫描，可能缺少索引"},
+		},
+		{
+			name:    "postgres bitmap",
+			dialect: PostgreSQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT \* FROM "explain_user" WHERE "age" > \$1;`).
+					WithArgs(18).
+					WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(`[{"Plan": {
+  "Node Type": "Bitmap Heap Scan", "Relation Name": "explain_user", "Plan Rows": 30,
+  "Plans": [{"Node Type": "Bitmap Index Scan", "Index Name": "idx_age", "Plan Rows": 30}]
+}}]`))
+			},
+			s: func(db *DB) *Selector[ExplainUser] {
+				return NewSelector[ExplainUser](db).Where(C("Age").GT(18))
+			},
+			wantPlan: &Plan{Dialect: "postgres", Steps: []PlanStep{
+				{Table: "explain_user", Index: "idx_age", Rows: 30, Detail: "Bitmap Heap Scan"},
+			}},
+		},
+		{
+			name:    "invalid column",
+			dialect: MySQL,
+			mock:    func(mock sqlmock.Sqlmock) {},
+			s: func(db *DB) *Selector[ExplainUser] {
+				return NewSelector[ExplainUser](db).Where(C("Invalid").EQ(1))
+			},
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock := newMock(t)
+			db, err := OpenDB(mockDB, DBWithDialect(tc.dialect))
+			require.NoError(t, err)
+			tc.mock(mock)
+			plan, err := tc.s(db).Explain(ctx)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantPlan, plan)
+			assert.Equal(t, tc.wantWarnings, plan.Warnings())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSelector_ExplainSharding(t *testing.T) {
+	db, _, _ := newShardingDB(t)
+	_, err := NewSelector[ShardingOrder](db).Where(C("UserId").EQ(1)).Explain(context.Background())
+	assert.Equal(t, errs.ErrExplainSharding, err)
+}
@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// baseType 去掉指针和 sql.NullXxx 的包装，返回真正用来转换的类型
+func baseType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	// sql.NullInt64, sql.NullString 之类的类型，以及 sql.Null[T]
+	// 第一个字段是值，第二个字段是 Valid
+	if typ.Kind() == reflect.Struct && typ != timeType && typ.NumField() == 2 &&
+		typ.Field(1).Name == "Valid" && typ.Field(1).Type.Kind() == reflect.Bool {
+		typ = typ.Field(0).Type
+	}
+	return typ
+}
+
+// coerce 按照字段的类型转换查询字符串里面的值
+// 返回的是基础类型，例如 int64, float64，驱动都能处理
+// 转换失败的时候返回给前端看的原因
+func coerce(typ reflect.Type, raw string) (any, string) {
+	if typ == timeType {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, ""
+		}
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t, ""
+		}
+		return nil, "必须是时间，格式是 RFC3339 或者 2006-01-02"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return raw, ""
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, "必须是 true 或者 false"
+		}
+		return v, ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, typ.Bits())
+		if err != nil {
+			return nil, "必须是整数，并且不能超出范围"
+		}
+		return v, ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(raw, 10, typ.Bits())
+		if err != nil {
+			return nil, "必须是非负整数，并且不能超出范围"
+		}
+		return v, ""
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(raw, typ.Bits())
+		if err != nil {
+			return nil, "必须是数字"
+		}
+		return v, ""
+	}
+	return nil, "不支持过滤"
+}
@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// FieldError 某一个过滤或者排序条件的问题
+type FieldError struct {
+	// Param 是 filter 或者 sort
+	Param string `json:"param"`
+	// Field 查询字符串里面的列名，缺少列名的时候是整个条件
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError 查询字符串不合法，可以直接序列化成 400 响应的 body
+// 通过 errors.Is(err, orm.ErrInvalidFilter) 判断
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) add(param, field, reason string) {
+	e.Errors = append(e.Errors, FieldError{Param: param, Field: field, Reason: reason})
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(errs.ErrInvalidFilter.Error())
+	for i, fe := range e.Errors {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(fe.Param)
+		sb.WriteByte('.')
+		sb.WriteString(fe.Field)
+		sb.WriteByte(' ')
+		sb.WriteString(fe.Reason)
+	}
+	return sb.String()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return errs.ErrInvalidFilter
+}
@@ -0,0 +1,229 @@
+// Package filter 把列表接口的查询字符串翻译成 orm.Predicate 和 orm.OrderBy，例如
+//
+//	?filter=age>18,name~tom&sort=-created_at
+//
+// filter 是逗号分隔的条件，每个条件是 列名 操作符 值，支持的操作符有
+//
+//	=   等于，值里面有 | 的时候是 IN，例如 status=1|2
+//	!=  不等于，同样支持 |
+//	>, <, >=, <=
+//	~   包含，只能用于字符串字段，翻译成 LIKE %值% ESCAPE '\'，值里面的 %, _ 和 \ 会被转义
+//
+// | 没有转义的写法，所以 = 和 != 没有办法匹配带有 | 的值
+//
+// sort 是逗号分隔的列名，前缀 - 代表降序，+ 或者没有前缀代表升序
+//
+// 字段名使用的是列名，只有通过 Filterable 和 Sortable 允许的列才可以使用
+// 值会按照字段的类型转换，转换失败返回 *ValidationError，一般直接作为 400 返回给前端
+package filter
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// 查询字符串里面的参数名，也是 FieldError.Param 的取值
+const (
+	ParamFilter = "filter"
+	ParamSort   = "sort"
+)
+
+// 操作符，两个字符的要放在前面，先匹配
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "~"}
+
+type Option func(p *Parser)
+
+// Filterable 允许过滤的列，使用列名，例如 created_at
+func Filterable(cols ...string) Option {
+	return func(p *Parser) {
+		for _, col := range cols {
+			p.filterable[col] = nil
+		}
+	}
+}
+
+// Sortable 允许排序的列，使用列名，例如 created_at
+func Sortable(cols ...string) Option {
+	return func(p *Parser) {
+		for _, col := range cols {
+			p.sortable[col] = nil
+		}
+	}
+}
+
+// Parser 针对一个模型的解析器，创建之后是线程安全的
+type Parser struct {
+	filterable map[string]*model.Field
+	sortable   map[string]*model.Field
+}
+
+// NewParser 创建一个解析器，默认不允许任何列过滤和排序
+// 允许的列在模型里面不存在的时候返回错误，这是代码的问题，而不是请求的问题
+func NewParser(m *model.Model, opts ...Option) (*Parser, error) {
+	p := &Parser{
+		filterable: make(map[string]*model.Field),
+		sortable:   make(map[string]*model.Field),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for _, allowed := range []map[string]*model.Field{p.filterable, p.sortable} {
+		for col := range allowed {
+			fd, ok := m.ColumnMap[col]
+			if !ok {
+				return nil, errs.NewErrUnknownColumn(col)
+			}
+			allowed[col] = fd
+		}
+	}
+	return p, nil
+}
+
+// ParseQuery 解析查询字符串里面的 filter 和 sort 参数
+func (p *Parser) ParseQuery(q url.Values) ([]orm.Predicate, []orm.OrderBy, error) {
+	return p.Parse(q.Get(ParamFilter), q.Get(ParamSort))
+}
+
+// Parse 解析 filter 和 sort，返回的 Predicate 可以直接传给 Where，OrderBy 可以直接传给 OrderBy
+// 两者的错误会一起返回，方便前端一次改完
+func (p *Parser) Parse(filter, sort string) ([]orm.Predicate, []orm.OrderBy, error) {
+	var ve ValidationError
+	ps := p.parseFilter(filter, &ve)
+	obs := p.parseSort(sort, &ve)
+	if len(ve.Errors) > 0 {
+		return nil, nil, &ve
+	}
+	return ps, obs, nil
+}
+
+func (p *Parser) parseFilter(filter string, ve *ValidationError) []orm.Predicate {
+	var res []orm.Predicate
+	for _, term := range strings.Split(filter, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		col, op, val, ok := splitTerm(term)
+		if !ok {
+			ve.add(ParamFilter, term, "缺少操作符")
+			continue
+		}
+		if col == "" {
+			ve.add(ParamFilter, term, "缺少字段名")
+			continue
+		}
+		fd, ok := p.filterable[col]
+		if !ok {
+			ve.add(ParamFilter, col, "不允许过滤")
+			continue
+		}
+		pred, reason := predicate(fd, op, val)
+		if reason != "" {
+			ve.add(ParamFilter, col, reason)
+			continue
+		}
+		res = append(res, pred)
+	}
+	return res
+}
+
+// splitTerm 把 age>=18 拆成 age, >=, 18
+func splitTerm(term string) (col, op, val string, ok bool) {
+	idx := strings.IndexAny(term, "=<>!~")
+	if idx < 0 {
+		return "", "", "", false
+	}
+	rest := term[idx:]
+	for _, o := range operators {
+		if strings.HasPrefix(rest, o) {
+			return strings.TrimSpace(term[:idx]), o, strings.TrimSpace(rest[len(o):]), true
+		}
+	}
+	// 只有一个 !
+	return "", "", "", false
+}
+
+func predicate(fd *model.Field, op string, val string) (orm.Predicate, string) {
+	if fd.Serializer != nil {
+		// 数据库里面存的是序列化之后的值，没有办法直接比较
+		return orm.Predicate{}, "不支持过滤"
+	}
+	col := orm.C(fd.GoName)
+	typ := baseType(fd.Type)
+	if op == "~" {
+		if typ.Kind() != reflect.String {
+			return orm.Predicate{}, "只有字符串字段支持 ~"
+		}
+		return col.Contains(val), ""
+	}
+
+	if (op == "=" || op == "!=") && strings.Contains(val, "|") {
+		raws := strings.Split(val, "|")
+		vals := make([]any, 0, len(raws))
+		for _, raw := range raws {
+			v, reason := coerce(typ, raw)
+			if reason != "" {
+				return orm.Predicate{}, reason
+			}
+			vals = append(vals, v)
+		}
+		if op == "!=" {
+			return orm.Not(col.In(vals...)), ""
+		}
+		return col.In(vals...), ""
+	}
+
+	v, reason := coerce(typ, val)
+	if reason != "" {
+		return orm.Predicate{}, reason
+	}
+	switch op {
+	case "=":
+		return col.EQ(v), ""
+	case "!=":
+		return orm.Not(col.EQ(v)), ""
+	case ">":
+		return col.GT(v), ""
+	case "<":
+		return col.LT(v), ""
+	case ">=":
+		return orm.Not(col.LT(v)), ""
+	default:
+		// <=
+		return orm.Not(col.GT(v)), ""
+	}
+}
+
+func (p *Parser) parseSort(sort string, ve *ValidationError) []orm.OrderBy {
+	var res []orm.OrderBy
+	for _, term := range strings.Split(sort, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		desc := false
+		switch term[0] {
+		case '-':
+			desc = true
+			term = term[1:]
+		case '+':
+			term = term[1:]
+		}
+		fd, ok := p.sortable[term]
+		if !ok {
+			ve.add(ParamSort, term, "不允许排序")
+			continue
+		}
+		if desc {
+			res = append(res, orm.Desc(fd.GoName))
+		} else {
+			res = append(res, orm.Asc(fd.GoName))
+		}
+	}
+	return res
+}
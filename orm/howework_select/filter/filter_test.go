@@ -0,0 +1,197 @@
+package filter
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Id        uint64
+	Name      string
+	Age       *int8
+	Score     sql.NullFloat64
+	Vip       bool
+	CreatedAt time.Time
+	Remark    string `orm:"serializer=json"`
+}
+
+func newParser(t *testing.T) *Parser {
+	m, err := model.NewRegistry().Get(&User{})
+	require.NoError(t, err)
+	p, err := NewParser(m,
+		Filterable("id", "name", "age", "score", "vip", "created_at", "remark"),
+		Sortable("id", "created_at"))
+	require.NoError(t, err)
+	return p
+}
+
+func TestNewParser(t *testing.T) {
+	m, err := model.NewRegistry().Get(&User{})
+	require.NoError(t, err)
+	// 允许的是列名，不是字段名
+	_, err = NewParser(m, Filterable("CreatedAt"))
+	assert.Equal(t, errs.NewErrUnknownColumn("CreatedAt"), err)
+	_, err = NewParser(m, Sortable("unknown"))
+	assert.Equal(t, errs.NewErrUnknownColumn("unknown"), err)
+}
+
+func TestParser_Parse(t *testing.T) {
+	p := newParser(t)
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	db, err := orm.OpenDB(mockDB)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		filter string
+		sort   string
+
+		wantQuery *orm.Query
+		wantErrs  []FieldError
+	}{
+		{
+			name:      "empty",
+			wantQuery: &orm.Query{SQL: "SELECT * FROM `user`;"},
+		},
+		{
+			name:   "compare",
+			filter: "age>18,name~tom",
+			sort:   "-created_at",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE (`age` > ?) AND (`name` LIKE ? ESCAPE '\\\\') ORDER BY `created_at` DESC;",
+				Args: []any{int64(18), "%tom%"},
+			},
+		},
+		{
+			name:   "two char operators",
+			filter: "age>=18, age<=60, id!=3",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE (( NOT (`age` < ?)) AND ( NOT (`age` > ?))) AND ( NOT (`id` = ?));",
+				Args: []any{int64(18), int64(60), uint64(3)},
+			},
+		},
+		{
+			name:   "in",
+			filter: "id=1|2|3,name!=tom|jerry",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE (`id` IN (?,?,?)) AND ( NOT (`name` IN (?,?)));",
+				Args: []any{uint64(1), uint64(2), uint64(3), "tom", "jerry"},
+			},
+		},
+		{
+			// | 总是拆分成 IN，没有办法匹配带有 | 的字符串
+			name:   "pipe in string",
+			filter: "name=a|b,name!=|",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE (`name` IN (?,?)) AND ( NOT (`name` IN (?,?)));",
+				Args: []any{"a", "b", "", ""},
+			},
+		},
+		{
+			// % 和 _ 会被转义，只匹配字面量
+			name:   "like wildcards",
+			filter: "name~50%_off",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE `name` LIKE ? ESCAPE '\\\\';",
+				Args: []any{`%50\%\_off%`},
+			},
+		},
+		{
+			name:   "coerce",
+			filter: "score<59.5,vip=true,created_at>2022-10-01",
+			sort:   "+id,created_at",
+			wantQuery: &orm.Query{
+				SQL: "SELECT * FROM `user` WHERE ((`score` < ?) AND (`vip` = ?)) AND (`created_at` > ?) " +
+					"ORDER BY `id` ASC,`created_at` ASC;",
+				Args: []any{59.5, true, time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:   "empty string",
+			filter: "name=",
+			wantQuery: &orm.Query{
+				SQL:  "SELECT * FROM `user` WHERE `name` = ?;",
+				Args: []any{""},
+			},
+		},
+		{
+			name:   "invalid terms",
+			filter: "age,=18,age!18,vip~true",
+			wantErrs: []FieldError{
+				{Param: ParamFilter, Field: "age", Reason: "缺少操作符"},
+				{Param: ParamFilter, Field: "=18", Reason: "缺少字段名"},
+				{Param: ParamFilter, Field: "age!18", Reason: "缺少操作符"},
+				{Param: ParamFilter, Field: "vip", Reason: "只有字符串字段支持 ~"},
+			},
+		},
+		{
+			name:   "invalid values",
+			filter: "age>200,id=1|-1,score<abc,vip=yes,created_at>yesterday,remark=a",
+			wantErrs: []FieldError{
+				{Param: ParamFilter, Field: "age", Reason: "必须是整数，并且不能超出范围"},
+				{Param: ParamFilter, Field: "id", Reason: "必须是非负整数，并且不能超出范围"},
+				{Param: ParamFilter, Field: "score", Reason: "必须是数字"},
+				{Param: ParamFilter, Field: "vip", Reason: "必须是 true 或者 false"},
+				{Param: ParamFilter, Field: "created_at", Reason: "必须是时间，格式是 RFC3339 或者 2006-01-02"},
+				{Param: ParamFilter, Field: "remark", Reason: "不支持过滤"},
+			},
+		},
+		{
+			name:   "not allowed",
+			filter: "password=123,Name=tom",
+			sort:   "-name",
+			wantErrs: []FieldError{
+				{Param: ParamFilter, Field: "password", Reason: "不允许过滤"},
+				{Param: ParamFilter, Field: "Name", Reason: "不允许过滤"},
+				{Param: ParamSort, Field: "name", Reason: "不允许排序"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, obs, err := p.Parse(tc.filter, tc.sort)
+			if len(tc.wantErrs) > 0 {
+				assert.True(t, errors.Is(err, orm.ErrInvalidFilter))
+				var ve *ValidationError
+				require.True(t, errors.As(err, &ve))
+				assert.Equal(t, tc.wantErrs, ve.Errors)
+				return
+			}
+			require.NoError(t, err)
+			q, err := orm.NewSelector[User](db).Where(ps...).OrderBy(obs...).Build()
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestParser_ParseQuery(t *testing.T) {
+	p := newParser(t)
+	q, err := url.ParseQuery("filter=age%3E18,name~tom&sort=-created_at")
+	require.NoError(t, err)
+	ps, obs, err := p.ParseQuery(q)
+	require.NoError(t, err)
+	assert.Equal(t, []orm.Predicate{orm.C("Age").GT(int64(18)), orm.C("Name").Contains("tom")}, ps)
+	assert.Equal(t, []orm.OrderBy{orm.Desc("CreatedAt")}, obs)
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := &ValidationError{Errors: []FieldError{
+		{Param: ParamFilter, Field: "age", Reason: "必须是整数，并且不能超出范围"},
+		{Param: ParamSort, Field: "name", Reason: "不允许排序"},
+	}}
+	assert.Equal(t, "orm-40025: 过滤或者排序参数不合法: filter.age 必须是整数，并且不能超出范围; sort.name 不允许排序", err.Error())
+}
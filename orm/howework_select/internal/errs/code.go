@@ -0,0 +1,156 @@
+package errs
+
+//go:generate go run ../../cmd/ormerrs -dir . -output ../../ERRORS.md
+
+// 错误码，格式是 orm-xxxxx，发布之后不会再修改
+// 4 开头的是使用错误，一般需要修改代码；5 开头的是运行时错误，一般和数据库本身有关
+//
+// 每个错误码上面的注释会被 cmd/ormerrs 生成错误排除手册 ERRORS.md，格式是
+//
+//	@错误名 错误码
+//	发生的原因和解决办法
+const (
+	// @ErrUnsupportedExpressionType 40001
+	// 发生该错误，主要是因为传入了不支持的 Expression 的实际类型
+	// 一般来说，这是因为中间件或者用户自己实现了 Expression 接口，ORM 不知道怎么构造它
+	codeUnsupportedExpressionType = "orm-40001"
+	// @ErrUnsupportedSelectable 40002
+	// Select 里面传入了不支持的 Selectable，目前支持列、聚合函数、算术表达式、窗口函数、CASE 和 RawExpr
+	codeUnsupportedSelectable = "orm-40002"
+	// @ErrUnknownField 40003
+	// 使用了模型里面不存在的字段，或者不存在的别名
+	// 注意 C("xxx") 里面是 Go 的字段名，例如 FirstName，而不是列名 first_name
+	codeUnknownField = "orm-40003"
+	// @ErrUnknownColumn 40004
+	// 查询返回了模型里面不存在的列，一般是 SELECT 的列和模型对不上，或者模型漏了字段
+	codeUnknownColumn = "orm-40004"
+	// @ErrPointerOnly 40005
+	// 注册模型的时候只支持一级指针，例如 &User{}，不支持结构体、二级指针或者其它类型
+	codePointerOnly = "orm-40005"
+	// @ErrInvalidTagContent 40006
+	// orm 标签的格式不对，正确的格式是 orm:"key1=value1,key2=value2"
+	codeInvalidTagContent = "orm-40006"
+	// @ErrUnknownSerializer 40007
+	// 标签 serializer=xxx 里面的序列化器没有注册，检查名字是否写错，或者忘记了 DBWithSerializer
+	codeUnknownSerializer = "orm-40007"
+	// @ErrUnsupportedSerializeType 40008
+	// 序列化器不支持字段的类型，例如 AES 序列化器只支持 string 和 []byte
+	codeUnsupportedSerializeType = "orm-40008"
+	// @ErrTooManyReturnedColumns 40009
+	// 查询返回的列比模型的字段还要多，一般是 SELECT * 的时候表里面多了模型没有的列
+	codeTooManyReturnedColumns = "orm-40009"
+	// @ErrCompoundOperand 40010
+	// UNION 之类的集合操作里面的子查询设置了 ORDER BY, LIMIT 或者 OFFSET
+	// 请在组合之后的 SetSelector 上面设置
+	codeCompoundOperand = "orm-40010"
+	// @ErrLockOutsideTx 40011
+	// FOR UPDATE 和 FOR SHARE 在事务之外没有意义，锁会在语句结束的时候立刻释放
+	// 请使用 BeginTx 开启事务，然后在 Tx 上面构造 Selector
+	codeLockOutsideTx = "orm-40011"
+	// @ErrInvalidLockOption 40012
+	// SKIP LOCKED 和 NOWAIT 必须和 ForUpdate 或者 ForShare 一起使用，并且只能二选一
+	codeInvalidLockOption = "orm-40012"
+	// @ErrUnsupportedLock 40013
+	// 数据库方言不支持该锁子句，例如 SQLite 不支持 SKIP LOCKED 和 NOWAIT
+	codeUnsupportedLock = "orm-40013"
+	// @ErrMissingParam 40014
+	// 执行 PreparedSelector 的时候，Bind 里面缺少了某个 Param 的值
+	codeMissingParam = "orm-40014"
+	// @ErrUnboundParam 40015
+	// 使用了 Param 的查询没有经过 Prepare 和 Bind 就直接执行了
+	codeUnboundParam = "orm-40015"
+	// @ErrPrepareSharding 40016
+	// 分库分表的模型需要根据参数决定分片，不能提前构造好 SQL，请直接使用 Selector
+	codePrepareSharding = "orm-40016"
+	// @ErrShardingInTx 40017
+	// 分库分表的模型暂时不支持在事务中使用，因为一个事务只能在一个数据库上
+	codeShardingInTx = "orm-40017"
+	// @ErrShardingAggregate 40018
+	// 跨分片的查询不支持 DISTINCT, GROUP BY, HAVING 和聚合函数，因为没有办法在内存里面正确地合并
+	// 请在查询条件里面加上分片键，让查询只落到一个分片上
+	codeShardingAggregate = "orm-40018"
+	// @ErrShardingScatter 40019
+	// 开启了 DBWithStrictSharding，但是查询条件没有办法确定分片，需要广播到全部分片
+	codeShardingScatter = "orm-40019"
+	// @ErrInvalidShardingValue 40020
+	// 分片键的值不合法，例如按照取余分片，但是分片键的值不是整数
+	codeInvalidShardingValue = "orm-40020"
+	// @ErrUnknownDataSource 40021
+	// 分片算法计算出来的库名没有通过 DBWithDataSources 注册
+	codeUnknownDataSource = "orm-40021"
+	// @ErrUnsupportedCompareType 40022
+	// 合并多个分片的结果时，ORDER BY 的字段类型不能在内存里面比较
+	codeUnsupportedCompareType = "orm-40022"
+	// @ErrMissingTenant 40023
+	// 多租户的模型必须在租户范围内读写，但是 ctx 里面没有租户
+	// 请使用 orm.WithTenant 把租户 ID 放进 ctx，一般在 HTTP middleware 里面根据登录信息设置
+	codeMissingTenant = "orm-40023"
+	// @ErrMultipleTenantFields 40024
+	// 一个模型只能有一个租户字段，也就是只能有一个字段打上 orm:"tenant=true" 标签
+	codeMultipleTenantFields = "orm-40024"
+	// @ErrInvalidFilter 40025
+	// 查询字符串里面的 filter 或者 sort 不合法，例如使用了不允许过滤或者排序的字段，或者值的类型不对
+	// 这是调用方的错误，一般直接返回 400，具体的原因见 filter.ValidationError 的 Errors
+	codeInvalidFilter = "orm-40025"
+	// @ErrExplainSharding 40026
+	// 分库分表的模型对应多张物理表，没有办法确定解释哪一条 SQL
+	// 请用 From 指定一张物理表之后再调用 Explain
+	codeExplainSharding = "orm-40026"
+	// @ErrNoPrimaryKey 40027
+	// DeleteEntity, UpdateEntity 和 DeleteByIDs 需要根据主键构造 WHERE，但是模型没有主键
+	// 默认 Id 字段是主键，其它字段需要打上 orm:"primary_key=true" 标签，或者使用 model.WithPrimaryKeys
+	codeNoPrimaryKey = "orm-40027"
+	// @ErrCompositePrimaryKey 40028
+	// DeleteByIDs 只支持单一主键，复合主键请使用 DeleteEntity，或者自己构造 WHERE 条件
+	codeCompositePrimaryKey = "orm-40028"
+	// @ErrUnexpectedResult 40029
+	// middleware 返回的结果类型不对，一般是缓存之类的 middleware 把别的类型的结果当成了这一次查询的结果
+	codeUnexpectedResult = "orm-40029"
+	// @ErrNonUpdatableField 40030
+	// UpdateEntity 不能更新主键、租户字段和分片键
+	// 更新租户字段会把数据挪到别的租户，更新分片键会让数据留在错误的分片上
+	codeNonUpdatableField = "orm-40030"
+	// @ErrCompoundSharding 40031
+	// 分库分表的模型对应多张物理表，集合操作的子查询没有办法确定查询哪一张
+	// 请用 From 指定一张物理表，或者分别查询之后在内存里面合并
+	codeCompoundSharding = "orm-40031"
+	// @ErrCompoundLock 40032
+	// 集合操作的子查询不能使用 FOR UPDATE 和 FOR SHARE，数据库会把锁子句当成整个语句的一部分，或者直接报错
+	// 请对每个子查询单独加锁查询
+	codeCompoundLock = "orm-40032"
+	// @ErrNoUpdatableField 40033
+	// 模型除了主键、租户字段和分片键之外没有别的字段，UpdateEntity 没有东西可以更新
+	codeNoUpdatableField = "orm-40033"
+	// @ErrInvalidShardingConfig 40034
+	// 分片算法的配置不合法，例如 Mod 的 DBCount 或者 TableCount 小于等于 0，Range 没有任何范围
+	codeInvalidShardingConfig = "orm-40034"
+	// @ErrShardingOrderBy 40035
+	// 跨分片的查询在内存里面按照 ORDER BY 合并，排序的字段必须出现在 SELECT 里面，否则读到的都是零值
+	// 请把排序的字段加到 Select 里面，或者不指定 Select
+	codeShardingOrderBy = "orm-40035"
+	// @ErrCompoundCTE 40036
+	// 集合操作只有第一个子查询可以使用 With，它的 WITH 会作用在整个语句上
+	// 后面的子查询再使用 With 会在 UNION 中间生成 WITH，大部分数据库都不支持
+	codeCompoundCTE = "orm-40036"
+
+	// @ErrNoRows 50001
+	// Get 没有找到数据，这是正常的业务情况，一般需要单独处理
+	codeNoRows = "orm-50001"
+	// @ErrInvalidCipherText 50002
+	// 密文长度不对，一般是数据库里面的数据并不是加密之后写入的，或者密钥不对
+	codeInvalidCipherText = "orm-50002"
+	// @ErrDuplicateKey 50101
+	// 违反了主键或者唯一索引，一般是重复插入了数据
+	codeDuplicateKey = "orm-50101"
+	// @ErrForeignKey 50102
+	// 违反了外键约束，例如插入的数据引用了不存在的行，或者删除了被引用的行
+	codeForeignKey = "orm-50102"
+	// @ErrDeadlock 50103
+	// 事务之间发生了死锁，或者等待锁超时，数据库回滚了其中一个事务
+	// 一般重试整个事务就可以了，SQLite 的 SQLITE_BUSY 也归到这一类
+	codeDeadlock = "orm-50103"
+	// @ErrConnection 50104
+	// 和数据库的连接出了问题，例如网络断开、连接被数据库关闭或者数据库文件打不开
+	// 没有办法确定语句有没有执行成功，写操作要谨慎重试
+	codeConnection = "orm-50104"
+)
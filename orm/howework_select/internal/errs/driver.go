@@ -0,0 +1,179 @@
+package errs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+)
+
+// 驱动错误的分类，WrapDriverError 返回的错误可以通过 errors.Is 和它们比较
+var (
+	ErrDuplicateKey = newError(codeDuplicateKey, "违反唯一约束")
+	ErrForeignKey   = newError(codeForeignKey, "违反外键约束")
+	ErrDeadlock     = newError(codeDeadlock, "死锁或者锁冲突")
+	ErrConnection   = newError(codeConnection, "数据库连接错误")
+)
+
+// WrapDriverError 把 MySQL, PostgreSQL 和 SQLite 驱动返回的错误归类
+// 不认识的错误原样返回，认识的错误包装成 *Error，通过 errors.As 仍然可以拿到原本的驱动错误
+// 为了不依赖具体的驱动，这里根据错误的形状判断：
+//   - go-sql-driver/mysql 的 *MySQLError 有 Number 字段
+//   - lib/pq 和 pgx 的错误有 SQLState 方法
+//   - mattn/go-sqlite3 的 Error 有 Code 和 ExtendedCode 字段
+func WrapDriverError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	class := classify(err)
+	if class == nil {
+		return err
+	}
+	c := class.(*Error)
+	return &Error{code: c.code, msg: c.msg, cause: err}
+}
+
+func classify(err error) error {
+	// context.DeadlineExceeded 也实现了 net.Error，但是超时或者取消是调用者放弃了，
+	// 不是连接出了问题，不能归类成 ErrConnection，否则会被重试
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return ErrConnection
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return ErrConnection
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if class := classifyPostgres(e); class != nil {
+			return class
+		}
+		if class := classifyMySQL(e); class != nil {
+			return class
+		}
+		if class := classifySQLite(e); class != nil {
+			return class
+		}
+	}
+	return nil
+}
+
+// classifyMySQL MySQL 的错误码
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func classifyMySQL(err error) error {
+	num, ok := intField(err, "Number")
+	if !ok {
+		// go-sql-driver/mysql 连接失效的时候返回的是 ErrInvalidConn
+		if err.Error() == "invalid connection" {
+			return ErrConnection
+		}
+		return nil
+	}
+	switch num {
+	case 1062, 1586:
+		// ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return ErrDuplicateKey
+	case 1216, 1217, 1451, 1452:
+		// ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED 以及它们的 _2 版本
+		return ErrForeignKey
+	case 1205, 1213:
+		// ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+		return ErrDeadlock
+	case 1040, 1053, 2002, 2003, 2006, 2013:
+		// 连接数太多，服务器正在关闭，以及客户端的连接错误
+		return ErrConnection
+	}
+	return nil
+}
+
+// classifyPostgres PostgreSQL 的 SQLSTATE
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+func classifyPostgres(err error) error {
+	s, ok := err.(interface{ SQLState() string })
+	if !ok {
+		return nil
+	}
+	state := s.SQLState()
+	switch {
+	case state == "23505":
+		// unique_violation
+		return ErrDuplicateKey
+	case state == "23503":
+		// foreign_key_violation
+		return ErrForeignKey
+	case state == "40P01", state == "55P03":
+		// deadlock_detected, lock_not_available
+		return ErrDeadlock
+	case strings.HasPrefix(state, "08"), state == "57P01":
+		// connection_exception 这一类，admin_shutdown
+		return ErrConnection
+	}
+	return nil
+}
+
+// classifySQLite SQLite 的错误码
+// https://www.sqlite.org/rescode.html
+func classifySQLite(err error) error {
+	code, ok := intField(err, "Code")
+	if !ok {
+		return nil
+	}
+	extended, ok := intField(err, "ExtendedCode")
+	if !ok {
+		return nil
+	}
+	switch extended {
+	case 1555, 2067:
+		// SQLITE_CONSTRAINT_PRIMARYKEY, SQLITE_CONSTRAINT_UNIQUE
+		return ErrDuplicateKey
+	case 787:
+		// SQLITE_CONSTRAINT_FOREIGNKEY
+		return ErrForeignKey
+	}
+	switch code {
+	case 5, 6:
+		// SQLITE_BUSY, SQLITE_LOCKED
+		return ErrDeadlock
+	case 14:
+		// SQLITE_CANTOPEN
+		return ErrConnection
+	}
+	return nil
+}
+
+// intField 读取错误结构体里面整数类型的字段
+func intField(err error, name string) (int64, bool) {
+	val := reflect.ValueOf(err)
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return 0, false
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return 0, false
+	}
+	fd := val.FieldByName(name)
+	switch fd.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fd.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(fd.Uint()), true
+	}
+	return 0, false
+}
+
+// NewErrPing 返回数据源 Ping 失败的错误，错误码和 ErrConnection 一样
+func NewErrPing(source string, cause error) error {
+	return &Error{code: codeConnection, msg: fmt.Sprintf("数据源 %s 不可用", source), cause: cause}
+}
@@ -1,53 +1,192 @@
 package errs
 
 import (
-	"errors"
 	"fmt"
 )
 
+// Error 带有错误码的错误，错误码的含义见 code.go 或者生成的 ERRORS.md
+// 错误码相同的两个 Error 通过 errors.Is 判断是相等的，和具体的错误信息无关
+// 包装了驱动错误的 Error 可以通过 errors.As 拿到原本的驱动错误
+type Error struct {
+	code  string
+	msg   string
+	cause error
+}
+
+func newError(code string, msg string) error {
+	return &Error{code: code, msg: msg}
+}
+
+func newErrorf(code string, format string, args ...any) error {
+	return &Error{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Code 返回错误码，例如 orm-40001
+func (e *Error) Code() string {
+	return e.code
+}
+
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.code + ": " + e.msg
+	}
+	return e.code + ": " + e.msg + ": " + e.cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误码相同就认为是同一个错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code == e.code
+}
+
 var (
 	// ErrPointerOnly 只支持一级指针作为输入
 	// 看到这个 error 说明你输入了其它的东西
 	// 我们并不希望用户能够直接使用 err == ErrPointerOnly
 	// 所以放在我们的 internal 包里
-	ErrPointerOnly = errors.New("orm: 只支持一级指针作为输入，例如 *User")
-	ErrNoRows                 = errors.New("orm: 未找到数据")
-	ErrTooManyReturnedColumns = errors.New("eorm: 过多列")
+	ErrPointerOnly            = newError(codePointerOnly, "只支持一级指针作为输入，例如 *User")
+	ErrNoRows                 = newError(codeNoRows, "未找到数据")
+	ErrTooManyReturnedColumns = newError(codeTooManyReturnedColumns, "过多列")
+	// ErrInvalidCipherText 密文长度不对，一般是数据库里面的数据并不是加密后写入的
+	ErrInvalidCipherText = newError(codeInvalidCipherText, "非法密文")
+	// ErrShardingScatter 开启了严格模式，但是查询条件里面没有分片键，需要查询全部分片
+	ErrShardingScatter = newError(codeShardingScatter, "查询条件无法确定分片，严格模式下不允许广播")
+	// ErrShardingInTx 分库分表的模型暂时不支持在事务中使用
+	ErrShardingInTx = newError(codeShardingInTx, "分库分表的模型不支持事务")
+	// ErrShardingAggregate 跨分片的查询暂时不支持 DISTINCT, GROUP BY, HAVING 和聚合函数
+	ErrShardingAggregate = newError(codeShardingAggregate, "不支持跨分片的聚合查询")
+	// ErrCompoundOperand UNION 之类的集合操作里面的子查询不能单独排序和分页
+	ErrCompoundOperand = newError(codeCompoundOperand, "集合操作的子查询不能使用 ORDER BY, LIMIT 和 OFFSET，请在组合之后设置")
+	// ErrCompoundSharding 集合操作的子查询需要用 From 指定分库分表模型的物理表
+	ErrCompoundSharding = newError(codeCompoundSharding, "分库分表的模型请用 From 指定物理表之后再使用集合操作")
+	// ErrCompoundLock 集合操作的子查询不能加锁
+	ErrCompoundLock = newError(codeCompoundLock, "集合操作的子查询不能使用 FOR UPDATE 和 FOR SHARE")
+	// ErrCompoundCTE 集合操作只有第一个子查询可以使用 With
+	ErrCompoundCTE = newError(codeCompoundCTE, "集合操作只有第一个子查询可以使用 With，请把 CTE 放到第一个子查询上")
+	// ErrLockOutsideTx 锁只有在事务里面才有意义，事务提交或者回滚的时候释放
+	ErrLockOutsideTx = newError(codeLockOutsideTx, "FOR UPDATE 和 FOR SHARE 只能在事务中使用")
+	// ErrInvalidLockOption SKIP LOCKED 和 NOWAIT 必须跟在 FOR UPDATE 或者 FOR SHARE 后面，并且不能同时使用
+	ErrInvalidLockOption = newError(codeInvalidLockOption, "SKIP LOCKED 和 NOWAIT 必须和 FOR UPDATE 或者 FOR SHARE 一起使用，并且只能二选一")
+	// ErrPrepareSharding 分库分表的模型需要根据参数决定分片，不能提前构造好 SQL
+	ErrPrepareSharding = newError(codePrepareSharding, "分库分表的模型不支持 Prepare")
+	// ErrMissingTenant 多租户的模型只能在租户范围内读写，ctx 里面必须有租户
+	ErrMissingTenant = newError(codeMissingTenant, "多租户的模型必须在 ctx 里面设置租户")
+	// ErrInvalidFilter 查询字符串里面的过滤或者排序条件不合法
+	ErrInvalidFilter = newError(codeInvalidFilter, "过滤或者排序参数不合法")
+	// ErrExplainSharding 分库分表的模型要指定物理表才能 Explain
+	ErrExplainSharding = newError(codeExplainSharding, "分库分表的模型请用 From 指定物理表之后再 Explain")
 )
 
 // NewErrUnknownField 返回代表未知字段的错误
 // 一般意味着你可能输入的是列名，或者输入了错误的字段名
 // 注意和 NewErrUnknownColumn 区别
 func NewErrUnknownField(fd string) error {
-	return fmt.Errorf("orm: 未知字段 %s", fd)
+	return newErrorf(codeUnknownField, "未知字段 %s", fd)
 }
 
 // NewErrUnknownColumn 返回代表未知列的错误
 // 一般意味着你使用了错误的列名
 // 注意和 NewErrUnknownField 区别
 func NewErrUnknownColumn(col string) error {
-	return fmt.Errorf("orm: 未知列 %s", col)
+	return newErrorf(codeUnknownColumn, "未知列 %s", col)
 }
 
 // NewErrUnsupportedExpressionType 返回一个不支持该 expression 错误信息
 func NewErrUnsupportedExpressionType(exp any) error {
-	return fmt.Errorf("orm: 不支持的表达式 %v", exp)
+	return newErrorf(codeUnsupportedExpressionType, "不支持的表达式 %v", exp)
 }
 
 func NewErrUnsupportedSelectable(exp any) error {
-	return fmt.Errorf("orm: 不支持的目标列 %v", exp)
+	return newErrorf(codeUnsupportedSelectable, "不支持的目标列 %v", exp)
 }
 
-// 后面可以考虑支持错误码
-// func NewErrUnsupportedExpressionType(exp any) error {
-// 	return fmt.Errorf("orm-50001: 不支持的表达式 %v", exp)
-// }
+func NewErrInvalidTagContent(tag string) error {
+	return newErrorf(codeInvalidTagContent, "错误的标签设置: %s", tag)
+}
 
-// 后面还可以考虑用 AST 分析源码，生成错误排除手册，例如
-// @ErrUnsupportedExpressionType 40001
-// 发生该错误，主要是因为传入了不支持的 Expression 的实际类型
-// 一般来说，这是因为中间件
+// NewErrUnknownSerializer 返回代表未注册的序列化器的错误
+// 一般意味着标签 serializer=xxx 里面的名字写错了，或者忘记了注册
+func NewErrUnknownSerializer(name string) error {
+	return newErrorf(codeUnknownSerializer, "未知序列化器 %s", name)
+}
 
-func NewErrInvalidTagContent(tag string) error {
-	return fmt.Errorf("orm: 错误的标签设置: %s", tag)
-}
\ No newline at end of file
+// NewErrUnsupportedSerializeType 返回序列化器不支持该类型的错误
+func NewErrUnsupportedSerializeType(val any) error {
+	return newErrorf(codeUnsupportedSerializeType, "序列化器不支持的类型 %T", val)
+}
+
+// NewErrInvalidShardingValue 返回分片键的值不合法的错误
+// 例如按照取余分片，但是分片键的值不是整数
+func NewErrInvalidShardingValue(key string, val any) error {
+	return newErrorf(codeInvalidShardingValue, "分片键 %s 的值 %v 不合法", key, val)
+}
+
+// NewErrUnknownDataSource 返回未知数据源的错误
+// 一般意味着分片算法计算出来的库名没有通过 DBWithDataSources 注册
+func NewErrUnknownDataSource(name string) error {
+	return newErrorf(codeUnknownDataSource, "未知数据源 %s", name)
+}
+
+// NewErrUnsupportedCompareType 返回合并分片结果时无法排序的类型的错误
+func NewErrUnsupportedCompareType(val any) error {
+	return newErrorf(codeUnsupportedCompareType, "不支持比较的类型 %T", val)
+}
+
+// NewErrMissingParam 返回 PreparedSelector 执行的时候缺少参数的错误
+func NewErrMissingParam(name string) error {
+	return newErrorf(codeMissingParam, "缺少参数 %s", name)
+}
+
+// NewErrUnboundParam 返回 Param 没有经过 PreparedSelector 绑定就直接执行的错误
+func NewErrUnboundParam(name string) error {
+	return newErrorf(codeUnboundParam, "参数 %s 没有绑定，请使用 Prepare", name)
+}
+
+// NewErrUnsupportedLock 返回数据库方言不支持该锁子句的错误
+func NewErrUnsupportedLock(dialect string, clause string) error {
+	return newErrorf(codeUnsupportedLock, "%s 不支持 %s", dialect, clause)
+}
+
+// NewErrMultipleTenantFields 返回模型有多个租户字段的错误
+func NewErrMultipleTenantFields(first, second string) error {
+	return newErrorf(codeMultipleTenantFields, "租户字段 %s 和 %s 只能有一个", first, second)
+}
+
+// NewErrNoPrimaryKey 模型没有主键，不能根据实体构造 WHERE
+func NewErrNoPrimaryKey(table string) error {
+	return newErrorf(codeNoPrimaryKey, "模型 %s 没有主键", table)
+}
+
+// NewErrUnexpectedResult 返回 middleware 返回的结果类型不对的错误，want 是期望的类型，例如 []*main.User
+func NewErrUnexpectedResult(want string, got any) error {
+	return newErrorf(codeUnexpectedResult, "middleware 返回的结果 %T 不是 %s", got, want)
+}
+
+// NewErrNonUpdatableField 返回字段不能被 UpdateEntity 更新的错误，reason 例如 "主键"
+func NewErrNonUpdatableField(field string, reason string) error {
+	return newErrorf(codeNonUpdatableField, "字段 %s 是%s，不能更新", field, reason)
+}
+
+// NewErrNoUpdatableField 返回模型没有可以更新的字段的错误
+func NewErrNoUpdatableField(table string) error {
+	return newErrorf(codeNoUpdatableField, "%s 没有可以更新的字段", table)
+}
+
+// NewErrInvalidShardingConfig 返回分片算法配置不合法的错误，alg 例如 "Mod"
+func NewErrInvalidShardingConfig(alg string, reason string) error {
+	return newErrorf(codeInvalidShardingConfig, "分片算法 %s 配置不合法: %s", alg, reason)
+}
+
+// NewErrShardingOrderBy 返回跨分片排序的字段没有在 SELECT 里面的错误
+func NewErrShardingOrderBy(field string) error {
+	return newErrorf(codeShardingOrderBy, "跨分片排序的字段 %s 必须出现在 SELECT 里面", field)
+}
+
+// NewErrCompositePrimaryKey DeleteByIDs 不支持复合主键
+func NewErrCompositePrimaryKey(table string) error {
+	return newErrorf(codeCompositePrimaryKey, "模型 %s 是复合主键，不能使用 DeleteByIDs", table)
+}
@@ -0,0 +1,170 @@
+package errs
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	err := NewErrUnknownField("Name")
+	assert.Equal(t, "orm-40003: 未知字段 Name", err.Error())
+	// 错误码相同就是同一个错误，和参数无关
+	assert.True(t, errors.Is(err, NewErrUnknownField("Age")))
+	assert.False(t, errors.Is(err, NewErrUnknownColumn("Name")))
+	assert.True(t, errors.Is(fmt.Errorf("wrap: %w", ErrNoRows), ErrNoRows))
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "orm-40003", e.Code())
+}
+
+// mysqlError 模拟 go-sql-driver/mysql 的 *MySQLError
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+// pgError 模拟 pgx 的 *pgconn.PgError
+type pgError struct {
+	Code string
+}
+
+func (e *pgError) Error() string {
+	return "ERROR: " + e.Code
+}
+
+func (e *pgError) SQLState() string {
+	return e.Code
+}
+
+// sqliteError 模拟 mattn/go-sqlite3 的 Error
+type sqliteError struct {
+	Code         int
+	ExtendedCode int
+}
+
+func (e sqliteError) Error() string {
+	return fmt.Sprintf("sqlite error %d", e.ExtendedCode)
+}
+
+func TestWrapDriverError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		wantClass error
+	}{
+		{
+			name:      "mysql duplicate",
+			err:       &mysqlError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			wantClass: ErrDuplicateKey,
+		},
+		{
+			name:      "mysql foreign key",
+			err:       &mysqlError{Number: 1452},
+			wantClass: ErrForeignKey,
+		},
+		{
+			name:      "mysql deadlock",
+			err:       &mysqlError{Number: 1213},
+			wantClass: ErrDeadlock,
+		},
+		{
+			name:      "mysql invalid connection",
+			err:       errors.New("invalid connection"),
+			wantClass: ErrConnection,
+		},
+		{
+			name:      "postgres duplicate",
+			err:       &pgError{Code: "23505"},
+			wantClass: ErrDuplicateKey,
+		},
+		{
+			name:      "postgres foreign key",
+			err:       &pgError{Code: "23503"},
+			wantClass: ErrForeignKey,
+		},
+		{
+			name:      "postgres deadlock",
+			err:       &pgError{Code: "40P01"},
+			wantClass: ErrDeadlock,
+		},
+		{
+			name:      "postgres connection",
+			err:       &pgError{Code: "08006"},
+			wantClass: ErrConnection,
+		},
+		{
+			name:      "sqlite unique",
+			err:       sqliteError{Code: 19, ExtendedCode: 2067},
+			wantClass: ErrDuplicateKey,
+		},
+		{
+			name:      "sqlite foreign key",
+			err:       sqliteError{Code: 19, ExtendedCode: 787},
+			wantClass: ErrForeignKey,
+		},
+		{
+			name:      "sqlite busy",
+			err:       sqliteError{Code: 5, ExtendedCode: 5},
+			wantClass: ErrDeadlock,
+		},
+		{
+			name:      "bad conn",
+			err:       driver.ErrBadConn,
+			wantClass: ErrConnection,
+		},
+		{
+			name:      "net error",
+			err:       &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			wantClass: ErrConnection,
+		},
+		{
+			name:      "wrapped driver error",
+			err:       fmt.Errorf("exec: %w", &mysqlError{Number: 1062}),
+			wantClass: ErrDuplicateKey,
+		},
+		{
+			name: "unknown",
+			err:  &mysqlError{Number: 1064},
+		},
+		{
+			// 它实现了 net.Error，但是不是连接错误
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+		},
+		{
+			name: "canceled",
+			err:  context.Canceled,
+		},
+		{
+			name: "wrapped deadline exceeded",
+			err:  &net.OpError{Op: "read", Err: context.DeadlineExceeded},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WrapDriverError(tc.err)
+			if tc.wantClass == nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.wantClass))
+			// 原本的驱动错误仍然可以拿到
+			assert.True(t, errors.Is(err, tc.err))
+			assert.Contains(t, err.Error(), tc.err.Error())
+		})
+	}
+	assert.Nil(t, WrapDriverError(nil))
+	assert.Equal(t, ErrNoRows, WrapDriverError(ErrNoRows))
+}
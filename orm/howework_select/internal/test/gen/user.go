@@ -0,0 +1,34 @@
+// Package gen 存放 ormfields 生成的代码，用于测试和基准测试
+package gen
+
+import (
+	"database/sql"
+)
+
+//go:generate go run ../../../cmd/ormfields -type=User,Order
+
+// User 是一个比较宽的结构体，用来对比不同 valuer 的性能
+type User struct {
+	Id         uint64
+	FirstName  string
+	LastName   *sql.NullString
+	Email      string `orm:"column=mail"`
+	Age        int8
+	Score      float64
+	Balance    int64
+	Deleted    bool
+	Avatar     []byte
+	CreateTime int64
+	UpdateTime int64
+}
+
+type Order struct {
+	Id       uint64
+	UserId   uint64
+	Amount   int64
+	TenantId uint64 `orm:"tenant=true"`
+}
+
+func (o *Order) TableName() string {
+	return "orders"
+}
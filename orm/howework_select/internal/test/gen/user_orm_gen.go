@@ -0,0 +1,312 @@
+// Code generated by ormfields. DO NOT EDIT.
+
+package gen
+
+import (
+	"database/sql"
+	"reflect"
+	"unsafe"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// UserFields 是 User 的字段，例如 UserFields.Id.EQ(...)
+var UserFields = struct {
+	Id         orm.TypedColumn[uint64]
+	FirstName  orm.TypedColumn[string]
+	LastName   orm.TypedColumn[*sql.NullString]
+	Email      orm.TypedColumn[string]
+	Age        orm.TypedColumn[int8]
+	Score      orm.TypedColumn[float64]
+	Balance    orm.TypedColumn[int64]
+	Deleted    orm.TypedColumn[bool]
+	Avatar     orm.TypedColumn[[]byte]
+	CreateTime orm.TypedColumn[int64]
+	UpdateTime orm.TypedColumn[int64]
+}{
+	Id:         orm.TC[uint64]("Id"),
+	FirstName:  orm.TC[string]("FirstName"),
+	LastName:   orm.TC[*sql.NullString]("LastName"),
+	Email:      orm.TC[string]("Email"),
+	Age:        orm.TC[int8]("Age"),
+	Score:      orm.TC[float64]("Score"),
+	Balance:    orm.TC[int64]("Balance"),
+	Deleted:    orm.TC[bool]("Deleted"),
+	Avatar:     orm.TC[[]byte]("Avatar"),
+	CreateTime: orm.TC[int64]("CreateTime"),
+	UpdateTime: orm.TC[int64]("UpdateTime"),
+}
+
+// UserModel 是 User 的元数据，和 model.Registry 解析出来的结果一致
+// 通过 Registry.RegisterModel 注册之后，orm.DB 就不需要再解析 User 了
+var UserModel = func() *model.Model {
+	var t User
+	fields := []*model.Field{
+		{
+			ColName: "id",
+			GoName:  "Id",
+			Type:    reflect.TypeOf(&t.Id).Elem(),
+			Offset:  unsafe.Offsetof(t.Id),
+		},
+		{
+			ColName: "first_name",
+			GoName:  "FirstName",
+			Type:    reflect.TypeOf(&t.FirstName).Elem(),
+			Offset:  unsafe.Offsetof(t.FirstName),
+		},
+		{
+			ColName: "last_name",
+			GoName:  "LastName",
+			Type:    reflect.TypeOf(&t.LastName).Elem(),
+			Offset:  unsafe.Offsetof(t.LastName),
+		},
+		{
+			ColName: "mail",
+			GoName:  "Email",
+			Type:    reflect.TypeOf(&t.Email).Elem(),
+			Offset:  unsafe.Offsetof(t.Email),
+		},
+		{
+			ColName: "age",
+			GoName:  "Age",
+			Type:    reflect.TypeOf(&t.Age).Elem(),
+			Offset:  unsafe.Offsetof(t.Age),
+		},
+		{
+			ColName: "score",
+			GoName:  "Score",
+			Type:    reflect.TypeOf(&t.Score).Elem(),
+			Offset:  unsafe.Offsetof(t.Score),
+		},
+		{
+			ColName: "balance",
+			GoName:  "Balance",
+			Type:    reflect.TypeOf(&t.Balance).Elem(),
+			Offset:  unsafe.Offsetof(t.Balance),
+		},
+		{
+			ColName: "deleted",
+			GoName:  "Deleted",
+			Type:    reflect.TypeOf(&t.Deleted).Elem(),
+			Offset:  unsafe.Offsetof(t.Deleted),
+		},
+		{
+			ColName: "avatar",
+			GoName:  "Avatar",
+			Type:    reflect.TypeOf(&t.Avatar).Elem(),
+			Offset:  unsafe.Offsetof(t.Avatar),
+		},
+		{
+			ColName: "create_time",
+			GoName:  "CreateTime",
+			Type:    reflect.TypeOf(&t.CreateTime).Elem(),
+			Offset:  unsafe.Offsetof(t.CreateTime),
+		},
+		{
+			ColName: "update_time",
+			GoName:  "UpdateTime",
+			Type:    reflect.TypeOf(&t.UpdateTime).Elem(),
+			Offset:  unsafe.Offsetof(t.UpdateTime),
+		},
+	}
+	tableName := "user"
+	res := &model.Model{
+		TableName: tableName,
+		Fields:    fields,
+		FieldMap:  make(map[string]*model.Field, len(fields)),
+		ColumnMap: make(map[string]*model.Field, len(fields)),
+	}
+	for _, fd := range fields {
+		res.FieldMap[fd.GoName] = fd
+		res.ColumnMap[fd.ColName] = fd
+	}
+	res.PrimaryKeys = append(res.PrimaryKeys, res.FieldMap["Id"])
+	return res
+}()
+
+// UserValue 是不依赖反射的 valuer 实现，直接使用字段的指针
+// 使用 orm.DBWithValuer(NewUserValue) 让 orm.DB 使用它
+type UserValue struct {
+	val *User
+}
+
+func NewUserValue(val *User) UserValue {
+	return UserValue{val: val}
+}
+
+func (v UserValue) SetColumns(rows *sql.Rows) error {
+	cs, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	colValues := make([]any, len(cs))
+	for i, c := range cs {
+		switch c {
+		case "id":
+			colValues[i] = &v.val.Id
+		case "first_name":
+			colValues[i] = &v.val.FirstName
+		case "last_name":
+			colValues[i] = &v.val.LastName
+		case "mail":
+			colValues[i] = &v.val.Email
+		case "age":
+			colValues[i] = &v.val.Age
+		case "score":
+			colValues[i] = &v.val.Score
+		case "balance":
+			colValues[i] = &v.val.Balance
+		case "deleted":
+			colValues[i] = &v.val.Deleted
+		case "avatar":
+			colValues[i] = &v.val.Avatar
+		case "create_time":
+			colValues[i] = &v.val.CreateTime
+		case "update_time":
+			colValues[i] = &v.val.UpdateTime
+		default:
+			return orm.NewErrUnknownColumn(c)
+		}
+	}
+	return rows.Scan(colValues...)
+}
+
+func (v UserValue) Field(name string) (any, error) {
+	switch name {
+	case "Id":
+		return v.val.Id, nil
+	case "FirstName":
+		return v.val.FirstName, nil
+	case "LastName":
+		return v.val.LastName, nil
+	case "Email":
+		return v.val.Email, nil
+	case "Age":
+		return v.val.Age, nil
+	case "Score":
+		return v.val.Score, nil
+	case "Balance":
+		return v.val.Balance, nil
+	case "Deleted":
+		return v.val.Deleted, nil
+	case "Avatar":
+		return v.val.Avatar, nil
+	case "CreateTime":
+		return v.val.CreateTime, nil
+	case "UpdateTime":
+		return v.val.UpdateTime, nil
+	default:
+		return nil, orm.NewErrUnknownField(name)
+	}
+}
+
+// OrderFields 是 Order 的字段，例如 OrderFields.Id.EQ(...)
+var OrderFields = struct {
+	Id       orm.TypedColumn[uint64]
+	UserId   orm.TypedColumn[uint64]
+	Amount   orm.TypedColumn[int64]
+	TenantId orm.TypedColumn[uint64]
+}{
+	Id:       orm.TC[uint64]("Id"),
+	UserId:   orm.TC[uint64]("UserId"),
+	Amount:   orm.TC[int64]("Amount"),
+	TenantId: orm.TC[uint64]("TenantId"),
+}
+
+// OrderModel 是 Order 的元数据，和 model.Registry 解析出来的结果一致
+// 通过 Registry.RegisterModel 注册之后，orm.DB 就不需要再解析 Order 了
+var OrderModel = func() *model.Model {
+	var t Order
+	fields := []*model.Field{
+		{
+			ColName: "id",
+			GoName:  "Id",
+			Type:    reflect.TypeOf(&t.Id).Elem(),
+			Offset:  unsafe.Offsetof(t.Id),
+		},
+		{
+			ColName: "user_id",
+			GoName:  "UserId",
+			Type:    reflect.TypeOf(&t.UserId).Elem(),
+			Offset:  unsafe.Offsetof(t.UserId),
+		},
+		{
+			ColName: "amount",
+			GoName:  "Amount",
+			Type:    reflect.TypeOf(&t.Amount).Elem(),
+			Offset:  unsafe.Offsetof(t.Amount),
+		},
+		{
+			ColName: "tenant_id",
+			GoName:  "TenantId",
+			Type:    reflect.TypeOf(&t.TenantId).Elem(),
+			Offset:  unsafe.Offsetof(t.TenantId),
+		},
+	}
+	tableName := (&t).TableName()
+	if tableName == "" {
+		tableName = "order"
+	}
+	res := &model.Model{
+		TableName: tableName,
+		Fields:    fields,
+		FieldMap:  make(map[string]*model.Field, len(fields)),
+		ColumnMap: make(map[string]*model.Field, len(fields)),
+	}
+	for _, fd := range fields {
+		res.FieldMap[fd.GoName] = fd
+		res.ColumnMap[fd.ColName] = fd
+	}
+	res.PrimaryKeys = append(res.PrimaryKeys, res.FieldMap["Id"])
+	res.TenantField = res.FieldMap["TenantId"]
+	return res
+}()
+
+// OrderValue 是不依赖反射的 valuer 实现，直接使用字段的指针
+// 使用 orm.DBWithValuer(NewOrderValue) 让 orm.DB 使用它
+type OrderValue struct {
+	val *Order
+}
+
+func NewOrderValue(val *Order) OrderValue {
+	return OrderValue{val: val}
+}
+
+func (v OrderValue) SetColumns(rows *sql.Rows) error {
+	cs, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	colValues := make([]any, len(cs))
+	for i, c := range cs {
+		switch c {
+		case "id":
+			colValues[i] = &v.val.Id
+		case "user_id":
+			colValues[i] = &v.val.UserId
+		case "amount":
+			colValues[i] = &v.val.Amount
+		case "tenant_id":
+			colValues[i] = &v.val.TenantId
+		default:
+			return orm.NewErrUnknownColumn(c)
+		}
+	}
+	return rows.Scan(colValues...)
+}
+
+func (v OrderValue) Field(name string) (any, error) {
+	switch name {
+	case "Id":
+		return v.val.Id, nil
+	case "UserId":
+		return v.val.UserId, nil
+	case "Amount":
+		return v.val.Amount, nil
+	case "TenantId":
+		return v.val.TenantId, nil
+	default:
+		return nil, orm.NewErrUnknownField(name)
+	}
+}
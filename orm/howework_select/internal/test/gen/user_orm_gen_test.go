@@ -0,0 +1,223 @@
+package gen
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/mattn/go-sqlite3"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/valuer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 生成的元数据必须和 Registry 解析出来的一模一样
+func TestModel(t *testing.T) {
+	r := model.NewRegistry()
+	m, err := r.Get(&User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, m, UserModel)
+
+	m, err = r.Get(&Order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, m, OrderModel)
+}
+
+func TestFields(t *testing.T) {
+	db, err := orm.Open("sqlite3", "file:gen.db?cache=shared&mode=memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := orm.NewSelector[User](db).
+		Select(UserFields.Id.Column(), UserFields.Email.As("email")).
+		Where(UserFields.Age.GT(18), UserFields.Email.EQ("tom@example.com")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &orm.Query{
+		SQL:  "SELECT `id`,`mail` AS `email` FROM `user` WHERE (`age` > ?) AND (`mail` = ?);",
+		Args: []any{int8(18), "tom@example.com"},
+	}, q)
+}
+
+// orm.DB 使用注册的元数据和生成的 valuer
+func TestDBWithValuer(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	r := model.NewRegistry()
+	require.NoError(t, r.RegisterModel(&User{}, UserModel))
+	var created int
+	db, err := orm.OpenDB(mockDB, orm.DBWithRegistry(r),
+		orm.DBWithValuer(func(u *User) UserValue {
+			created++
+			return NewUserValue(u)
+		}))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "mail"}).
+			AddRow(1, "Tom", "tom@example.com").AddRow(2, "Jerry", "jerry@example.com"))
+	res, err := orm.NewSelector[User](db).Where(UserFields.Id.EQ(1)).GetMulti(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*User{
+		{Id: 1, FirstName: "Tom", Email: "tom@example.com"},
+		{Id: 2, FirstName: "Jerry", Email: "jerry@example.com"},
+	}, res)
+	assert.Equal(t, 2, created)
+	m, err := r.Get(&User{})
+	require.NoError(t, err)
+	assert.Same(t, UserModel, m)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserValue_SetColumns(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cs      []string
+		vals    []driver.Value
+		wantVal *User
+		wantErr error
+	}{
+		{
+			name: "normal value",
+			cs:   []string{"id", "first_name", "last_name", "mail", "age"},
+			vals: []driver.Value{[]byte("1"), []byte("Tom"), []byte("Jerry"), []byte("tom@example.com"), []byte("18")},
+			wantVal: &User{
+				Id:        1,
+				FirstName: "Tom",
+				LastName:  &sql.NullString{String: "Jerry", Valid: true},
+				Email:     "tom@example.com",
+				Age:       18,
+			},
+		},
+		{
+			name:    "invalid column",
+			cs:      []string{"id", "email"},
+			vals:    []driver.Value{[]byte("1"), []byte("tom@example.com")},
+			wantErr: errs.NewErrUnknownColumn("email"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := mockRows(t, tc.cs, tc.vals)
+			val := &User{}
+			err := NewUserValue(val).SetColumns(rows)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+var benchColumns = []string{"id", "first_name", "last_name", "mail", "age", "score",
+	"balance", "deleted", "avatar", "create_time", "update_time"}
+
+var benchValues = []driver.Value{[]byte("1"), []byte("Tom"), []byte("Jerry"),
+	[]byte("tom@example.com"), []byte("18"), []byte("99.5"), []byte("10000"),
+	[]byte("false"), []byte("avatar"), []byte("1672502400"), []byte("1672502400")}
+
+var benchCreators = []struct {
+	name   string
+	create func(u *User) valuer.Value
+}{
+	{
+		name: "generated",
+		create: func(u *User) valuer.Value {
+			return NewUserValue(u)
+		},
+	},
+	{
+		name: "unsafe",
+		create: func(u *User) valuer.Value {
+			return valuer.NewUnsafeValue(u, UserModel)
+		},
+	},
+	{
+		name: "reflect",
+		create: func(u *User) valuer.Value {
+			return valuer.NewReflectValue(u, UserModel)
+		},
+	},
+}
+
+// 同一行可以反复 Scan，所以这里只查询一次，避免把 sqlmock 的开销算进去
+func BenchmarkSetColumns(b *testing.B) {
+	for _, c := range benchCreators {
+		b.Run(c.name, func(b *testing.B) {
+			rows := mockRows(b, benchColumns, benchValues)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := c.create(&User{}).SetColumns(rows); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkField 读取所有的字段，模拟 INSERT 一行数据
+func BenchmarkField(b *testing.B) {
+	u := &User{Id: 1, FirstName: "Tom", Email: "tom@example.com", Age: 18}
+	for _, c := range benchCreators {
+		b.Run(c.name, func(b *testing.B) {
+			val := c.create(u)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, fd := range UserModel.Fields {
+					if _, err := val.Field(fd.GoName); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUserValue_Field(t *testing.T) {
+	u := &User{Id: 1, FirstName: "Tom", Email: "tom@example.com", Avatar: []byte("avatar")}
+	for _, c := range benchCreators {
+		t.Run(c.name, func(t *testing.T) {
+			val := c.create(u)
+			res, err := val.Field("Email")
+			require.NoError(t, err)
+			assert.Equal(t, "tom@example.com", res)
+			res, err = val.Field("Avatar")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("avatar"), res)
+			_, err = val.Field("mail")
+			assert.Equal(t, errs.NewErrUnknownField("mail"), err)
+		})
+	}
+}
+
+func mockRows(t testing.TB, cs []string, vals []driver.Value) *sql.Rows {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	mock.ExpectQuery("SELECT *").
+		WillReturnRows(sqlmock.NewRows(cs).AddRow(vals...))
+	rows, err := db.Query("SELECT *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	return rows
+}
@@ -166,3 +166,21 @@ func NewSimpleStruct(id uint64) *SimpleStruct {
 		},
 	}
 }
+
+// SerialStruct 包含需要通过序列化器读写的字段
+type SerialStruct struct {
+	Id     uint64
+	Tags   []string          `orm:"serializer=json"`
+	Attrs  map[string]string `orm:"serializer=json"`
+	Status Status            `orm:"serializer=gob"`
+	Phone  string            `orm:"serializer=aes"`
+}
+
+// Status 模拟业务里面的枚举
+type Status uint8
+
+const (
+	StatusUnknown Status = iota
+	StatusActive
+	StatusBanned
+)
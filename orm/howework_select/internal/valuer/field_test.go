@@ -0,0 +1,105 @@
+package valuer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/test"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValue_Field(t *testing.T) {
+	meta, err := model.NewRegistry().Get(&test.SimpleStruct{})
+	require.NoError(t, err)
+	entity := test.NewSimpleStruct(1)
+
+	testCases := []struct {
+		name    string
+		field   string
+		wantVal any
+		wantErr error
+	}{
+		{
+			name:    "int",
+			field:   "Int",
+			wantVal: entity.Int,
+		},
+		{
+			name:    "pointer",
+			field:   "Int64Ptr",
+			wantVal: entity.Int64Ptr,
+		},
+		{
+			name:    "null string",
+			field:   "NullStringPtr",
+			wantVal: entity.NullStringPtr,
+		},
+		{
+			name:    "byte array",
+			field:   "ByteArray",
+			wantVal: entity.ByteArray,
+		},
+		{
+			// 使用了列名，而不是字段名
+			name:    "unknown field",
+			field:   "int",
+			wantErr: errs.NewErrUnknownField("int"),
+		},
+	}
+
+	creators := map[string]Creator{
+		"unsafe":  NewUnsafeValue,
+		"reflect": NewReflectValue,
+	}
+	for name, creator := range creators {
+		val := creator(entity, meta)
+		for _, tc := range testCases {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				res, err := val.Field(tc.field)
+				assert.Equal(t, tc.wantErr, err)
+				if err != nil {
+					return
+				}
+				assert.Equal(t, tc.wantVal, res)
+			})
+		}
+		t.Run(name+"/all fields", func(t *testing.T) {
+			rv := reflect.ValueOf(entity).Elem()
+			for _, fd := range meta.Fields {
+				res, err := val.Field(fd.GoName)
+				require.NoError(t, err)
+				assert.Equal(t, rv.FieldByName(fd.GoName).Interface(), res, fd.GoName)
+			}
+		})
+	}
+}
+
+// BenchmarkValue_Field 读取 SimpleStruct 所有的字段，模拟 INSERT 一行数据
+// unsafe 根据偏移量读取，reflect 需要按照名字查找字段，字段越多差距越明显
+func BenchmarkValue_Field(b *testing.B) {
+	meta, err := model.NewRegistry().Get(&test.SimpleStruct{})
+	require.NoError(b, err)
+	entity := test.NewSimpleStruct(1)
+
+	b.Run("unsafe", func(b *testing.B) {
+		benchmarkField(b, NewUnsafeValue(entity, meta), meta)
+	})
+	b.Run("reflect", func(b *testing.B) {
+		benchmarkField(b, NewReflectValue(entity, meta), meta)
+	})
+}
+
+func benchmarkField(b *testing.B, val Value, meta *model.Model) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, fd := range meta.Fields {
+			if _, err := val.Field(fd.GoName); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
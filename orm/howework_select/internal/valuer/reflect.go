@@ -42,6 +42,12 @@ func (r reflectValue) SetColumns(rows *sql.Rows) error {
 		if !ok {
 			return errs.NewErrUnknownColumn(c)
 		}
+		// 需要解码的字段直接解码到字段上，不需要后面再 Set
+		if cm.Serializer != nil {
+			dst := r.val.FieldByName(cm.GoName).Addr().Interface()
+			colValues[i] = serialColumn{s: cm.Serializer, dst: dst}
+			continue
+		}
 		val := reflect.New(cm.Type)
 		colValues[i] = val.Interface()
 		colEleValues[i] = val.Elem()
@@ -50,9 +56,19 @@ func (r reflectValue) SetColumns(rows *sql.Rows) error {
 		return err
 	}
 	for i, c := range cs {
+		if !colEleValues[i].IsValid() {
+			continue
+		}
 		cm := r.meta.ColumnMap[c]
 		fd := r.val.FieldByName(cm.GoName)
 		fd.Set(colEleValues[i])
 	}
 	return nil
 }
+
+func (r reflectValue) Field(name string) (any, error) {
+	if _, ok := r.meta.FieldMap[name]; !ok {
+		return nil, errs.NewErrUnknownField(name)
+	}
+	return r.val.FieldByName(name).Interface(), nil
+}
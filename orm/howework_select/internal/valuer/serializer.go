@@ -0,0 +1,33 @@
+package valuer
+
+import (
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
+)
+
+// serialColumn 是打了 serializer 标签的列在 Scan 时候的中间载体
+// 它拿到数据库返回的字节之后，调用 Serializer 解码到 dst 上
+type serialColumn struct {
+	s serializer.Serializer
+	// dst 指向字段的指针
+	dst any
+}
+
+func (c serialColumn) Scan(src any) error {
+	var bs []byte
+	switch val := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		bs = val
+	case string:
+		bs = []byte(val)
+	default:
+		return errs.NewErrUnsupportedSerializeType(src)
+	}
+	// NULL 或者空字符串都保持零值
+	if len(bs) == 0 {
+		return nil
+	}
+	return c.s.Deserialize(bs, c.dst)
+}
@@ -0,0 +1,96 @@
+package valuer
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/test"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue_SetColumns_Serializer(t *testing.T) {
+	aes, err := serializer.NewAES([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := model.NewRegistry()
+	r.RegisterSerializer(serializer.NameAES, aes)
+	meta, err := r.Get(&test.SerialStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := serializer.Gob{}.Serialize(test.StatusBanned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := aes.Serialize("13800138000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		cs      []string
+		vals    []driver.Value
+		wantVal *test.SerialStruct
+	}{
+		{
+			name: "normal value",
+			cs:   []string{"id", "tags", "attrs", "status", "phone"},
+			vals: []driver.Value{
+				[]byte("1"),
+				[]byte(`["a","b"]`),
+				[]byte(`{"k":"v"}`),
+				status,
+				phone,
+			},
+			wantVal: &test.SerialStruct{
+				Id:     1,
+				Tags:   []string{"a", "b"},
+				Attrs:  map[string]string{"k": "v"},
+				Status: test.StatusBanned,
+				Phone:  "13800138000",
+			},
+		},
+		{
+			// NULL 和空字符串都保持零值
+			name: "null",
+			cs:   []string{"id", "tags", "attrs", "status", "phone"},
+			vals: []driver.Value{[]byte("2"), nil, []byte(""), nil, nil},
+			wantVal: &test.SerialStruct{
+				Id: 2,
+			},
+		},
+	}
+
+	creators := map[string]Creator{
+		"unsafe":  NewUnsafeValue,
+		"reflect": NewReflectValue,
+	}
+	for cname, creator := range creators {
+		for _, tc := range testCases {
+			t.Run(cname+" "+tc.name, func(t *testing.T) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer func() { _ = db.Close() }()
+				mock.ExpectQuery("SELECT *").
+					WillReturnRows(sqlmock.NewRows(tc.cs).AddRow(tc.vals...))
+				rows, _ := db.Query("SELECT *")
+				rows.Next()
+
+				val := &test.SerialStruct{}
+				err = creator(val, meta).SetColumns(rows)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tc.wantVal, val)
+			})
+		}
+	}
+}
@@ -40,7 +40,21 @@ func (u unsafeValue) SetColumns(rows *sql.Rows) error {
 		}
 		ptr := unsafe.Pointer(uintptr(u.addr) + cm.Offset)
 		val := reflect.NewAt(cm.Type, ptr)
+		if cm.Serializer != nil {
+			colValues[i] = serialColumn{s: cm.Serializer, dst: val.Interface()}
+			continue
+		}
 		colValues[i] = val.Interface()
 	}
 	return rows.Scan(colValues...)
 }
+
+// Field 根据偏移量直接读取字段，不需要按照名字查找字段
+func (u unsafeValue) Field(name string) (any, error) {
+	fd, ok := u.meta.FieldMap[name]
+	if !ok {
+		return nil, errs.NewErrUnknownField(name)
+	}
+	ptr := unsafe.Pointer(uintptr(u.addr) + fd.Offset)
+	return reflect.NewAt(fd.Type, ptr).Elem().Interface(), nil
+}
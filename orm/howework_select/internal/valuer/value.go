@@ -10,6 +10,9 @@ import (
 type Value interface {
 	// SetColumns 设置新值
 	SetColumns(rows *sql.Rows) error
+	// Field 读取字段的值，name 是字段名，INSERT 和 UPDATE 用它来拿参数
+	// 返回的是字段原本的值，需要序列化的字段由调用者处理
+	Field(name string) (any, error)
 }
 
 type Creator func(val interface{}, meta *model.Model) Value
@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// 语句的类型，middleware 可以根据它区分读写
+const (
+	QueryTypeSelect = "SELECT"
+	QueryTypeInsert = "INSERT"
+	QueryTypeUpdate = "UPDATE"
+	QueryTypeDelete = "DELETE"
+)
+
+// QueryContext 是 middleware 能够拿到的查询信息
+type QueryContext struct {
+	// Type 语句的类型，例如 QueryTypeSelect
+	Type string
+	// Builder 构造语句的 Selector 或者 Deleter
+	Builder QueryBuilder
+	// Model 语句操作的模型
+	Model *model.Model
+	// Query 已经构造好的语句
+	Query *Query
+	// InTx 语句是不是在事务中执行，事务里面的语句失败之后不能单独重试
+	InTx bool
+	// Locked 语句带有 FOR UPDATE 或者 FOR SHARE，必须真的发送到数据库才能加锁
+	Locked bool
+	// Session 执行语句的 DB 或者 Tx，middleware 可以用它执行额外的语句，例如 ExplainQuery
+	Session Session
+}
+
+// QueryResult 是查询的结果
+// 对于 SELECT 来说 Result 是 []*T，对于写操作来说是 sql.Result
+type QueryResult struct {
+	Result any
+	Err    error
+}
+
+type Handler func(ctx context.Context, qc *QueryContext) *QueryResult
+
+type Middleware func(next Handler) Handler
+
+// DBWithMiddlewares 设置 middleware，按照传入的顺序从外到内执行
+func DBWithMiddlewares(mdls ...Middleware) DBOption {
+	return func(db *DB) {
+		db.mdls = mdls
+	}
+}
+
+// queryResult 取出 middleware 返回的结果
+// 类型不对说明某个 middleware 有问题，返回错误，而不是当成没有数据
+func queryResult[R any](qr *QueryResult) (R, error) {
+	res, ok := qr.Result.(R)
+	if !ok {
+		return res, errs.NewErrUnexpectedResult(reflect.TypeOf((*R)(nil)).Elem().String(), qr.Result)
+	}
+	return res, nil
+}
+
+// handle 用 middleware 把 root 包起来再执行
+func (c core) handle(ctx context.Context, qc *QueryContext, root Handler) *QueryResult {
+	for i := len(c.mdls) - 1; i >= 0; i-- {
+		root = c.mdls[i](root)
+	}
+	return root(ctx, qc)
+}
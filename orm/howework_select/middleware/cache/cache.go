@@ -0,0 +1,23 @@
+// Package cache 提供查询结果缓存的 middleware
+//
+// 缓存的 key 由表名、表的版本号、SQL 和参数组成。
+// 任何对同一张表的写操作（Deleter, 以及后续的 Inserter, Updater）执行之后，表的版本号加一，
+// 之前缓存的结果就不会再被读到，只能等着被淘汰或者过期。
+//
+// 注意：
+//   - 缓存的 []*T 会在多个调用者之间共享，不要修改查询结果
+//   - 版本号保存在进程内，所以只能感知到本进程的写操作
+//   - RawQuery 不经过 middleware，不会被缓存，也不会触发失效
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache 缓存查询结果，值是查询返回的 []*T，所以一般是进程内缓存
+type Cache interface {
+	Get(ctx context.Context, key string) (any, bool)
+	// Set 设置缓存，ttl 之后过期
+	Set(ctx context.Context, key string, val any, ttl time.Duration)
+}
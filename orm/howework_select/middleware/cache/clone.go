@@ -0,0 +1,62 @@
+package cache
+
+import "reflect"
+
+// clone 深拷贝查询结果，例如 []*User
+// 缓存里面的结果会返回给很多调用者，如果直接共享指针，一个调用者修改了结果，其它调用者和缓存都会受影响
+func clone(val any) any {
+	if val == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(val)).Interface()
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return res
+	case reflect.Struct:
+		// 先整体复制，私有字段没有办法单独设置，例如 time.Time 里面的字段
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if fd := res.Field(i); fd.CanSet() {
+				fd.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.Set(deepCopy(v.Elem()))
+		return res
+	default:
+		return v
+	}
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	type Profile struct {
+		Nickname *sql.NullString
+		Tags     []string
+		Attrs    map[string]any
+		Created  time.Time
+	}
+	now := time.Now()
+	val := []*Profile{{
+		Nickname: &sql.NullString{String: "Tom", Valid: true},
+		Tags:     []string{"go"},
+		Attrs:    map[string]any{"level": []int{1}},
+		Created:  now,
+	}, nil}
+	res := clone(val).([]*Profile)
+	assert.Equal(t, val, res)
+
+	res[0].Nickname.String = "Jerry"
+	res[0].Tags[0] = "java"
+	res[0].Attrs["level"].([]int)[0] = 2
+	assert.Equal(t, "Tom", val[0].Nickname.String)
+	assert.Equal(t, []string{"go"}, val[0].Tags)
+	assert.Equal(t, []int{1}, val[0].Attrs["level"])
+	assert.Nil(t, clone(nil))
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU 是进程内的 Cache 实现，超过容量的时候淘汰最久没有被使用的数据
+// 过期的数据在读的时候才会被删除
+type LRU struct {
+	mutex    sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+type entry struct {
+	key      string
+	val      any
+	expireAt time.Time
+}
+
+var _ Cache = &LRU{}
+
+// NewLRU 创建一个最多保存 capacity 个结果的 LRU
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+		now:      time.Now,
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, key string) (any, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	ele, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	en := ele.Value.(*entry)
+	if !en.expireAt.After(l.now()) {
+		l.remove(ele)
+		return nil, false
+	}
+	l.ll.MoveToFront(ele)
+	return en.val, true
+}
+
+func (l *LRU) Set(ctx context.Context, key string, val any, ttl time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	expireAt := l.now().Add(ttl)
+	if ele, ok := l.items[key]; ok {
+		en := ele.Value.(*entry)
+		en.val = val
+		en.expireAt = expireAt
+		l.ll.MoveToFront(ele)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&entry{key: key, val: val, expireAt: expireAt})
+	for l.ll.Len() > l.capacity {
+		l.remove(l.ll.Back())
+	}
+}
+
+// Len 返回缓存的数量，包括已经过期但是还没有被删除的
+func (l *LRU) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRU) remove(ele *list.Element) {
+	l.ll.Remove(ele)
+	delete(l.items, ele.Value.(*entry).key)
+}
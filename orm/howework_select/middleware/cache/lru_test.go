@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLRU(2)
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	l.Set(ctx, "k1", 1, time.Minute)
+	l.Set(ctx, "k2", 2, time.Minute)
+	// 访问 k1，之后 k2 是最久没有被使用的
+	val, ok := l.Get(ctx, "k1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+
+	l.Set(ctx, "k3", 3, time.Second)
+	_, ok = l.Get(ctx, "k2")
+	assert.False(t, ok)
+	assert.Equal(t, 2, l.Len())
+
+	// 覆盖已有的 key
+	l.Set(ctx, "k1", 11, time.Minute)
+	val, ok = l.Get(ctx, "k1")
+	assert.True(t, ok)
+	assert.Equal(t, 11, val)
+
+	// k3 过期
+	now = now.Add(time.Second)
+	_, ok = l.Get(ctx, "k3")
+	assert.False(t, ok)
+	assert.Equal(t, 1, l.Len())
+}
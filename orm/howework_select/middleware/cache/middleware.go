@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+type MiddlewareBuilder struct {
+	cache      Cache
+	defaultTTL time.Duration
+	ttls       map[string]time.Duration
+	timeout    time.Duration
+
+	group    group
+	mutex    sync.RWMutex
+	versions map[string]uint64
+}
+
+// NewBuilder 默认所有模型的查询结果都缓存一分钟
+// 返回的结果是缓存的深拷贝，调用者可以随意修改
+func NewBuilder(c Cache) *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		cache:      c,
+		defaultTTL: time.Minute,
+		timeout:    10 * time.Second,
+		ttls:       make(map[string]time.Duration),
+		versions:   make(map[string]uint64),
+	}
+}
+
+// DefaultTTL 设置没有单独设置过期时间的模型的过期时间，小于等于 0 则不缓存
+func (b *MiddlewareBuilder) DefaultTTL(ttl time.Duration) *MiddlewareBuilder {
+	b.defaultTTL = ttl
+	return b
+}
+
+// TTL 单独设置某个模型的过期时间，table 是模型的表名，小于等于 0 则不缓存
+func (b *MiddlewareBuilder) TTL(table string, ttl time.Duration) *MiddlewareBuilder {
+	b.ttls[table] = ttl
+	return b
+}
+
+// Timeout 设置合并之后的查询的超时时间，默认是 10 秒
+// 合并之后的查询不使用调用者的 ctx，所以不会因为某一个调用者取消了就失败
+func (b *MiddlewareBuilder) Timeout(timeout time.Duration) *MiddlewareBuilder {
+	b.timeout = timeout
+	return b
+}
+
+func (b *MiddlewareBuilder) Build() orm.Middleware {
+	return func(next orm.Handler) orm.Handler {
+		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+			table := qc.Model.TableName
+			if qc.Type != orm.QueryTypeSelect {
+				res := next(ctx, qc)
+				// 事务里面的写操作要等提交之后才能让缓存失效，
+				// 否则别的会话可能在提交之前把旧的数据缓存到新的版本下面
+				if tx, ok := qc.Session.(*orm.Tx); ok {
+					tx.OnCommit(func() {
+						b.invalidate(table)
+					})
+				} else {
+					b.invalidate(table)
+				}
+				return res
+			}
+			// 事务里面的查询可能读到自己还没有提交的数据，加锁的查询必须发送到数据库，都不能缓存
+			if qc.InTx || qc.Locked {
+				return next(ctx, qc)
+			}
+
+			ttl, ok := b.ttls[table]
+			if !ok {
+				ttl = b.defaultTTL
+			}
+			if ttl <= 0 {
+				return next(ctx, qc)
+			}
+			key := b.key(table, qc)
+			if val, ok := b.cache.Get(ctx, key); ok {
+				return &orm.QueryResult{Result: clone(val)}
+			}
+			res := b.group.do(ctx, key, func() *orm.QueryResult {
+				ctx, cancel := context.WithTimeout(detached{Context: ctx}, b.timeout)
+				defer cancel()
+				res := next(ctx, qc)
+				if res.Err == nil {
+					b.cache.Set(ctx, key, clone(res.Result), ttl)
+				}
+				return res
+			})
+			// 合并的调用者拿到的是同一个结果，每个人都要有自己的拷贝
+			return &orm.QueryResult{Result: clone(res.Result), Err: res.Err}
+		}
+	}
+}
+
+// key 里面带上表的版本号，写操作之后版本号变化，旧的缓存自然失效
+// Builder 的类型里面有结果的类型，映射到同一张表的不同结构体不会共用缓存
+func (b *MiddlewareBuilder) key(table string, qc *orm.QueryContext) string {
+	b.mutex.RLock()
+	version := b.versions[table]
+	b.mutex.RUnlock()
+	return fmt.Sprintf("%s:%d:%T:%s:%#v", table, version, qc.Builder, qc.Query.SQL, qc.Query.Args)
+}
+
+func (b *MiddlewareBuilder) invalidate(table string) {
+	b.mutex.Lock()
+	b.versions[table]++
+	b.mutex.Unlock()
+}
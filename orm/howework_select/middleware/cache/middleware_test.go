@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+type Order struct {
+	Id int64
+}
+
+// UserBrief 和 User 映射到同一张表
+type UserBrief struct {
+	Id int64
+}
+
+func (UserBrief) TableName() string {
+	return "user"
+}
+
+func newDB(t *testing.T, b *MiddlewareBuilder) (*orm.DB, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mockDB.Close() })
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(b.Build()))
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)).TTL("order", 0))
+
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Jerry"))
+	// 查询失败不缓存
+	mock.ExpectQuery("SELECT \\* FROM `user`;").WillReturnError(errors.New("query error"))
+	mock.ExpectQuery("SELECT \\* FROM `user`;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	// 不缓存的模型
+	mock.ExpectQuery("SELECT \\* FROM `order`;").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `order`;").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	for i := 0; i < 2; i++ {
+		res, err := orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).GetMulti(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, res)
+	}
+	// 参数不同，key 也不同
+	u, err := orm.NewSelector[User](db).Where(orm.C("Id").EQ(2)).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 2, Name: "Jerry"}}, u)
+
+	_, err = orm.NewSelector[User](db).GetMulti(ctx)
+	assert.Equal(t, errors.New("query error"), err)
+	_, err = orm.NewSelector[User](db).GetMulti(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = orm.NewSelector[Order](db).GetMulti(ctx)
+		require.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_invalidate(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\? LIMIT \\?;").WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	mock.ExpectQuery("SELECT \\* FROM `order`;").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("DELETE FROM `order`;").WillReturnResult(sqlmock.NewResult(0, 1))
+	// 删除 order 之后，order 要重新查询，user 不受影响
+	mock.ExpectQuery("SELECT \\* FROM `order`;").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).Get(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewSelector[Order](db).GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewDeleter[Order](db).Exec(ctx)
+	require.NoError(t, err)
+
+	orders, err := orm.NewSelector[Order](db).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Order{}, orders)
+	u, err := orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &User{Id: 1, Name: "Tom"}, u)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_singleflight(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+	// 只会查询一次
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\? LIMIT \\?;").WithArgs(1, 1).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).Get(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, &User{Id: 1, Name: "Tom"}, u)
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_singleflightCancel(t *testing.T) {
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+	mock.ExpectQuery("SELECT \\* FROM `user`;").
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+
+	// 第一个调用者取消了，其它调用者还是能拿到结果
+	cancelCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := orm.NewSelector[User](db).GetMulti(cancelCtx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	users, err := orm.NewSelector[User](db).GetMulti(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+	wg.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_panic(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(NewBuilder(NewLRU(16)).Build(),
+		func(next orm.Handler) orm.Handler {
+			return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+				panic("boom")
+			}
+		}))
+	require.NoError(t, err)
+	_, err = orm.NewSelector[User](db).GetMulti(context.Background())
+	assert.EqualError(t, err, "cache: 查询的时候 panic: boom")
+}
+
+func TestMiddlewareBuilder_clone(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+	mock.ExpectQuery("SELECT \\* FROM `user`;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+
+	// 修改查询结果不会影响缓存
+	users, err := orm.NewSelector[User](db).GetMulti(ctx)
+	require.NoError(t, err)
+	users[0].Name = "Jerry"
+	users, err = orm.NewSelector[User](db).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+	users[0].Name = "Jerry"
+	users, err = orm.NewSelector[User](db).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_sameTable(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+	mock.ExpectQuery("SELECT \\* FROM `user`;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	mock.ExpectQuery("SELECT \\* FROM `user`;").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	users, err := orm.NewSelector[User](db).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+	// SQL 一样，但是结果的类型不一样，不能用 User 的缓存
+	briefs, err := orm.NewSelector[UserBrief](db).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*UserBrief{{Id: 1}}, briefs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareBuilder_tx(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newDB(t, NewBuilder(NewLRU(16)))
+	selectUser := "SELECT \\* FROM `user` WHERE `id` = \\?;"
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom")
+	}
+
+	mock.ExpectQuery(selectUser).WillReturnRows(userRows())
+	// 事务里面的查询和加锁的查询都不走缓存
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectUser).WillReturnRows(userRows())
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\? FOR UPDATE;").WillReturnRows(userRows())
+	mock.ExpectExec("DELETE FROM `user` WHERE `id` = \\?;").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+	// 回滚之后缓存还是有效的
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user` WHERE `id` = \\?;").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// 提交之后缓存失效
+	mock.ExpectQuery(selectUser).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	getUser := func(sess orm.Session) ([]*User, error) {
+		return orm.NewSelector[User](sess).Where(orm.C("Id").EQ(1)).GetMulti(ctx)
+	}
+	_, err := getUser(db)
+	require.NoError(t, err)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = getUser(tx)
+	require.NoError(t, err)
+	_, err = orm.NewSelector[User](tx).Where(orm.C("Id").EQ(1)).ForUpdate().GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewDeleter[User](tx).Where(orm.C("Id").EQ(1)).Exec(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	users, err := getUser(db)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = orm.NewDeleter[User](tx).Where(orm.C("Id").EQ(1)).Exec(ctx)
+	require.NoError(t, err)
+	// 还没有提交，其它会话读到的还是提交之前的数据
+	users, err = getUser(db)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, users)
+	require.NoError(t, tx.Commit())
+	users, err = getUser(db)
+	require.NoError(t, err)
+	assert.Equal(t, []*User{}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+// group 把并发的相同查询合并成一次，只有第一个调用者真的查询数据库
+// 查询在单独的 goroutine 里面执行，每个调用者只等待到自己的 ctx 结束
+type group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done chan struct{}
+	res  *orm.QueryResult
+}
+
+func (g *group) do(ctx context.Context, key string, fn func() *orm.QueryResult) *orm.QueryResult {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.res
+	case <-ctx.Done():
+		return &orm.QueryResult{Err: ctx.Err()}
+	}
+}
+
+// run 执行 fn，panic 会转化成错误返回给所有的调用者
+func (g *group) run(key string, c *call, fn func() *orm.QueryResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.res = &orm.QueryResult{Err: fmt.Errorf("cache: 查询的时候 panic: %v", r)}
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(c.done)
+	}()
+	c.res = fn()
+}
+
+// detached 保留 ctx 里面的值，但是不会被取消，也没有截止时间
+// 合并之后的查询不能因为第一个调用者取消了就让所有人都失败
+// go.mod 还是 1.19，所以没有使用 context.WithoutCancel
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package explain 开发环境使用的 middleware，每一种形状的查询在事务外面第一次执行的时候 EXPLAIN 一次，EXPLAIN 失败的话下一次再试
+// 执行计划里面有全表扫描之类的问题的时候输出警告，不要在生产环境使用
+package explain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+// inList IN 后面的参数个数不影响执行计划，统一成一个
+var inList = regexp.MustCompile(`\(\?(,\s*\?)*\)`)
+
+type MiddlewareBuilder struct {
+	logFunc func(msg string)
+	// seen 已经解释过的查询形状
+	seen sync.Map
+}
+
+// NewBuilder 默认使用 log.Println 输出警告
+func NewBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		logFunc: func(msg string) {
+			log.Println(msg)
+		},
+	}
+}
+
+// LogFunc 设置输出警告的方法
+func (b *MiddlewareBuilder) LogFunc(logFunc func(msg string)) *MiddlewareBuilder {
+	b.logFunc = logFunc
+	return b
+}
+
+func (b *MiddlewareBuilder) Build() orm.Middleware {
+	return func(next orm.Handler) orm.Handler {
+		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+			res := next(ctx, qc)
+			// 分库分表的时候 Query 是逻辑表上的 SQL，并不是真的执行的 SQL
+			// 事务里面不 EXPLAIN，PostgreSQL 上 EXPLAIN 失败会让整个事务中止，留到事务外面执行的时候再 EXPLAIN
+			if res.Err != nil || qc.Session == nil || qc.InTx || qc.Model.Sharding != nil {
+				return res
+			}
+			key := shape(qc.Query.SQL)
+			if _, loaded := b.seen.LoadOrStore(key, struct{}{}); loaded {
+				return res
+			}
+			if err := b.explain(ctx, qc); err != nil {
+				// 失败的时候下一次执行再试
+				b.seen.Delete(key)
+			}
+			return res
+		}
+	}
+}
+
+func (b *MiddlewareBuilder) explain(ctx context.Context, qc *orm.QueryContext) error {
+	// 写操作的 EXPLAIN 不能发到从库
+	if qc.Type != orm.QueryTypeSelect {
+		ctx = orm.UseMaster(ctx)
+	}
+	plan, err := orm.ExplainQuery(ctx, qc.Session, qc.Query)
+	if err != nil {
+		b.logFunc(fmt.Sprintf("explain: %s 失败: %v", qc.Query.SQL, err))
+		return err
+	}
+	if warnings := plan.Warnings(); len(warnings) > 0 {
+		b.logFunc(fmt.Sprintf("explain: %s: %s", qc.Query.SQL, strings.Join(warnings, "; ")))
+	}
+	return nil
+}
+
+func shape(query string) string {
+	return inList.ReplaceAllString(query, "(?)")
+}
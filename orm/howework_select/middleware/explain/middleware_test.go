@@ -0,0 +1,110 @@
+package explain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+
+	var logs []string
+	b := NewBuilder().LogFunc(func(msg string) {
+		logs = append(logs, msg)
+	})
+	db, err := orm.OpenDB(mockDB, orm.DBWithDialect(orm.SQLite), orm.DBWithMiddlewares(b.Build()))
+	require.NoError(t, err)
+
+	planCols := []string{"id", "parent", "notused", "detail"}
+	// 第一次执行，需要 EXPLAIN
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `name` IN \\(\\?,\\?\\);").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("EXPLAIN QUERY PLAN SELECT \\* FROM `user` WHERE `name` IN \\(\\?,\\?\\);").
+		WillReturnRows(sqlmock.NewRows(planCols).AddRow(2, 0, 0, "SCAN user"))
+	// IN 的参数个数不一样，但是形状是一样的，不需要 EXPLAIN
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `name` IN \\(\\?,\\?,\\?\\);").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	// 没有问题的执行计划不输出
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("EXPLAIN QUERY PLAN SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows(planCols).AddRow(2, 0, 0, "SEARCH user USING INTEGER PRIMARY KEY (rowid=?)"))
+	// 执行失败的查询不 EXPLAIN
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` > \\?;").WillReturnError(errors.New("mock error"))
+
+	_, err = orm.NewSelector[User](db).Where(orm.C("Name").In("Tom", "Jerry")).GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewSelector[User](db).Where(orm.C("Name").In("Tom", "Jerry", "Spike")).GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = orm.NewSelector[User](db).Where(orm.C("Id").GT(1)).GetMulti(ctx)
+	assert.Equal(t, errors.New("mock error"), err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{
+		"explain: SELECT * FROM `user` WHERE `name` IN (?,?);: 表 user 全表扫描，可能缺少索引",
+	}, logs)
+}
+
+func TestMiddlewareBuilder_retry(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+
+	var logs []string
+	b := NewBuilder().LogFunc(func(msg string) {
+		logs = append(logs, msg)
+	})
+	db, err := orm.OpenDB(mockDB, orm.DBWithDialect(orm.SQLite), orm.DBWithMiddlewares(b.Build()))
+	require.NoError(t, err)
+
+	// 事务里面不 EXPLAIN
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectCommit()
+	// 事务外面第一次执行才 EXPLAIN，失败的时候下一次再试
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("EXPLAIN QUERY PLAN .*").WillReturnError(errors.New("mock error"))
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("EXPLAIN QUERY PLAN .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent", "notused", "detail"}).AddRow(2, 0, 0, "SCAN user"))
+	// 成功之后不再 EXPLAIN
+	mock.ExpectQuery("SELECT \\* FROM `user` WHERE `id` = \\?;").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	err = db.DoTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx *orm.Tx) error {
+		_, err := orm.NewSelector[User](tx).Where(orm.C("Id").EQ(1)).GetMulti(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = orm.NewSelector[User](db).Where(orm.C("Id").EQ(1)).GetMulti(ctx)
+		require.NoError(t, err)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{
+		"explain: SELECT * FROM `user` WHERE `id` = ?; 失败: mock error",
+		"explain: SELECT * FROM `user` WHERE `id` = ?;: 表 user 全表扫描，可能缺少索引",
+	}, logs)
+}
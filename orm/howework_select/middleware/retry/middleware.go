@@ -0,0 +1,37 @@
+// Package retry 提供重试查询的 middleware
+//
+// 只会重试事务之外的 SELECT：
+//   - 写操作重试可能会重复执行，例如连接在返回结果之前断开，但是语句其实已经执行了
+//   - 事务里面的语句失败之后，数据库一般已经回滚了整个事务，需要使用 DB.DoTx 重试整个事务
+package retry
+
+import (
+	"context"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+)
+
+type MiddlewareBuilder struct {
+	policy orm.RetryPolicy
+}
+
+// NewBuilder 按照 policy 重试，policy.Retryable 为 nil 的时候只重试死锁和连接错误
+func NewBuilder(policy orm.RetryPolicy) *MiddlewareBuilder {
+	return &MiddlewareBuilder{policy: policy}
+}
+
+func (b *MiddlewareBuilder) Build() orm.Middleware {
+	return func(next orm.Handler) orm.Handler {
+		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+			if qc.Type != orm.QueryTypeSelect || qc.InTx {
+				return next(ctx, qc)
+			}
+			var res *orm.QueryResult
+			_ = b.policy.Do(ctx, func(ctx context.Context) error {
+				res = next(ctx, qc)
+				return res.Err
+			})
+			return res
+		}
+	}
+}
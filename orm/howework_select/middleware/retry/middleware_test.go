@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+// deadlockError 模拟 go-sql-driver/mysql 的死锁错误
+type deadlockError struct {
+	Number uint16
+}
+
+func (e *deadlockError) Error() string {
+	return "Error 1213: Deadlock found when trying to get lock"
+}
+
+func newDB(t *testing.T) (*orm.DB, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mockDB.Close() })
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(NewBuilder(orm.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}).Build()))
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	ctx := context.Background()
+	deadlock := &deadlockError{Number: 1213}
+	testCases := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		do      func(db *orm.DB) error
+		wantErr error
+	}{
+		{
+			name: "retry select",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `user`;").WillReturnError(deadlock)
+				mock.ExpectQuery("SELECT \\* FROM `user`;").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+			},
+			do: func(db *orm.DB) error {
+				res, err := orm.NewSelector[User](db).GetMulti(ctx)
+				if err == nil {
+					assert.Equal(t, []*User{{Id: 1, Name: "Tom"}}, res)
+				}
+				return err
+			},
+		},
+		{
+			name: "max attempts",
+			mock: func(mock sqlmock.Sqlmock) {
+				for i := 0; i < 3; i++ {
+					mock.ExpectQuery("SELECT \\* FROM `user`;").WillReturnError(deadlock)
+				}
+			},
+			do: func(db *orm.DB) error {
+				_, err := orm.NewSelector[User](db).GetMulti(ctx)
+				return err
+			},
+			wantErr: orm.ErrDeadlock,
+		},
+		{
+			name: "not retryable",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `user`;").WillReturnError(errors.New("syntax error"))
+			},
+			do: func(db *orm.DB) error {
+				_, err := orm.NewSelector[User](db).GetMulti(ctx)
+				return err
+			},
+			wantErr: errors.New("syntax error"),
+		},
+		{
+			// 写操作不重试
+			name: "delete",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM `user`;").WillReturnError(deadlock)
+			},
+			do: func(db *orm.DB) error {
+				_, err := orm.NewDeleter[User](db).Exec(ctx)
+				return err
+			},
+			wantErr: orm.ErrDeadlock,
+		},
+		{
+			// 事务里面的查询不重试
+			name: "in tx",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `user`;").WillReturnError(deadlock)
+				mock.ExpectRollback()
+			},
+			do: func(db *orm.DB) error {
+				tx, err := db.BeginTx(ctx, nil)
+				require.NoError(t, err)
+				defer func() { _ = tx.Rollback() }()
+				_, err = orm.NewSelector[User](tx).GetMulti(ctx)
+				return err
+			},
+			wantErr: orm.ErrDeadlock,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newDB(t)
+			tc.mock(mock)
+			err := tc.do(db)
+			if tc.wantErr == orm.ErrDeadlock {
+				assert.True(t, errors.Is(err, orm.ErrDeadlock))
+			} else {
+				assert.Equal(t, tc.wantErr, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBWithMiddlewares(t *testing.T) {
+	var logs []string
+	mdl := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, qc *QueryContext) *QueryResult {
+				logs = append(logs, name+" "+qc.Type+" "+qc.Query.SQL)
+				return next(ctx, qc)
+			}
+		}
+	}
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB, DBWithMiddlewares(mdl("first"), mdl("second")))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("DELETE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	res, err := NewSelector[TestModel](db).GetMulti(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 1}}, res)
+	_, err = NewDeleter[TestModel](db).Exec(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"first SELECT SELECT * FROM `test_model`;",
+		"second SELECT SELECT * FROM `test_model`;",
+		"first DELETE DELETE FROM `test_model`;",
+		"second DELETE DELETE FROM `test_model`;",
+	}, logs)
+
+	// middleware 可以直接返回结果，不查询数据库
+	db, err = OpenDB(mockDB, DBWithMiddlewares(func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			return &QueryResult{Result: []*TestModel{{Id: 2}}}
+		}
+	}))
+	require.NoError(t, err)
+	m, err := NewSelector[TestModel](db).Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 2}, m)
+
+	// 结果的类型不对的时候返回错误，而不是当成没有数据
+	db, err = OpenDB(mockDB, DBWithMiddlewares(func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			return &QueryResult{Result: []*Category{{Id: 2}}}
+		}
+	}))
+	require.NoError(t, err)
+	_, err = NewSelector[TestModel](db).GetMulti(context.Background())
+	assert.Equal(t, errs.NewErrUnexpectedResult("[]*orm.TestModel", []*Category{{Id: 2}}), err)
+	_, err = NewDeleter[TestModel](db).Exec(context.Background())
+	assert.Equal(t, errs.NewErrUnexpectedResult("sql.Result", []*Category{{Id: 2}}), err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,28 +2,47 @@ package model
 
 import (
 	"reflect"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
 )
 
 type Model struct {
 	// TableName 结构体对应的表名
 	TableName string
+	// Fields 按照字段定义的顺序排列
+	Fields    []*Field
 	FieldMap  map[string]*Field
 	ColumnMap map[string]*Field
+	// PrimaryKeys 主键，复合主键的时候有多个，为空说明模型没有主键
+	PrimaryKeys []*Field
+	// Sharding 分片算法，为 nil 说明没有分库分表
+	Sharding sharding.Algorithm
+	// TenantField 租户字段，为 nil 说明不是多租户的模型
+	TenantField *Field
 }
 
 // Field 字段
 type Field struct {
 	ColName string
-	GoName string
-	Type   reflect.Type
+	GoName  string
+	Type    reflect.Type
 	// Offset 相对于对象起始地址的字段偏移量
 	Offset uintptr
+	// Serializer 不为 nil 的时候，读写该字段都要经过它编解码
+	Serializer serializer.Serializer
 }
 
 // 我们支持的全部标签上的 key 都放在这里
 // 方便用户查找，和我们后期维护
 const (
-	tagKeyColumn = "column"
+	tagKeyColumn     = "column"
+	tagKeySerializer = "serializer"
+	// tagKeyTenant 标记租户字段，例如 orm:"tenant=true"
+	tagKeyTenant = "tenant"
+	// tagKeyPrimaryKey 标记主键，例如 orm:"primary_key=true"
+	// 没有任何字段打上这个标签的时候，Id 字段就是主键
+	tagKeyPrimaryKey = "primary_key"
 )
 
 // 用户自定义一些模型信息的接口，集中放在这里
@@ -32,4 +51,4 @@ const (
 // TableName 用户实现这个接口来返回自定义的表名
 type TableName interface {
 	TableName() string
-}
\ No newline at end of file
+}
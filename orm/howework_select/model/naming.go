@@ -0,0 +1,129 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy 命名策略
+// 在用户没有通过标签、TableName 接口或者 Option 指定的时候，
+// 用它来决定结构体对应的表名和字段对应的列名
+type NamingStrategy interface {
+	// TableName 根据结构体的名字返回表名
+	TableName(structName string) string
+	// ColumnName 根据字段名返回列名
+	ColumnName(fieldName string) string
+}
+
+// UnderscoreNaming 默认的命名策略
+// 每一个大写字母前面都会加下划线，所以 ID 会变成 i_d
+// 保留它主要是为了兼容已有的表
+type UnderscoreNaming struct{}
+
+func (UnderscoreNaming) TableName(structName string) string {
+	return underscoreName(structName)
+}
+
+func (UnderscoreNaming) ColumnName(fieldName string) string {
+	return underscoreName(fieldName)
+}
+
+// SnakeCaseNaming 能够识别缩写的蛇形命名
+// 例如 ID 转化为 id，UserID 转化为 user_id，HTTPServer 转化为 http_server
+type SnakeCaseNaming struct{}
+
+func (SnakeCaseNaming) TableName(structName string) string {
+	return snakeCase(structName)
+}
+
+func (SnakeCaseNaming) ColumnName(fieldName string) string {
+	return snakeCase(fieldName)
+}
+
+// TablePrefix 在 base 的基础上，给表名加上前缀
+// 用户通过 TableName 接口或者 WithTableName 指定的表名不会加前缀
+func TablePrefix(prefix string, base NamingStrategy) NamingStrategy {
+	return prefixNaming{prefix: prefix, NamingStrategy: base}
+}
+
+type prefixNaming struct {
+	prefix string
+	NamingStrategy
+}
+
+func (p prefixNaming) TableName(structName string) string {
+	return p.prefix + p.NamingStrategy.TableName(structName)
+}
+
+// PluralTable 在 base 的基础上，把表名转化为英语复数形式
+// 例如 user 变成 users，category 变成 categories
+func PluralTable(base NamingStrategy) NamingStrategy {
+	return pluralNaming{NamingStrategy: base}
+}
+
+type pluralNaming struct {
+	NamingStrategy
+}
+
+func (p pluralNaming) TableName(structName string) string {
+	return plural(p.NamingStrategy.TableName(structName))
+}
+
+// NamingFunc 用户自定义的命名策略
+// 没有设置的部分会退化为 UnderscoreNaming 的行为
+type NamingFunc struct {
+	Table  func(structName string) string
+	Column func(fieldName string) string
+}
+
+func (n NamingFunc) TableName(structName string) string {
+	if n.Table == nil {
+		return underscoreName(structName)
+	}
+	return n.Table(structName)
+}
+
+func (n NamingFunc) ColumnName(fieldName string) string {
+	if n.Column == nil {
+		return underscoreName(fieldName)
+	}
+	return n.Column(fieldName)
+}
+
+// snakeCase 驼峰转蛇形，连续的大写字母被看做是一个单词
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var sb strings.Builder
+	sb.Grow(len(rs) + 4)
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			// 前一个是小写或者数字，说明新单词开始了，例如 UserId 里面的 I
+			// 前一个是大写，而后一个是小写，说明缩写结束了，例如 HTTPServer 里面的 S
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// plural 只处理常见的英语复数规则，不规则的复数请使用 NamingFunc
+func plural(name string) string {
+	if name == "" {
+		return name
+	}
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"),
+		strings.HasSuffix(name, "z"), strings.HasSuffix(name, "ch"),
+		strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 &&
+		!strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
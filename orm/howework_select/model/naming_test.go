@@ -0,0 +1,150 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamingStrategy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		naming     NamingStrategy
+		structName string
+		fieldName  string
+		wantTable  string
+		wantColumn string
+	}{
+		{
+			// 默认的行为，ID 会变成 i_d
+			name:       "underscore",
+			naming:     UnderscoreNaming{},
+			structName: "UserProfile",
+			fieldName:  "UserID",
+			wantTable:  "user_profile",
+			wantColumn: "user_i_d",
+		},
+		{
+			name:       "snake case",
+			naming:     SnakeCaseNaming{},
+			structName: "UserProfile",
+			fieldName:  "UserID",
+			wantTable:  "user_profile",
+			wantColumn: "user_id",
+		},
+		{
+			name:       "snake case initialism",
+			naming:     SnakeCaseNaming{},
+			structName: "HTTPServer",
+			fieldName:  "ID",
+			wantTable:  "http_server",
+			wantColumn: "id",
+		},
+		{
+			name:       "snake case number",
+			naming:     SnakeCaseNaming{},
+			structName: "Table1Name",
+			fieldName:  "Address2",
+			wantTable:  "table1_name",
+			wantColumn: "address2",
+		},
+		{
+			// 前缀只影响表名
+			name:       "prefix",
+			naming:     TablePrefix("t_", SnakeCaseNaming{}),
+			structName: "UserProfile",
+			fieldName:  "UserID",
+			wantTable:  "t_user_profile",
+			wantColumn: "user_id",
+		},
+		{
+			name:       "plural",
+			naming:     PluralTable(SnakeCaseNaming{}),
+			structName: "Category",
+			fieldName:  "ParentID",
+			wantTable:  "categories",
+			wantColumn: "parent_id",
+		},
+		{
+			name:       "prefix and plural",
+			naming:     TablePrefix("legacy_", PluralTable(SnakeCaseNaming{})),
+			structName: "Box",
+			fieldName:  "Id",
+			wantTable:  "legacy_boxes",
+			wantColumn: "id",
+		},
+		{
+			name: "func",
+			naming: NamingFunc{
+				Table:  strings.ToUpper,
+				Column: strings.ToUpper,
+			},
+			structName: "User",
+			fieldName:  "FirstName",
+			wantTable:  "USER",
+			wantColumn: "FIRSTNAME",
+		},
+		{
+			// 没有设置的部分使用默认行为
+			name:       "empty func",
+			naming:     NamingFunc{},
+			structName: "User",
+			fieldName:  "FirstName",
+			wantTable:  "user",
+			wantColumn: "first_name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantTable, tc.naming.TableName(tc.structName))
+			assert.Equal(t, tc.wantColumn, tc.naming.ColumnName(tc.fieldName))
+		})
+	}
+}
+
+func Test_plural(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want string
+	}{
+		{src: "user", want: "users"},
+		{src: "order", want: "orders"},
+		{src: "address", want: "addresses"},
+		{src: "box", want: "boxes"},
+		{src: "match", want: "matches"},
+		{src: "category", want: "categories"},
+		{src: "day", want: "days"},
+		{src: "", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			assert.Equal(t, tc.want, plural(tc.src))
+		})
+	}
+}
+
+func TestRegistryWithNamingStrategy(t *testing.T) {
+	type UserProfile struct {
+		ID       uint64
+		UserName string `orm:"column=name"`
+	}
+	r := NewRegistry(RegistryWithNamingStrategy(
+		TablePrefix("t_", PluralTable(SnakeCaseNaming{}))))
+	m, err := r.Get(&UserProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "t_user_profiles", m.TableName)
+	assert.Equal(t, "id", m.FieldMap["ID"].ColName)
+	// 标签优先
+	assert.Equal(t, "name", m.FieldMap["UserName"].ColName)
+
+	// TableName 接口优先
+	m, err = r.Get(&CustomTableName{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "custom_table_name_t", m.TableName)
+}
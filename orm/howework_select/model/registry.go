@@ -7,6 +7,8 @@ import (
 	"unicode"
 
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
 )
 
 type Option func(m *Model) error
@@ -17,16 +19,48 @@ type Registry interface {
 	Get(val any) (*Model, error)
 	// Register 注册一个模型
 	Register(val any, opts ...Option) (*Model, error)
+	// RegisterModel 直接注册已经构造好的元数据，例如 ormfields 生成的 XModel，不会再解析标签
+	RegisterModel(val any, m *Model) error
+	// RegisterSerializer 注册一个序列化器，name 就是标签 serializer=name 里面的名字
+	// 只会影响之后解析的模型，所以应该在使用模型之前注册
+	RegisterSerializer(name string, s serializer.Serializer)
 }
 
 // registry 基于标签和接口的实现
 // 目前来看，我们只有一个实现，所以暂时可以维持私有
 type registry struct {
-	models sync.Map
+	models      sync.Map
+	serializers sync.Map
+	// naming 为 nil 的时候使用 UnderscoreNaming
+	naming NamingStrategy
 }
 
-func NewRegistry() Registry {
-	return &registry{}
+type RegistryOption func(r *registry)
+
+// NewRegistry 创建一个 Registry，默认注册了 json 和 gob 两种序列化器
+// aes 因为需要密钥，所以要用户自己注册
+func NewRegistry(opts ...RegistryOption) Registry {
+	res := &registry{
+		naming: UnderscoreNaming{},
+	}
+	res.RegisterSerializer(serializer.NameJSON, serializer.JSON{})
+	res.RegisterSerializer(serializer.NameGob, serializer.Gob{})
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// RegistryWithNamingStrategy 指定命名策略
+// 例如 RegistryWithNamingStrategy(PluralTable(SnakeCaseNaming{}))
+func RegistryWithNamingStrategy(naming NamingStrategy) RegistryOption {
+	return func(r *registry) {
+		r.naming = naming
+	}
+}
+
+func (r *registry) RegisterSerializer(name string, s serializer.Serializer) {
+	r.serializers.Store(name, s)
 }
 
 // Get 查找元数据模型
@@ -55,6 +89,16 @@ func (r *registry) Register(val any, opts ...Option) (*Model, error) {
 	return m, nil
 }
 
+func (r *registry) RegisterModel(val any, m *Model) error {
+	typ := reflect.TypeOf(val)
+	if typ == nil || typ.Kind() != reflect.Ptr ||
+		typ.Elem().Kind() != reflect.Struct {
+		return errs.ErrPointerOnly
+	}
+	r.models.Store(typ, m)
+	return nil
+}
+
 // parseModel 支持从标签中提取自定义设置
 // 标签形式 orm:"key1=value1,key2=value2"
 func (r *registry) parseModel(val any) (*Model, error) {
@@ -65,10 +109,20 @@ func (r *registry) parseModel(val any) (*Model, error) {
 	}
 	typ = typ.Elem()
 
+	naming := r.naming
+	if naming == nil {
+		naming = UnderscoreNaming{}
+	}
+
 	// 获得字段的数量
 	numField := typ.NumField()
+	fields := make([]*Field, 0, numField)
 	fds := make(map[string]*Field, numField)
 	colMap := make(map[string]*Field, numField)
+	var (
+		tenant *Field
+		pks    []*Field
+	)
 	for i := 0; i < numField; i++ {
 		fdType := typ.Field(i)
 		tags, err := r.parseTag(fdType.Tag)
@@ -77,7 +131,7 @@ func (r *registry) parseModel(val any) (*Model, error) {
 		}
 		colName := tags[tagKeyColumn]
 		if colName == "" {
-			colName = underscoreName(fdType.Name)
+			colName = naming.ColumnName(fdType.Name)
 		}
 		f := &Field{
 			ColName: colName,
@@ -85,22 +139,45 @@ func (r *registry) parseModel(val any) (*Model, error) {
 			GoName:  fdType.Name,
 			Offset:  fdType.Offset,
 		}
+		if name := tags[tagKeySerializer]; name != "" {
+			s, ok := r.serializers.Load(name)
+			if !ok {
+				return nil, errs.NewErrUnknownSerializer(name)
+			}
+			f.Serializer = s.(serializer.Serializer)
+		}
+		if tags[tagKeyTenant] == "true" {
+			if tenant != nil {
+				return nil, errs.NewErrMultipleTenantFields(tenant.GoName, f.GoName)
+			}
+			tenant = f
+		}
+		if tags[tagKeyPrimaryKey] == "true" {
+			pks = append(pks, f)
+		}
+		fields = append(fields, f)
 		fds[fdType.Name] = f
 		colMap[colName] = f
 	}
+	if id, ok := fds["Id"]; ok && len(pks) == 0 {
+		pks = []*Field{id}
+	}
 	var tableName string
 	if tn, ok := val.(TableName); ok {
 		tableName = tn.TableName()
 	}
 
 	if tableName == "" {
-		tableName = underscoreName(typ.Name())
+		tableName = naming.TableName(typ.Name())
 	}
 
 	return &Model{
-		TableName: tableName,
-		FieldMap:  fds,
-		ColumnMap: colMap,
+		TableName:   tableName,
+		Fields:      fields,
+		FieldMap:    fds,
+		ColumnMap:   colMap,
+		PrimaryKeys: pks,
+		TenantField: tenant,
 	}, nil
 }
 
@@ -111,8 +188,8 @@ func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
 		return map[string]string{}, nil
 	}
 	// 这个初始化容量就是我们支持的 key 的数量，
-	// 现在只有一个，所以我们初始化为 1
-	res := make(map[string]string, 1)
+	// 现在有 column, serializer, tenant 和 primary_key 四个，所以我们初始化为 4
+	res := make(map[string]string, 4)
 
 	// 接下来就是字符串处理了
 	pairs := strings.Split(ormTag, ",")
@@ -163,3 +240,47 @@ func WithColumnName(field string, columnName string) Option {
 		return nil
 	}
 }
+
+// WithSharding 声明模型的分片算法，分片键必须是模型的字段
+func WithSharding(alg sharding.Algorithm) Option {
+	return func(model *Model) error {
+		if _, ok := model.FieldMap[alg.ShardingKey()]; !ok {
+			return errs.NewErrUnknownField(alg.ShardingKey())
+		}
+		if v, ok := alg.(sharding.Validator); ok {
+			if err := v.Validate(); err != nil {
+				return err
+			}
+		}
+		model.Sharding = alg
+		return nil
+	}
+}
+
+// WithTenantField 声明模型的租户字段，作用和标签 orm:"tenant=true" 一样
+func WithTenantField(field string) Option {
+	return func(model *Model) error {
+		fd, ok := model.FieldMap[field]
+		if !ok {
+			return errs.NewErrUnknownField(field)
+		}
+		model.TenantField = fd
+		return nil
+	}
+}
+
+// WithPrimaryKeys 声明模型的主键，多个字段就是复合主键，作用和标签 orm:"primary_key=true" 一样
+func WithPrimaryKeys(fields ...string) Option {
+	return func(model *Model) error {
+		pks := make([]*Field, 0, len(fields))
+		for _, field := range fields {
+			fd, ok := model.FieldMap[field]
+			if !ok {
+				return errs.NewErrUnknownField(field)
+			}
+			pks = append(pks, fd)
+		}
+		model.PrimaryKeys = pks
+		return nil
+	}
+}
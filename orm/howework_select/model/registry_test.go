@@ -2,11 +2,12 @@ package model
 
 import (
 	"database/sql"
-	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/serializer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,17 +94,174 @@ func TestWithColumnName(t *testing.T) {
 	}
 }
 
+func TestWithSharding(t *testing.T) {
+	testCases := []struct {
+		name    string
+		alg     sharding.Algorithm
+		wantErr error
+	}{
+		{
+			name: "mod",
+			alg:  sharding.Mod{Key: "Id", DBPattern: "db_%d", TablePattern: "test_model_%d", DBCount: 2, TableCount: 4},
+		},
+		{
+			// 分片键必须是字段
+			name:    "invalid key",
+			alg:     sharding.Mod{Key: "id", DBPattern: "db_%d", TablePattern: "test_model_%d", DBCount: 2, TableCount: 4},
+			wantErr: errs.NewErrUnknownField("id"),
+		},
+		{
+			// 配置不合法的时候注册就返回错误，而不是查询的时候 panic
+			name:    "zero mod",
+			alg:     sharding.Mod{Key: "Id"},
+			wantErr: errs.NewErrInvalidShardingConfig("Mod", "DBCount 和 TableCount 必须大于 0"),
+		},
+	}
+
+	r := NewRegistry().(*registry)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := r.Register(&TestModel{}, WithSharding(tc.alg))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.alg, m.Sharding)
+		})
+	}
+}
+
+func TestWithTenantField(t *testing.T) {
+	type Order struct {
+		Id       int64
+		TenantId int64 `orm:"tenant=true"`
+	}
+	type MultiTenant struct {
+		OrgId    int64 `orm:"tenant=true"`
+		TenantId int64 `orm:"tenant=true"`
+	}
+	testCases := []struct {
+		name       string
+		val        any
+		opts       []Option
+		wantTenant string
+		wantErr    error
+	}{
+		{
+			name:       "tag",
+			val:        &Order{},
+			wantTenant: "TenantId",
+		},
+		{
+			name:       "option",
+			val:        &TestModel{},
+			opts:       []Option{WithTenantField("Age")},
+			wantTenant: "Age",
+		},
+		{
+			name: "no tenant",
+			val:  &TestModel{},
+		},
+		{
+			name:    "unknown field",
+			val:     &TestModel{},
+			opts:    []Option{WithTenantField("TenantId")},
+			wantErr: errs.NewErrUnknownField("TenantId"),
+		},
+		{
+			name:    "multiple",
+			val:     &MultiTenant{},
+			wantErr: errs.NewErrMultipleTenantFields("OrgId", "TenantId"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewRegistry().Register(tc.val, tc.opts...)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			if tc.wantTenant == "" {
+				assert.Nil(t, m.TenantField)
+				return
+			}
+			assert.Equal(t, m.FieldMap[tc.wantTenant], m.TenantField)
+		})
+	}
+}
+
+func TestWithPrimaryKeys(t *testing.T) {
+	type OrderItem struct {
+		OrderId int64 `orm:"primary_key=true"`
+		ItemId  int64 `orm:"primary_key=true"`
+		Id      int64
+	}
+	type NoPrimaryKey struct {
+		Name string
+	}
+	testCases := []struct {
+		name            string
+		val             any
+		opts            []Option
+		wantPrimaryKeys []string
+		wantErr         error
+	}{
+		{
+			name:            "default id",
+			val:             &TestModel{},
+			wantPrimaryKeys: []string{"Id"},
+		},
+		{
+			// 标签优先于默认的 Id
+			name:            "composite tag",
+			val:             &OrderItem{},
+			wantPrimaryKeys: []string{"OrderId", "ItemId"},
+		},
+		{
+			name:            "option",
+			val:             &OrderItem{},
+			opts:            []Option{WithPrimaryKeys("Id")},
+			wantPrimaryKeys: []string{"Id"},
+		},
+		{
+			name: "no primary key",
+			val:  &NoPrimaryKey{},
+		},
+		{
+			name:    "unknown field",
+			val:     &TestModel{},
+			opts:    []Option{WithPrimaryKeys("id")},
+			wantErr: errs.NewErrUnknownField("id"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewRegistry().Register(tc.val, tc.opts...)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantPrimaryKeys, goNames(m.PrimaryKeys))
+		})
+	}
+}
+
 func TestRegistry_get(t *testing.T) {
 	testCases := []struct {
 		name      string
 		val       any
 		wantModel *Model
-		wantErr   error
+		// Fields 和 PrimaryKeys 里面的字段就是 FieldMap 里面的字段，所以只比较字段名
+		wantFields      []string
+		wantPrimaryKeys []string
+		wantErr         error
 	}{
 		{
 			name:    "test Model",
 			val:     TestModel{},
-			wantErr: errors.New("orm: 只支持一级指针作为输入，例如 *User"),
+			wantErr: errs.ErrPointerOnly,
 		},
 		{
 			// 指针
@@ -164,6 +322,8 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields:      []string{"Id", "FirstName", "Age", "LastName"},
+			wantPrimaryKeys: []string{"Id"},
 		},
 		{
 			// 多级指针
@@ -173,22 +333,22 @@ func TestRegistry_get(t *testing.T) {
 				val := &TestModel{}
 				return &val
 			}(),
-			wantErr: errors.New("orm: 只支持一级指针作为输入，例如 *User"),
+			wantErr: errs.ErrPointerOnly,
 		},
 		{
 			name:    "map",
 			val:     map[string]string{},
-			wantErr: errors.New("orm: 只支持一级指针作为输入，例如 *User"),
+			wantErr: errs.ErrPointerOnly,
 		},
 		{
 			name:    "slice",
 			val:     []int{},
-			wantErr: errors.New("orm: 只支持一级指针作为输入，例如 *User"),
+			wantErr: errs.ErrPointerOnly,
 		},
 		{
 			name:    "basic type",
 			val:     0,
-			wantErr: errors.New("orm: 只支持一级指针作为输入，例如 *User"),
+			wantErr: errs.ErrPointerOnly,
 		},
 
 		// 标签相关测试用例
@@ -218,6 +378,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"ID"},
 		},
 		{
 			// 如果用户设置了 column，但是传入一个空字符串，那么会用默认的名字
@@ -246,6 +407,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"FirstName"},
 		},
 		{
 			// 如果用户设置了 column，但是没有赋值
@@ -286,6 +448,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"FirstName"},
 		},
 
 		// 利用接口自定义模型信息
@@ -309,6 +472,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"Name"},
 		},
 		{
 			name: "table name ptr",
@@ -330,6 +494,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"Name"},
 		},
 		{
 			name: "empty table name",
@@ -351,6 +516,7 @@ func TestRegistry_get(t *testing.T) {
 					},
 				},
 			},
+			wantFields: []string{"Name"},
 		},
 	}
 
@@ -362,11 +528,105 @@ func TestRegistry_get(t *testing.T) {
 			if err != nil {
 				return
 			}
-			assert.Equal(t, tc.wantModel, m)
+			assert.Equal(t, tc.wantFields, goNames(m.Fields))
+			assert.Equal(t, tc.wantPrimaryKeys, goNames(m.PrimaryKeys))
+			want := *tc.wantModel
+			want.Fields, want.PrimaryKeys = m.Fields, m.PrimaryKeys
+			assert.Equal(t, &want, m)
+		})
+	}
+}
+
+func goNames(fds []*Field) []string {
+	var res []string
+	for _, fd := range fds {
+		res = append(res, fd.GoName)
+	}
+	return res
+}
+
+func TestRegistry_serializer(t *testing.T) {
+	testCases := []struct {
+		name           string
+		val            any
+		field          string
+		wantSerializer serializer.Serializer
+		wantErr        error
+	}{
+		{
+			name: "json",
+			val: func() any {
+				type JsonTag struct {
+					Tags []string `orm:"serializer=json"`
+				}
+				return &JsonTag{}
+			}(),
+			field:          "Tags",
+			wantSerializer: serializer.JSON{},
+		},
+		{
+			name: "with column",
+			val: func() any {
+				type ColumnAndJsonTag struct {
+					Tags []string `orm:"column=tag_list,serializer=gob"`
+				}
+				return &ColumnAndJsonTag{}
+			}(),
+			field:          "Tags",
+			wantSerializer: serializer.Gob{},
+		},
+		{
+			name: "no serializer",
+			val: func() any {
+				type NoSerializer struct {
+					Name string
+				}
+				return &NoSerializer{}
+			}(),
+			field: "Name",
+		},
+		{
+			// aes 需要用户自己注册
+			name: "unknown serializer",
+			val: func() any {
+				type UnknownSerializer struct {
+					Phone string `orm:"serializer=aes"`
+				}
+				return &UnknownSerializer{}
+			}(),
+			wantErr: errs.NewErrUnknownSerializer("aes"),
+		},
+	}
+
+	r := NewRegistry()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := r.Get(tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantSerializer, m.FieldMap[tc.field].Serializer)
 		})
 	}
 }
 
+func TestRegistry_RegisterModel(t *testing.T) {
+	type Prebuilt struct {
+		Id int64
+	}
+	r := NewRegistry()
+	m := &Model{TableName: "prebuilt_table"}
+	assert.Equal(t, errs.ErrPointerOnly, r.RegisterModel(Prebuilt{}, m))
+	assert.Equal(t, errs.ErrPointerOnly, r.RegisterModel(nil, m))
+
+	assert.NoError(t, r.RegisterModel(&Prebuilt{}, m))
+	res, err := r.Get(&Prebuilt{})
+	assert.NoError(t, err)
+	// 直接使用注册的元数据，不会解析结构体
+	assert.Same(t, m, res)
+}
+
 func Test_underscoreName(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -0,0 +1,74 @@
+package orm
+
+const (
+	orderAsc  = "ASC"
+	orderDesc = "DESC"
+
+	nullsFirst = "NULLS FIRST"
+	nullsLast  = "NULLS LAST"
+)
+
+// OrderBy 代表 ORDER BY 里面的一项
+// expr 可以是字段、SELECT 里面的别名、聚合函数或者 RawExpr
+type OrderBy struct {
+	expr  Expression
+	order string
+	// nulls 为空说明使用数据库默认的 NULL 排序
+	nulls string
+}
+
+// Asc 按照字段或者别名升序排序，例如 Asc("Age")
+func Asc(col string) OrderBy {
+	return AscOf(C(col))
+}
+
+// Desc 按照字段或者别名降序排序，例如 Desc("Age")
+func Desc(col string) OrderBy {
+	return DescOf(C(col))
+}
+
+// AscOf 按照任意表达式升序排序，例如 AscOf(Count("Id"))
+func AscOf(e Expression) OrderBy {
+	return OrderBy{expr: e, order: orderAsc}
+}
+
+// DescOf 按照任意表达式降序排序，例如 DescOf(Raw("RAND()"))
+func DescOf(e Expression) OrderBy {
+	return OrderBy{expr: e, order: orderDesc}
+}
+
+// NullsFirst NULL 排在最前面，不支持的数据库会使用 expr IS NULL 模拟
+func (o OrderBy) NullsFirst() OrderBy {
+	o.nulls = nullsFirst
+	return o
+}
+
+// NullsLast NULL 排在最后面，不支持的数据库会使用 expr IS NULL 模拟
+func (o OrderBy) NullsLast() OrderBy {
+	o.nulls = nullsLast
+	return o
+}
+
+func (c Column) Asc() OrderBy {
+	return AscOf(c)
+}
+
+func (c Column) Desc() OrderBy {
+	return DescOf(c)
+}
+
+func (c TypedColumn[V]) Asc() OrderBy {
+	return AscOf(c.col)
+}
+
+func (c TypedColumn[V]) Desc() OrderBy {
+	return DescOf(c.col)
+}
+
+func (a Aggregate) Asc() OrderBy {
+	return AscOf(a)
+}
+
+func (a Aggregate) Desc() OrderBy {
+	return DescOf(a)
+}
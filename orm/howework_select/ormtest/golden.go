@@ -0,0 +1,47 @@
+// Package ormtest 测试使用 ORM 的代码的辅助方法，只应该在测试里面使用
+package ormtest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update-golden", false, "用录制的语句覆盖 golden 文件")
+
+// AssertGolden 把 r 录制的语句和 testdata/测试名.golden 比较，每一行是一条语句
+// golden 文件不存在或者需要更新的时候，执行 go test -update-golden 重新生成，然后检查 diff
+func AssertGolden(t testing.TB, r *orm.Recorder) {
+	t.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+	got := Dump(r)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败，可以使用 -update-golden 生成: %v", err)
+	}
+	assert.Equal(t, string(want), got)
+}
+
+// Dump 把录制的语句格式化成文本，每一行是一条语句
+func Dump(r *orm.Recorder) string {
+	var sb strings.Builder
+	for _, stmt := range r.Statements() {
+		sb.WriteString(stmt.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
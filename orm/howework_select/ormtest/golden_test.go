@@ -0,0 +1,33 @@
+package ormtest
+
+import (
+	"context"
+	"testing"
+
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Id   int64
+	Name string
+	Age  int8
+}
+
+func TestAssertGolden(t *testing.T) {
+	ctx := context.Background()
+	r := orm.NewRecorder().WillReturnRows([]string{"id", "name", "age"}, []any{1, "Tom", 18})
+	db, err := orm.OpenDB(nil, orm.DBWithRecorder(r))
+	require.NoError(t, err)
+
+	err = db.DoTx(ctx, nil, func(ctx context.Context, tx *orm.Tx) error {
+		u, err := orm.NewSelector[User](tx).Where(orm.C("Name").EQ("Tom")).ForUpdate().Get(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = orm.NewDeleter[User](tx).Where(orm.C("Id").EQ(u.Id)).Exec(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	AssertGolden(t, r)
+}
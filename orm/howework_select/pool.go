@@ -0,0 +1,161 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// namedDB 数据源和它的名字，名字的规则和 Statement.Source 一样
+type namedDB struct {
+	name string
+	db   *sql.DB
+}
+
+// sources 返回所有的数据源，顺序是主库、从库、分库分表的数据源（按照名字排序）
+func (db *DB) sources() []namedDB {
+	res := make([]namedDB, 0, 1+len(db.replicas)+len(db.dataSources))
+	res = append(res, namedDB{name: SourceMaster, db: db.db})
+	for i, r := range db.replicas {
+		res = append(res, namedDB{name: sourceReplica + strconv.Itoa(i), db: r.DB})
+	}
+	names := make([]string, 0, len(db.dataSources))
+	for name := range db.dataSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res = append(res, namedDB{name: name, db: db.dataSources[name]})
+	}
+	return res
+}
+
+// dbWithPool 连接池的设置在所有 DBOption 执行之后才生效，
+// 所以和 DBWithReplicas、DBWithDataSources 的顺序无关
+func dbWithPool(fn func(sdb *sql.DB)) DBOption {
+	return func(db *DB) {
+		db.poolOpts = append(db.poolOpts, fn)
+	}
+}
+
+// DBWithMaxOpenConns 设置每个数据源最多打开的连接数，包括主库、从库和分库分表的数据源
+// 小于等于 0 是不限制，也就是 sql.DB 的默认值
+func DBWithMaxOpenConns(n int) DBOption {
+	return dbWithPool(func(sdb *sql.DB) {
+		sdb.SetMaxOpenConns(n)
+	})
+}
+
+// DBWithMaxIdleConns 设置每个数据源最多保留的空闲连接数，sql.DB 默认是 2
+func DBWithMaxIdleConns(n int) DBOption {
+	return dbWithPool(func(sdb *sql.DB) {
+		sdb.SetMaxIdleConns(n)
+	})
+}
+
+// DBWithConnMaxLifetime 设置连接最多可以使用多久，一般要比数据库的 wait_timeout 短
+func DBWithConnMaxLifetime(d time.Duration) DBOption {
+	return dbWithPool(func(sdb *sql.DB) {
+		sdb.SetConnMaxLifetime(d)
+	})
+}
+
+// DBWithConnMaxIdleTime 设置连接最多可以空闲多久
+func DBWithConnMaxIdleTime(d time.Duration) DBOption {
+	return dbWithPool(func(sdb *sql.DB) {
+		sdb.SetConnMaxIdleTime(d)
+	})
+}
+
+// DBWithPing 在 OpenDB 的时候 Ping 所有的数据源，任何一个失败 OpenDB 都会返回错误
+// sql.Open 并不会建立连接，不开启的话 DSN 写错了要到第一次查询才会发现
+func DBWithPing(timeout time.Duration) DBOption {
+	return func(db *DB) {
+		db.pingTimeout = timeout
+	}
+}
+
+// DBWithHealthCheck 每隔 interval 在后台 Ping 一次所有的数据源，Close 的时候停止
+// 每个数据源 Ping 完都会调用 fn，成功的时候 err 为 nil，可以在 fn 里面打日志或者告警
+func DBWithHealthCheck(interval time.Duration, fn func(source string, err error)) DBOption {
+	return func(db *DB) {
+		db.healthInterval = interval
+		db.healthFunc = fn
+	}
+}
+
+// Ping 检查所有的数据源，返回第一个失败的数据源的错误
+// 错误码和 ErrConnection 一样，可以通过 errors.Is 判断
+func (db *DB) Ping(ctx context.Context) error {
+	for _, src := range db.sources() {
+		if err := src.db.PingContext(ctx); err != nil {
+			return errs.NewErrPing(src.name, err)
+		}
+	}
+	return nil
+}
+
+// Stats 返回所有数据源的连接池统计，key 是数据源的名字，例如 master, replica-0, order_db_1
+func (db *DB) Stats() map[string]sql.DBStats {
+	srcs := db.sources()
+	res := make(map[string]sql.DBStats, len(srcs))
+	for _, src := range srcs {
+		res[src.name] = src.db.Stats()
+	}
+	return res
+}
+
+// initPool 在 OpenDB 的最后执行，设置连接池，Ping 并且启动健康检查
+func (db *DB) initPool() error {
+	srcs := db.sources()
+	for _, opt := range db.poolOpts {
+		for _, src := range srcs {
+			opt(src.db)
+		}
+	}
+	if db.pingTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), db.pingTimeout)
+		err := db.Ping(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	if db.healthInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		db.stopHealth = cancel
+		go db.healthCheck(ctx, srcs)
+	}
+	return nil
+}
+
+func (db *DB) healthCheck(ctx context.Context, srcs []namedDB) {
+	ticker := time.NewTicker(db.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, src := range srcs {
+			// 每一次 Ping 最多等一个周期，避免卡住的数据源影响下一轮检查
+			pingCtx, cancel := context.WithTimeout(ctx, db.healthInterval)
+			err := src.db.PingContext(pingCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				err = errs.NewErrPing(src.name, err)
+			}
+			if db.healthFunc != nil {
+				db.healthFunc(src.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Pool(t *testing.T) {
+	master, _ := newMock(t)
+	replica, _ := newMock(t)
+	// 连接池的设置和 DBWithReplicas 的顺序无关
+	db, err := OpenDB(master, DBWithMaxOpenConns(10), DBWithReplicas(Replica{DB: replica}))
+	require.NoError(t, err)
+
+	stats := db.Stats()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, 10, stats[SourceMaster].MaxOpenConnections)
+	assert.Equal(t, 10, stats["replica-0"].MaxOpenConnections)
+
+	db, _, _ = newShardingDB(t, DBWithMaxOpenConns(5))
+	stats = db.Stats()
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 5, stats["order_db_0"].MaxOpenConnections)
+	assert.Equal(t, 5, stats["order_db_1"].MaxOpenConnections)
+}
+
+func TestDB_Ping(t *testing.T) {
+	mockErr := errors.New("mock error")
+	testCases := []struct {
+		name    string
+		mock    func(master, replica sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "ok",
+			mock: func(master, replica sqlmock.Sqlmock) {
+				master.ExpectPing()
+				replica.ExpectPing()
+			},
+		},
+		{
+			name: "master",
+			mock: func(master, replica sqlmock.Sqlmock) {
+				master.ExpectPing().WillReturnError(mockErr)
+			},
+			wantErr: errs.NewErrPing(SourceMaster, mockErr),
+		},
+		{
+			name: "replica",
+			mock: func(master, replica sqlmock.Sqlmock) {
+				master.ExpectPing()
+				replica.ExpectPing().WillReturnError(mockErr)
+			},
+			wantErr: errs.NewErrPing("replica-0", mockErr),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			master, masterMock := newPingMock(t)
+			replica, replicaMock := newPingMock(t)
+			tc.mock(masterMock, replicaMock)
+			_, err := OpenDB(master, DBWithReplicas(Replica{DB: replica}), DBWithPing(time.Second))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrConnection))
+			}
+		})
+	}
+}
+
+func TestDB_HealthCheck(t *testing.T) {
+	mockErr := errors.New("mock error")
+	master, _ := newMock(t)
+	replica, replicaMock := newPingMock(t)
+	replicaMock.ExpectPing().WillReturnError(mockErr)
+
+	type result struct {
+		source string
+		err    error
+	}
+	results := make(chan result, 2)
+	db, err := OpenDB(master, DBWithReplicas(Replica{DB: replica}),
+		DBWithHealthCheck(10*time.Millisecond, func(source string, err error) {
+			select {
+			case results <- result{source: source, err: err}:
+			default:
+			}
+		}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var got []result
+	for len(got) < 2 {
+		select {
+		case r := <-results:
+			got = append(got, r)
+		case <-ctx.Done():
+			t.Fatal("健康检查没有执行")
+		}
+	}
+	// 之后几轮的 Ping 不在 mock 的预期里面，所以这里不检查 Close 的错误
+	_ = db.Close()
+	assert.Equal(t, []result{
+		{source: SourceMaster},
+		{source: "replica-0", err: errs.NewErrPing("replica-0", mockErr)},
+	}, got)
+}
+
+// newPingMock 返回一个会校验 Ping 的 mock
+func newPingMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, mock
+}
@@ -5,12 +5,20 @@ type op string
 
 // 后面可以每次支持新的操作符就加一个
 const (
-	opEQ  = "="
-	opLT  = "<"
-	opGT  = ">"
-	opAND = "AND"
-	opOR  = "OR"
-	opNOT = "NOT"
+	opEQ = "="
+	opLT = "<"
+	opGT = ">"
+	opIN = "IN"
+	// opLike 通配符由用户自己写在参数里面，例如 %tom%
+	opLike = "LIKE"
+	opAND  = "AND"
+	opOR   = "OR"
+	opNOT  = "NOT"
+
+	opAdd = "+"
+	opSub = "-"
+	opMul = "*"
+	opDiv = "/"
 )
 
 func (o op) String() string {
@@ -42,7 +50,6 @@ type Predicate struct {
 
 func (Predicate) expr() {}
 
-
 func Not(p Predicate) Predicate {
 	return Predicate{
 		op:    opNOT,
@@ -64,4 +71,4 @@ func (p Predicate) Or(right Predicate) Predicate {
 		op:    opOR,
 		right: right,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,138 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// NamedParam 命名参数，只能在 PreparedSelector 里面使用
+type NamedParam struct {
+	name string
+}
+
+func (NamedParam) expr() {}
+
+// Param 创建一个命名参数，例如
+//
+//	ps, err := NewSelector[User](db).Where(C("Id").EQ(Param("id"))).Prepare()
+//	u, err := ps.Get(ctx, map[string]any{"id": 12})
+func Param(name string) NamedParam {
+	return NamedParam{name: name}
+}
+
+// Value 没有经过 Prepare 的 NamedParam 会直接被发送给驱动，这个时候返回错误
+func (p NamedParam) Value() (driver.Value, error) {
+	return nil, errs.NewErrUnboundParam(p.name)
+}
+
+// paramPos 记录参数在 Args 里面的位置
+type paramPos struct {
+	idx  int
+	name string
+}
+
+// PreparedSelector 是编译好的 Selector，SQL 只构造一次
+// 每次执行只需要把命名参数替换成真实的值，它是线程安全的
+type PreparedSelector[T any] struct {
+	core
+	sess   Session
+	model  *model.Model
+	sql    string
+	args   []any
+	params []paramPos
+	// locked 编译的时候设置了 ForUpdate 或者 ForShare
+	locked bool
+}
+
+// Prepare 把 Selector 编译成 PreparedSelector，之后不要再修改这个 Selector
+// 分库分表的模型需要根据参数决定发送到哪些分片，所以不支持
+func (s *Selector[T]) Prepare() (*PreparedSelector[T], error) {
+	q, err := s.Build()
+	if err != nil {
+		return nil, err
+	}
+	if s.model.Sharding != nil && s.table == "" {
+		return nil, errs.ErrPrepareSharding
+	}
+	res := &PreparedSelector[T]{
+		core:   s.core,
+		sess:   s.sess,
+		model:  s.model,
+		sql:    q.SQL,
+		args:   q.Args,
+		locked: s.lock.mode != "",
+	}
+	for i, arg := range q.Args {
+		if p, ok := arg.(NamedParam); ok {
+			res.params = append(res.params, paramPos{idx: i, name: p.name})
+		}
+	}
+	return res, nil
+}
+
+// Build 返回编译好的 SQL，Args 里面的命名参数还是 NamedParam
+func (p *PreparedSelector[T]) Build() (*Query, error) {
+	return &Query{SQL: p.sql, Args: p.args}, nil
+}
+
+// Bind 用 params 替换命名参数，返回可以执行的查询
+func (p *PreparedSelector[T]) Bind(params map[string]any) (*Query, error) {
+	args := p.args
+	if len(p.params) > 0 {
+		args = make([]any, len(p.args))
+		copy(args, p.args)
+		for _, pos := range p.params {
+			val, ok := params[pos.name]
+			if !ok {
+				return nil, errs.NewErrMissingParam(pos.name)
+			}
+			args[pos.idx] = val
+		}
+	}
+	return &Query{SQL: p.sql, Args: args}, nil
+}
+
+// Get 返回第一条数据，它不会自动加上 LIMIT 1，所以编译的时候最好自己设置 Limit(1)
+func (p *PreparedSelector[T]) Get(ctx context.Context, params map[string]any) (*T, error) {
+	res, err := p.GetMulti(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNoRows
+	}
+	return res[0], nil
+}
+
+func (p *PreparedSelector[T]) GetMulti(ctx context.Context, params map[string]any) ([]*T, error) {
+	q, err := p.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	if !inTx(p.sess) && p.locked {
+		return nil, errs.ErrLockOutsideTx
+	}
+	qc := &QueryContext{
+		Type:    QueryTypeSelect,
+		Builder: p,
+		Model:   p.model,
+		Query:   q,
+		InTx:    inTx(p.sess),
+		Locked:  p.locked,
+		Session: p.sess,
+	}
+	qr := p.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		res, err := getMulti[T](ctx, p.sess, qc.Query)
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
+	}
+	return queryResult[[]*T](qr)
+}
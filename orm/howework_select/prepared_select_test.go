@@ -0,0 +1,149 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparedSelector_Bind(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		s         *Selector[TestModel]
+		params    map[string]any
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name:      "no params",
+			s:         NewSelector[TestModel](db).Where(C("Id").EQ(1)),
+			wantQuery: &Query{SQL: "SELECT * FROM `test_model` WHERE `id` = ?;", Args: []any{1}},
+		},
+		{
+			name:   "params",
+			s:      NewSelector[TestModel](db).Where(C("Id").EQ(Param("id")), C("Age").GT(18)).Limit(1),
+			params: map[string]any{"id": 12, "unused": 13},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE (`id` = ?) AND (`age` > ?) LIMIT ?;",
+				Args: []any{12, 18, 1},
+			},
+		},
+		{
+			name:   "in",
+			s:      NewSelector[TestModel](db).Where(C("Id").In(Param("a"), Param("b"), Param("a"))),
+			params: map[string]any{"a": 1, "b": 2},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` IN (?,?,?);",
+				Args: []any{1, 2, 1},
+			},
+		},
+		{
+			name:    "missing param",
+			s:       NewSelector[TestModel](db).Where(C("Id").EQ(Param("id"))),
+			params:  map[string]any{"Id": 12},
+			wantErr: errs.NewErrMissingParam("id"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, err := tc.s.Prepare()
+			require.NoError(t, err)
+			q, err := ps.Bind(tc.params)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_Prepare(t *testing.T) {
+	db := memoryDB(t)
+	_, err := NewSelector[TestModel](db).Where(C("Invalid").EQ(Param("id"))).Prepare()
+	assert.Equal(t, errs.NewErrUnknownField("Invalid"), err)
+
+	_, err = db.r.Register(&ShardingOrder{}, model.WithSharding(orderSharding))
+	require.NoError(t, err)
+	_, err = NewSelector[ShardingOrder](db).Where(C("UserId").EQ(Param("uid"))).Prepare()
+	assert.Equal(t, errs.ErrPrepareSharding, err)
+}
+
+func TestPreparedSelector_Get(t *testing.T) {
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ps, err := NewSelector[TestModel](db).Where(C("Id").EQ(Param("id"))).Limit(1).Prepare()
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT \\* FROM `test_model` WHERE `id` = \\? LIMIT \\?;").WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name"}).AddRow(1, "Tom"))
+	mock.ExpectQuery("SELECT \\* FROM `test_model` WHERE `id` = \\? LIMIT \\?;").WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	m, err := ps.Get(context.Background(), map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1, FirstName: "Tom"}, m)
+	_, err = ps.Get(context.Background(), map[string]any{"id": 2})
+	assert.Equal(t, ErrNoRows, err)
+	_, err = ps.Get(context.Background(), nil)
+	assert.Equal(t, errs.NewErrMissingParam("id"), err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPreparedSelector_LockInTx(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	ps, err := NewSelector[TestModel](db).Where(C("Id").EQ(Param("id"))).ForUpdate().Prepare()
+	require.NoError(t, err)
+	_, err = ps.Get(ctx, map[string]any{"id": 1})
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `test_model` WHERE `id` = \\? FOR UPDATE;").
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	ps, err = NewSelector[TestModel](tx).Where(C("Id").EQ(Param("id"))).ForUpdate().Prepare()
+	require.NoError(t, err)
+	m, err := ps.Get(ctx, map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1}, m)
+	require.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParam_unbound(t *testing.T) {
+	db := memoryDB(t)
+	_, err := NewSelector[TestModel](db).Where(C("Id").EQ(Param("id"))).GetMulti(context.Background())
+	assert.ErrorContains(t, err, errs.NewErrUnboundParam("id").Error())
+}
+
+func BenchmarkSelector_Build(b *testing.B) {
+	db, err := Open("sqlite3", "file:bench.db?cache=shared&mode=memory")
+	require.NoError(b, err)
+	b.Run("build", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = NewSelector[TestModel](db).Where(C("Id").EQ(i), C("Age").GT(18)).Limit(1).Build()
+		}
+	})
+	b.Run("prepared", func(b *testing.B) {
+		ps, err := NewSelector[TestModel](db).Where(C("Id").EQ(Param("id")), C("Age").GT(18)).Limit(1).Prepare()
+		require.NoError(b, err)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = ps.Bind(map[string]any{"id": i})
+		}
+	})
+}
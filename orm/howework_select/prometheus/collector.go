@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector 把 DB 所有数据源的 sql.DBStats 暴露给 Prometheus
+// 指标都带有 db 和 source 两个标签，db 是 Build 传入的名字，source 是数据源的名字，
+// 例如 master, replica-0, order_db_1。多个 DB 使用不同的名字注册到同一个 Registry 上就可以了
+type Collector struct {
+	db *orm.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+type CollectorBuilder struct {
+	Namespace   string
+	Subsystem   string
+	ConstLabels map[string]string
+}
+
+// NewBuilder 默认的指标形如 orm_db_in_use_connections
+func NewBuilder() *CollectorBuilder {
+	return &CollectorBuilder{
+		Namespace: "orm",
+		Subsystem: "db",
+	}
+}
+
+func (b *CollectorBuilder) Build(name string, db *orm.DB) *Collector {
+	labels := []string{"source"}
+	constLabels := make(prometheus.Labels, len(b.ConstLabels)+1)
+	for k, v := range b.ConstLabels {
+		constLabels[k] = v
+	}
+	constLabels["db"] = name
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(b.Namespace, b.Subsystem, metric), help, labels, constLabels)
+	}
+	return &Collector{
+		db:                db,
+		maxOpen:           desc("max_open_connections", "连接池允许打开的最大连接数"),
+		open:              desc("open_connections", "已经打开的连接数，包括使用中的和空闲的"),
+		inUse:             desc("in_use_connections", "使用中的连接数"),
+		idle:              desc("idle_connections", "空闲的连接数"),
+		waitCount:         desc("wait_count_total", "等待连接的总次数"),
+		waitDuration:      desc("wait_duration_seconds_total", "等待连接的总时间"),
+		maxIdleClosed:     desc("max_idle_closed_total", "因为超过 MaxIdleConns 关闭的连接数"),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "因为超过 ConnMaxIdleTime 关闭的连接数"),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "因为超过 ConnMaxLifetime 关闭的连接数"),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for source, s := range c.db.Stats() {
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), source)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections), source)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse), source)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle), source)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount), source)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds(), source)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed), source)
+		ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(s.MaxIdleTimeClosed), source)
+		ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed), source)
+	}
+}
@@ -0,0 +1,39 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/oreo0725/geektime-go-camp/orm/howework_select"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	master, _, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := orm.OpenDB(master, orm.DBWithReplicas(orm.Replica{DB: replica}), orm.DBWithMaxOpenConns(10))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	c := NewBuilder().Build("user_db", db)
+	assert.Equal(t, 18, testutil.CollectAndCount(c))
+
+	want := `
+# HELP orm_db_max_open_connections 连接池允许打开的最大连接数
+# TYPE orm_db_max_open_connections gauge
+orm_db_max_open_connections{db="user_db",source="master"} 10
+orm_db_max_open_connections{db="user_db",source="replica-0"} 10
+# HELP orm_db_in_use_connections 使用中的连接数
+# TYPE orm_db_in_use_connections gauge
+orm_db_in_use_connections{db="user_db",source="master"} 0
+orm_db_in_use_connections{db="user_db",source="replica-0"} 0
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(want),
+		"orm_db_max_open_connections", "orm_db_in_use_connections")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RawQuerier 执行原生查询，并且把结果集映射到 T 上
+// 适用于 Selector 表达不了的查询，例如 PRAGMA 或者存储过程
+type RawQuerier[T any] struct {
+	sess Session
+	sql  string
+	args []any
+}
+
+var _ Querier[any] = &RawQuerier[any]{}
+var _ Executor = &RawQuerier[any]{}
+
+// RawQuery 创建一个 RawQuerier，ORM 不会对 query 进行任何处理
+// 除了 PostgreSQL 会把 ? 换成 $n，和其它语句一样
+func RawQuery[T any](sess Session, query string, args ...any) *RawQuerier[T] {
+	return &RawQuerier[T]{
+		sess: sess,
+		sql:  query,
+		args: args,
+	}
+}
+
+func (r *RawQuerier[T]) Build() (*Query, error) {
+	return &Query{
+		SQL:  r.sql,
+		Args: r.args,
+	}, nil
+}
+
+func (r *RawQuerier[T]) Get(ctx context.Context) (*T, error) {
+	q, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	return get[T](ctx, r.sess, q)
+}
+
+func (r *RawQuerier[T]) GetMulti(ctx context.Context) ([]*T, error) {
+	q, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	return getMulti[T](ctx, r.sess, q)
+}
+
+// Exec 执行写操作，总是发送到主库
+func (r *RawQuerier[T]) Exec(ctx context.Context) (sql.Result, error) {
+	q, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	return r.sess.execContext(ctx, q.SQL, q.Args...)
+}
+
+// get 执行查询并且只取第一行，没有数据的时候返回 ErrNoRows
+func get[T any](ctx context.Context, sess Session, q *Query) (*T, error) {
+	rows, err := sess.queryContext(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoRows
+	}
+	c := sess.getCore()
+	t := new(T)
+	meta, err := c.r.Get(t)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.newValue(t, meta).SetColumns(rows); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// getMulti 执行查询并且取全部数据
+func getMulti[T any](ctx context.Context, sess Session, q *Query) ([]*T, error) {
+	rows, err := sess.queryContext(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMulti[T](sess.getCore(), rows)
+}
+
+// scanMulti 把结果集全部映射到 T 上，并且关闭 rows
+func scanMulti[T any](c core, rows *sql.Rows) ([]*T, error) {
+	defer func() { _ = rows.Close() }()
+	meta, err := c.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*T, 0, 8)
+	for rows.Next() {
+		t := new(T)
+		if err = c.newValue(t, meta).SetColumns(rows); err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,102 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawQuerier_Get(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mockDB.Close() }()
+	db, err := OpenDB(mockDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// query error
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+	// no rows
+	mock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// data
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	mock.ExpectQuery("SELECT .*").WithArgs(1).WillReturnRows(rows)
+
+	testCases := []struct {
+		name    string
+		r       *RawQuerier[TestModel]
+		wantErr error
+		wantRes *TestModel
+	}{
+		{
+			name:    "query error",
+			r:       RawQuery[TestModel](db, "SELECT * FROM `test_model`"),
+			wantErr: errors.New("query error"),
+		},
+		{
+			name:    "no rows",
+			r:       RawQuery[TestModel](db, "SELECT * FROM `test_model`"),
+			wantErr: ErrNoRows,
+		},
+		{
+			name: "data",
+			r:    RawQuery[TestModel](db, "SELECT * FROM `test_model` WHERE `id` = ?", 1),
+			wantRes: &TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{String: "Jerry", Valid: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.r.Get(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestRawQuerier_GetMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mockDB.Close() }()
+	db, err := OpenDB(mockDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	rows := sqlmock.NewRows([]string{"id", "first_name"})
+	rows.AddRow("1", "Tom")
+	rows.AddRow("2", "Jerry")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	res, err := RawQuery[TestModel](db, "SELECT `id`, `first_name` FROM `test_model`").
+		GetMulti(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []*TestModel{}, res)
+
+	res, err = RawQuery[TestModel](db, "SELECT `id`, `first_name` FROM `test_model`").
+		GetMulti(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []*TestModel{
+		{Id: 1, FirstName: "Tom"},
+		{Id: 2, FirstName: "Jerry"},
+	}, res)
+}
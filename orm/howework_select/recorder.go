@@ -0,0 +1,463 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 录制的数据源，从库是 replica- 加上下标，分库分表的数据源使用 DBWithDataSources 里面的名字
+const (
+	SourceMaster  = "master"
+	sourceReplica = "replica-"
+)
+
+// Statement 录制下来的一条语句
+type Statement struct {
+	// Source 语句发送到的数据源，例如 master, replica-0, order_db_1
+	Source string
+	SQL    string
+	// Args 是传给驱动的参数，实现了 driver.Valuer 的参数是 Value 的返回值
+	Args []any
+}
+
+// String 例如
+//
+//	SELECT * FROM `user` WHERE `id` = ?; -- args: 1
+//
+// 主库之外的数据源会加上前缀 [replica-0]
+func (s Statement) String() string {
+	var sb strings.Builder
+	if s.Source != SourceMaster {
+		sb.WriteByte('[')
+		sb.WriteString(s.Source)
+		sb.WriteString("] ")
+	}
+	sb.WriteString(s.SQL)
+	if len(s.Args) > 0 {
+		sb.WriteString(" -- args: ")
+		for i, arg := range s.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(formatArg(arg))
+		}
+	}
+	return sb.String()
+}
+
+func formatArg(arg any) string {
+	switch val := arg.(type) {
+	case string:
+		return strconv.Quote(val)
+	case []byte:
+		return strconv.Quote(string(val))
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case nil:
+		return "NULL"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// Recorder 记录所有的语句，但是不会发送到数据库，一般用于测试，例如
+//
+//	r := NewRecorder().WillReturnRows([]string{"id", "name"}, []any{1, "Tom"})
+//	db, err := OpenDB(nil, DBWithRecorder(r))
+//	u, err := NewSelector[User](db).Where(C("Id").EQ(1)).Get(ctx)
+//	r.Statements()
+//
+// 查询和写操作的结果按照 WillXxx 的顺序依次返回，没有设置的时候返回空结果
+//
+// 分库分表的查询会并发发送到各个数据源，顺序是不确定的，
+// 这个时候使用 On 给每个数据源单独设置结果，使用 StatementsOf 断言每个数据源收到的语句
+type Recorder struct {
+	mutex sync.Mutex
+	stmts []Statement
+	// queries 和 execs 的 key 是数据源，空字符串是所有数据源共用的队列
+	queries map[string][]fakeRows
+	execs   map[string][]fakeResult
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]any
+	err  error
+}
+
+type fakeResult struct {
+	lastInsertId int64
+	rowsAffected int64
+	err          error
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		queries: map[string][]fakeRows{},
+		execs:   map[string][]fakeResult{},
+	}
+}
+
+// DBWithRecorder 使用 Recorder 代替真实的数据库，主库、从库和分库分表的数据源都会被替换
+// 这个时候 OpenDB 的 db 参数可以是 nil
+func DBWithRecorder(r *Recorder) DBOption {
+	return func(db *DB) {
+		db.recorder = r
+	}
+}
+
+// useRecorder 在全部 DBOption 执行之后替换数据源，所以和 DBWithReplicas 的顺序无关
+// 被替换的数据源归 DB 所有，和 Close 一样在这里关闭
+func (db *DB) useRecorder() {
+	_ = db.closeSources()
+	db.db = db.recorder.open(SourceMaster)
+	replicas := make([]Replica, len(db.replicas))
+	for i, r := range db.replicas {
+		replicas[i] = Replica{DB: db.recorder.open(sourceReplica + strconv.Itoa(i)), Weight: r.Weight}
+	}
+	db.replicas = replicas
+	if len(db.dataSources) > 0 {
+		dataSources := make(map[string]*sql.DB, len(db.dataSources))
+		for name := range db.dataSources {
+			dataSources[name] = db.recorder.open(name)
+		}
+		db.dataSources = dataSources
+	}
+}
+
+// WillReturnRows 下一条没有设置结果的查询返回这些数据，rows 里面的每一个元素是一行
+func (r *Recorder) WillReturnRows(cols []string, rows ...[]any) *Recorder {
+	r.addQuery("", fakeRows{cols: cols, rows: rows})
+	return r
+}
+
+// WillQueryError 下一条没有设置结果的查询返回 err
+func (r *Recorder) WillQueryError(err error) *Recorder {
+	r.addQuery("", fakeRows{err: err})
+	return r
+}
+
+// WillReturnResult 下一条没有设置结果的写操作返回这个结果
+func (r *Recorder) WillReturnResult(lastInsertId, rowsAffected int64) *Recorder {
+	r.addExec("", fakeResult{lastInsertId: lastInsertId, rowsAffected: rowsAffected})
+	return r
+}
+
+// WillExecError 下一条没有设置结果的写操作返回 err
+func (r *Recorder) WillExecError(err error) *Recorder {
+	r.addExec("", fakeResult{err: err})
+	return r
+}
+
+// On 返回只对 source 这个数据源生效的结果，它们比共用的结果优先，例如
+//
+//	r.On("order_db_0").WillReturnRows(cols, row0)
+//	r.On("order_db_1").WillReturnRows(cols, row1)
+//
+// 同一个数据源里面的多张表仍然是并发查询的，它们会按照不确定的顺序拿到结果
+func (r *Recorder) On(source string) *SourceRecorder {
+	return &SourceRecorder{r: r, source: source}
+}
+
+// SourceRecorder 给一个数据源设置结果，通过 Recorder.On 创建
+type SourceRecorder struct {
+	r      *Recorder
+	source string
+}
+
+// WillReturnRows 这个数据源下一条没有设置结果的查询返回这些数据
+func (s *SourceRecorder) WillReturnRows(cols []string, rows ...[]any) *SourceRecorder {
+	s.r.addQuery(s.source, fakeRows{cols: cols, rows: rows})
+	return s
+}
+
+// WillQueryError 这个数据源下一条没有设置结果的查询返回 err
+func (s *SourceRecorder) WillQueryError(err error) *SourceRecorder {
+	s.r.addQuery(s.source, fakeRows{err: err})
+	return s
+}
+
+// WillReturnResult 这个数据源下一条没有设置结果的写操作返回这个结果
+func (s *SourceRecorder) WillReturnResult(lastInsertId, rowsAffected int64) *SourceRecorder {
+	s.r.addExec(s.source, fakeResult{lastInsertId: lastInsertId, rowsAffected: rowsAffected})
+	return s
+}
+
+// WillExecError 这个数据源下一条没有设置结果的写操作返回 err
+func (s *SourceRecorder) WillExecError(err error) *SourceRecorder {
+	s.r.addExec(s.source, fakeResult{err: err})
+	return s
+}
+
+func (r *Recorder) addQuery(source string, fr fakeRows) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.queries[source] = append(r.queries[source], fr)
+}
+
+func (r *Recorder) addExec(source string, fr fakeResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.execs[source] = append(r.execs[source], fr)
+}
+
+// Statements 返回到目前为止录制的语句，事务会被记录为 BEGIN, COMMIT 和 ROLLBACK
+func (r *Recorder) Statements() []Statement {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	res := make([]Statement, len(r.stmts))
+	copy(res, r.stmts)
+	return res
+}
+
+// StatementsOf 返回发送到 source 的语句，并发的分库分表查询可以用它按照数据源断言
+func (r *Recorder) StatementsOf(source string) []Statement {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var res []Statement
+	for _, stmt := range r.stmts {
+		if stmt.Source == source {
+			res = append(res, stmt)
+		}
+	}
+	return res
+}
+
+// Reset 清空录制的语句和没有用完的结果
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stmts = nil
+	r.queries = map[string][]fakeRows{}
+	r.execs = map[string][]fakeResult{}
+}
+
+func (r *Recorder) open(source string) *sql.DB {
+	return sql.OpenDB(recorderConnector{r: r, source: source})
+}
+
+func (r *Recorder) record(source string, query string, args []driver.NamedValue) {
+	stmt := Statement{Source: source, SQL: query}
+	for _, arg := range args {
+		stmt.Args = append(stmt.Args, arg.Value)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stmts = append(r.stmts, stmt)
+}
+
+func (r *Recorder) query(source string, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r.record(source, query, args)
+	r.mutex.Lock()
+	fr := pop(r.queries, source)
+	r.mutex.Unlock()
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	res := &recorderRows{cols: fr.cols, rows: make([][]driver.Value, 0, len(fr.rows))}
+	for _, row := range fr.rows {
+		vals := make([]driver.Value, len(row))
+		for i, val := range row {
+			v, err := driver.DefaultParameterConverter.ConvertValue(val)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		res.rows = append(res.rows, vals)
+	}
+	return res, nil
+}
+
+func (r *Recorder) exec(source string, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.record(source, query, args)
+	r.mutex.Lock()
+	fr := pop(r.execs, source)
+	r.mutex.Unlock()
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return fr, nil
+}
+
+// pop 先取 source 自己的结果，没有的话再取共用的结果
+func pop[T any](queues map[string][]T, source string) T {
+	for _, key := range []string{source, ""} {
+		if q := queues[key]; len(q) > 0 {
+			queues[key] = q[1:]
+			return q[0]
+		}
+	}
+	var t T
+	return t
+}
+
+func (f fakeResult) LastInsertId() (int64, error) {
+	return f.lastInsertId, nil
+}
+
+func (f fakeResult) RowsAffected() (int64, error) {
+	return f.rowsAffected, nil
+}
+
+// recorderConnector 下面是一个只做记录的驱动，每个数据源一个 connector
+type recorderConnector struct {
+	r      *Recorder
+	source string
+}
+
+func (c recorderConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recorderConn{recorderConnector: c}, nil
+}
+
+func (c recorderConnector) Driver() driver.Driver {
+	return recorderDriver{}
+}
+
+type recorderDriver struct{}
+
+func (recorderDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("Recorder 只能通过 DBWithRecorder 使用")
+}
+
+type recorderConn struct {
+	recorderConnector
+}
+
+var (
+	_ driver.QueryerContext    = &recorderConn{}
+	_ driver.ExecerContext     = &recorderConn{}
+	_ driver.ConnBeginTx       = &recorderConn{}
+	_ driver.NamedValueChecker = &recorderConn{}
+)
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{conn: c, query: query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.r.record(c.source, "BEGIN", nil)
+	return recorderTx{conn: c}, nil
+}
+
+func (c *recorderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.r.query(c.source, query, args)
+}
+
+func (c *recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.r.exec(c.source, query, args)
+}
+
+// CheckNamedValue 保留参数原本的类型，方便断言，只有 driver.Valuer 会被转换
+// 和 database/sql 一样，nil 指针会被当成 NULL
+func (c *recorderConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if rv := reflect.ValueOf(nv.Value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		nv.Value = nil
+		return nil
+	}
+	if vr, ok := nv.Value.(driver.Valuer); ok {
+		val, err := vr.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = val
+	}
+	return nil
+}
+
+type recorderTx struct {
+	conn *recorderConn
+}
+
+func (t recorderTx) Commit() error {
+	t.conn.r.record(t.conn.source, "COMMIT", nil)
+	return nil
+}
+
+func (t recorderTx) Rollback() error {
+	t.conn.r.record(t.conn.source, "ROLLBACK", nil)
+	return nil
+}
+
+type recorderStmt struct {
+	conn  *recorderConn
+	query string
+}
+
+var (
+	_ driver.StmtQueryContext = &recorderStmt{}
+	_ driver.StmtExecContext  = &recorderStmt{}
+)
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+// NumInput 返回 -1，database/sql 不检查参数的个数
+func (s *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.r.exec(s.conn.source, s.query, namedValues(args))
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.r.query(s.conn.source, s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	res := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		res[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return res
+}
+
+func (s *recorderStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.r.exec(s.conn.source, s.query, args)
+}
+
+func (s *recorderStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.r.query(s.conn.source, s.query, args)
+}
+
+type recorderRows struct {
+	cols []string
+	rows [][]driver.Value
+	idx  int
+}
+
+func (r *recorderRows) Columns() []string {
+	return r.cols
+}
+
+func (r *recorderRows) Close() error {
+	return nil
+}
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.idx])
+	r.idx++
+	return nil
+}
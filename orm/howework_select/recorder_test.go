@@ -0,0 +1,155 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder().
+		WillReturnRows([]string{"id", "first_name", "age", "last_name"}, []any{1, "Tom", 18, "Jerry"}).
+		WillQueryError(errors.New("mock error")).
+		WillReturnResult(0, 3)
+	db, err := OpenDB(nil, DBWithRecorder(r))
+	require.NoError(t, err)
+
+	u, err := NewSelector[TestModel](db).Where(C("Id").EQ(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1, FirstName: "Tom", Age: 18, LastName: &sql.NullString{String: "Jerry", Valid: true}}, u)
+
+	_, err = NewSelector[TestModel](db).Where(C("Age").GT(18)).GetMulti(ctx)
+	assert.Equal(t, errors.New("mock error"), err)
+
+	res, err := NewDeleter[TestModel](db).Where(C("Age").LT(18)).Exec(ctx)
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+
+	// 没有设置结果的时候返回空结果
+	_, err = NewSelector[TestModel](db).Get(ctx)
+	assert.Equal(t, ErrNoRows, err)
+
+	assert.Equal(t, []Statement{
+		{Source: SourceMaster, SQL: "SELECT * FROM `test_model` WHERE `id` = ? LIMIT ?;", Args: []any{1, 1}},
+		{Source: SourceMaster, SQL: "SELECT * FROM `test_model` WHERE `age` > ?;", Args: []any{18}},
+		{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `age` < ?;", Args: []any{18}},
+		{Source: SourceMaster, SQL: "SELECT * FROM `test_model` LIMIT ?;", Args: []any{1}},
+	}, r.Statements())
+
+	r.Reset()
+	assert.Empty(t, r.Statements())
+}
+
+func TestRecorder_Tx(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	db, err := OpenDB(nil, DBWithRecorder(r), DBWithStmtCache(10))
+	require.NoError(t, err)
+
+	err = db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+		_, err := NewDeleter[TestModel](tx).Where(C("Id").EQ(1)).Exec(ctx)
+		return err
+	})
+	require.NoError(t, err)
+
+	mockErr := errors.New("mock error")
+	err = db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+		return mockErr
+	})
+	assert.Equal(t, mockErr, err)
+
+	assert.Equal(t, []Statement{
+		{Source: SourceMaster, SQL: "BEGIN"},
+		{Source: SourceMaster, SQL: "DELETE FROM `test_model` WHERE `id` = ?;", Args: []any{1}},
+		{Source: SourceMaster, SQL: "COMMIT"},
+		{Source: SourceMaster, SQL: "BEGIN"},
+		{Source: SourceMaster, SQL: "ROLLBACK"},
+	}, r.Statements())
+}
+
+func TestRecorder_Sources(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	replica, _ := newMock(t)
+	db, _, _ := newShardingDB(t, DBWithReplicas(Replica{DB: replica}), DBWithRecorder(r))
+
+	_, err := NewSelector[TestModel](db).GetMulti(ctx)
+	require.NoError(t, err)
+	_, err = NewSelector[ShardingOrder](db).Where(C("UserId").EQ(3)).GetMulti(ctx)
+	require.NoError(t, err)
+
+	stmts := r.Statements()
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "[replica-0] SELECT * FROM `test_model`;", stmts[0].String())
+	assert.Equal(t, "[order_db_1] SELECT * FROM `order_03` WHERE `user_id` = ?; -- args: 3", stmts[1].String())
+}
+
+func TestRecorder_Broadcast(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	// 每个库一张表，这样每个数据源的结果是确定的
+	db, err := OpenDB(nil, DBWithRecorder(r), DBWithDataSources(map[string]*sql.DB{
+		"order_db_0": nil,
+		"order_db_1": nil,
+	}))
+	require.NoError(t, err)
+	_, err = db.r.Register(&ShardingOrder{}, model.WithSharding(sharding.Mod{
+		Key: "UserId", DBPattern: "order_db_%d", TablePattern: "order_%02d", DBCount: 2, TableCount: 2,
+	}))
+	require.NoError(t, err)
+
+	r.On("order_db_0").
+		WillReturnRows([]string{"id", "user_id"}, []any{2, 2}).
+		WillReturnResult(0, 1)
+	r.On("order_db_1").
+		WillReturnRows([]string{"id", "user_id"}, []any{1, 1}, []any{3, 1}).
+		WillExecError(errors.New("mock error"))
+
+	res, err := NewSelector[ShardingOrder](db).OrderBy(Asc("Id")).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*ShardingOrder{{Id: 1, UserId: 1}, {Id: 2, UserId: 2}, {Id: 3, UserId: 1}}, res)
+
+	_, err = NewDeleter[ShardingOrder](db).Exec(ctx)
+	assert.Equal(t, errors.New("mock error"), err)
+
+	assert.Equal(t, []Statement{
+		{Source: "order_db_0", SQL: "SELECT * FROM `order_00` ORDER BY `id` ASC;"},
+		{Source: "order_db_0", SQL: "DELETE FROM `order_00`;"},
+	}, r.StatementsOf("order_db_0"))
+	assert.Equal(t, []Statement{
+		{Source: "order_db_1", SQL: "SELECT * FROM `order_01` ORDER BY `id` ASC;"},
+		{Source: "order_db_1", SQL: "DELETE FROM `order_01`;"},
+	}, r.StatementsOf("order_db_1"))
+	assert.Len(t, r.Statements(), 4)
+}
+
+func TestDBWithRecorder_closeSources(t *testing.T) {
+	master, masterMock := newMock(t)
+	replica, replicaMock := newMock(t)
+	masterMock.ExpectClose()
+	replicaMock.ExpectClose()
+	// 被替换的数据源不再使用，需要关闭
+	_, err := OpenDB(master, DBWithReplicas(Replica{DB: replica}), DBWithRecorder(NewRecorder()))
+	require.NoError(t, err)
+	assert.NoError(t, masterMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestStatement_String(t *testing.T) {
+	stmt := Statement{
+		Source: SourceMaster,
+		SQL:    "SELECT * FROM `user` WHERE `name` = ? AND `avatar` = ? AND `age` > ? AND `email` = ?;",
+		Args:   []any{"Tom", []byte("a.png"), 18, nil},
+	}
+	assert.Equal(t, "SELECT * FROM `user` WHERE `name` = ? AND `avatar` = ? AND `age` > ? AND `email` = ?;"+
+		" -- args: \"Tom\", \"a.png\", 18, NULL", stmt.String())
+}
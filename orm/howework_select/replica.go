@@ -0,0 +1,175 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Replica 从库
+type Replica struct {
+	DB *sql.DB
+	// Weight 权重，只有 Weighted 会使用，小于等于 0 的时候当做 1
+	Weight int
+}
+
+// LoadBalancer 决定读操作发送到哪个从库，实现必须是线程安全的
+type LoadBalancer interface {
+	// Pick 返回这一次要使用的从库的下标
+	Pick() int
+	// Done 在查询返回之后回调，idx 是 Pick 的返回值
+	Done(idx int, latency time.Duration, err error)
+}
+
+// LoadBalancerBuilder 在 DB 创建的时候，根据从库构造 LoadBalancer
+type LoadBalancerBuilder func(replicas []Replica) LoadBalancer
+
+type useMasterKey struct{}
+
+// UseMaster 标记 ctx，使用这个 ctx 的读操作会发送到主库
+// 一般用于写操作之后，马上就要读到最新数据的场景，避免主从延迟
+func UseMaster(ctx context.Context) context.Context {
+	return context.WithValue(ctx, useMasterKey{}, true)
+}
+
+func isUseMaster(ctx context.Context) bool {
+	val, _ := ctx.Value(useMasterKey{}).(bool)
+	return val
+}
+
+// RoundRobin 轮询
+func RoundRobin(replicas []Replica) LoadBalancer {
+	return &roundRobin{length: uint64(len(replicas))}
+}
+
+type roundRobin struct {
+	cnt    uint64
+	length uint64
+}
+
+func (r *roundRobin) Pick() int {
+	cnt := atomic.AddUint64(&r.cnt, 1)
+	return int((cnt - 1) % r.length)
+}
+
+func (r *roundRobin) Done(idx int, latency time.Duration, err error) {}
+
+// Weighted 平滑加权轮询，和 nginx 的算法一致
+// 例如权重是 5, 1, 1 的时候，选择的顺序是 0, 0, 1, 0, 2, 0, 0
+func Weighted(replicas []Replica) LoadBalancer {
+	nodes := make([]*weightedNode, 0, len(replicas))
+	for _, r := range replicas {
+		w := r.Weight
+		if w <= 0 {
+			w = 1
+		}
+		nodes = append(nodes, &weightedNode{weight: w})
+	}
+	return &weighted{nodes: nodes}
+}
+
+type weighted struct {
+	mutex sync.Mutex
+	nodes []*weightedNode
+}
+
+type weightedNode struct {
+	weight        int
+	currentWeight int
+}
+
+func (w *weighted) Pick() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	total := 0
+	res := 0
+	for i, n := range w.nodes {
+		total += n.weight
+		n.currentWeight += n.weight
+		if n.currentWeight > w.nodes[res].currentWeight {
+			res = i
+		}
+	}
+	w.nodes[res].currentWeight -= total
+	return res
+}
+
+func (w *weighted) Done(idx int, latency time.Duration, err error) {}
+
+// errorLatency 出错之后额外加上的延迟，在 errorPenalty 时间内线性衰减到 0
+// 衰减完之后从库会重新被选中，恢复了的话就能重新拿到流量
+const (
+	errorLatency = time.Second
+	errorPenalty = 10 * time.Second
+)
+
+// LeastLatency 选择平均延迟最低的从库
+// 平均延迟使用指数加权移动平均计算，还没有被使用过的从库会被优先选择
+// 出错的从库会加上 errorLatency 的惩罚，惩罚随着时间衰减，所以它暂时不会被选中，但是之后还有机会恢复
+// 调用者自己取消或者超时的 context 错误不算从库出错
+func LeastLatency(replicas []Replica) LoadBalancer {
+	return &leastLatency{
+		latencies: make([]time.Duration, len(replicas)),
+		failedAt:  make([]time.Time, len(replicas)),
+		now:       time.Now,
+	}
+}
+
+type leastLatency struct {
+	mutex     sync.Mutex
+	latencies []time.Duration
+	// failedAt 最近一次出错的时间，零值代表没有出过错
+	failedAt []time.Time
+	now      func() time.Time
+}
+
+func (l *leastLatency) Pick() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	res := 0
+	min := l.latency(0, now)
+	for i := 1; i < len(l.latencies); i++ {
+		if lat := l.latency(i, now); lat < min {
+			res, min = i, lat
+		}
+	}
+	return res
+}
+
+// latency 平均延迟加上还没有衰减完的惩罚
+func (l *leastLatency) latency(idx int, now time.Time) time.Duration {
+	lat := l.latencies[idx]
+	if l.failedAt[idx].IsZero() {
+		return lat
+	}
+	elapsed := now.Sub(l.failedAt[idx])
+	if elapsed >= errorPenalty {
+		return lat
+	}
+	// 直接用 Duration 相乘会溢出
+	return lat + time.Duration(float64(errorLatency)*float64(errorPenalty-elapsed)/float64(errorPenalty))
+}
+
+func (l *leastLatency) Done(idx int, latency time.Duration, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// 调用者自己放弃了，延迟和错误都和从库无关
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err != nil {
+		// 出错的时候实际的延迟没有参考意义，很快就失败的从库延迟反而很低
+		l.failedAt[idx] = l.now()
+		return
+	}
+	old := l.latencies[idx]
+	if old == 0 {
+		l.latencies[idx] = latency
+		return
+	}
+	l.latencies[idx] = (old*7 + latency) / 8
+}
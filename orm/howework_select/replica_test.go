@@ -0,0 +1,158 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundRobin(t *testing.T) {
+	lb := RoundRobin(make([]Replica, 3))
+	res := make([]int, 0, 6)
+	for i := 0; i < 6; i++ {
+		res = append(res, lb.Pick())
+	}
+	assert.Equal(t, []int{0, 1, 2, 0, 1, 2}, res)
+}
+
+func TestWeighted(t *testing.T) {
+	lb := Weighted([]Replica{{Weight: 5}, {Weight: 1}, {Weight: 0}})
+	res := make([]int, 0, 7)
+	for i := 0; i < 7; i++ {
+		res = append(res, lb.Pick())
+	}
+	assert.Equal(t, []int{0, 0, 1, 0, 2, 0, 0}, res)
+}
+
+func TestLeastLatency(t *testing.T) {
+	lb := LeastLatency(make([]Replica, 3))
+	// 一开始都没有数据，选择第一个
+	assert.Equal(t, 0, lb.Pick())
+	lb.Done(0, 10*time.Millisecond, nil)
+	// 没有用过的优先
+	assert.Equal(t, 1, lb.Pick())
+	lb.Done(1, 5*time.Millisecond, nil)
+	assert.Equal(t, 2, lb.Pick())
+	lb.Done(2, 20*time.Millisecond, nil)
+	assert.Equal(t, 1, lb.Pick())
+
+	// 1 变慢了，平均延迟变成 (5*7 + 85) / 8 = 15ms
+	lb.Done(1, 85*time.Millisecond, nil)
+	assert.Equal(t, 0, lb.Pick())
+	// 出错之后加上 errorLatency 的惩罚，0 的延迟相当于 10ms + 1s
+	lb.Done(0, time.Millisecond, errors.New("mock error"))
+	assert.Equal(t, 1, lb.Pick())
+}
+
+func TestLeastLatency_penalty(t *testing.T) {
+	now := time.Now()
+	lb := LeastLatency(make([]Replica, 2)).(*leastLatency)
+	lb.now = func() time.Time { return now }
+	lb.Done(0, 10*time.Millisecond, nil)
+	lb.Done(1, 100*time.Millisecond, nil)
+	assert.Equal(t, 0, lb.Pick())
+
+	// 一直出错的从库不会一直被选中
+	lb.Done(0, time.Millisecond, errors.New("mock error"))
+	assert.Equal(t, 1, lb.Pick())
+	// 惩罚衰减到 0.5s，还是比 100ms 大
+	now = now.Add(errorPenalty / 2)
+	assert.Equal(t, 1, lb.Pick())
+	// 惩罚衰减到 1s * 1/10 = 100ms，加上 10ms 还是比 100ms 大
+	now = now.Add(errorPenalty * 4 / 10)
+	assert.Equal(t, 1, lb.Pick())
+	// 衰减完之后重新被选中，成功之后恢复
+	now = now.Add(errorPenalty / 10)
+	assert.Equal(t, 0, lb.Pick())
+	lb.Done(0, 10*time.Millisecond, nil)
+	assert.Equal(t, 0, lb.Pick())
+
+	// 调用者取消或者超时不算从库出错
+	lb.Done(0, time.Millisecond, context.Canceled)
+	lb.Done(0, time.Second, fmt.Errorf("query: %w", context.DeadlineExceeded))
+	assert.Equal(t, 0, lb.Pick())
+	assert.Equal(t, 10*time.Millisecond, lb.latencies[0])
+}
+
+func TestDB_ReadWriteSplitting(t *testing.T) {
+	master, masterMock := newMock(t)
+	replica1, replica1Mock := newMock(t)
+	replica2, replica2Mock := newMock(t)
+	db, err := OpenDB(master, DBWithReplicas(
+		Replica{DB: replica1}, Replica{DB: replica2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 读操作轮流发送到从库
+	replica1Mock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	replica2Mock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	res, err := NewSelector[TestModel](db).Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.Id)
+	res, err = NewSelector[TestModel](db).Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), res.Id)
+
+	// 写操作发送到主库
+	masterMock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = RawQuery[TestModel](db, "UPDATE `test_model` SET `age` = ?", 18).Exec(ctx)
+	assert.Nil(t, err)
+
+	// 强制读主库
+	masterMock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	res, err = NewSelector[TestModel](db).Get(UseMaster(ctx))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), res.Id)
+
+	// 事务里面的读操作发送到主库
+	masterMock.ExpectBegin()
+	masterMock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	masterMock.ExpectCommit()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = NewSelector[TestModel](tx).Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), res.Id)
+	assert.Nil(t, tx.Commit())
+
+	assert.Nil(t, masterMock.ExpectationsWereMet())
+	assert.Nil(t, replica1Mock.ExpectationsWereMet())
+	assert.Nil(t, replica2Mock.ExpectationsWereMet())
+}
+
+func TestDB_NoReplicas(t *testing.T) {
+	master, masterMock := newMock(t)
+	db, err := OpenDB(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterMock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	res, err := NewSelector[TestModel](db).Get(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.Id)
+	assert.Nil(t, masterMock.ExpectationsWereMet())
+}
+
+func newMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, mock
+}
@@ -0,0 +1,84 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy 重试策略，middleware/retry 用它重试事务之外的查询，DB.DoTx 用它重试整个事务
+// 零值不会重试
+type RetryPolicy struct {
+	// MaxAttempts 最多执行的次数，包括第一次，小于等于 1 说明不重试
+	MaxAttempts int
+	// InitialBackoff 第一次重试之前等待的时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// MaxBackoff 等待时间的上限，小于等于 0 说明没有上限
+	MaxBackoff time.Duration
+	// Retryable 判断错误能不能重试，为 nil 的时候使用 IsRetryable
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy 最多执行三次，两次重试之前分别等待 10ms 和 20ms 左右
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+}
+
+// IsRetryable 死锁和连接错误是暂时的，重试一般可以成功
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrDeadlock) || errors.Is(err, ErrConnection)
+}
+
+// jitter 返回 [0, n) 之间的随机数，测试的时候可以替换
+var jitter = rand.Int63n
+
+// Do 执行 fn，返回的错误可以重试的时候，等待一段时间之后再次执行
+// ctx 被取消，或者 ctx 的 deadline 等不到下一次重试的时候，直接返回最后一次的错误
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff 第 attempt 次失败之后的等待时间，指数增长，再加上随机抖动
+// 一半固定一半随机，避免发生死锁的几个事务同时重试，又一次死锁
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(jitter(int64(d-half)+1))
+}
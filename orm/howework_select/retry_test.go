@@ -0,0 +1,264 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlockError 模拟 go-sql-driver/mysql 的死锁错误
+type deadlockError struct {
+	Number uint16
+}
+
+func (e *deadlockError) Error() string {
+	return "Error 1213: Deadlock found when trying to get lock"
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	origin := jitter
+	defer func() { jitter = origin }()
+	// 总是取最大的随机数
+	jitter = func(n int64) int64 { return n - 1 }
+
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 30*time.Millisecond, p.backoff(3))
+	assert.Equal(t, 30*time.Millisecond, p.backoff(10))
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(1))
+
+	jitter = func(n int64) int64 { return 0 }
+	assert.Equal(t, 5*time.Millisecond, p.backoff(1))
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	deadlock := errs.WrapDriverError(&deadlockError{Number: 1213})
+	p := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	testCases := []struct {
+		name     string
+		policy   RetryPolicy
+		ctx      func() (context.Context, context.CancelFunc)
+		errs     []error
+		wantErr  error
+		wantCall int
+	}{
+		{
+			name:     "success",
+			policy:   p,
+			errs:     []error{nil},
+			wantCall: 1,
+		},
+		{
+			name:     "retry",
+			policy:   p,
+			errs:     []error{deadlock, ErrConnection, nil},
+			wantCall: 3,
+		},
+		{
+			name:     "max attempts",
+			policy:   p,
+			errs:     []error{deadlock, deadlock, deadlock},
+			wantErr:  deadlock,
+			wantCall: 3,
+		},
+		{
+			name:     "not retryable",
+			policy:   p,
+			errs:     []error{ErrNoRows},
+			wantErr:  ErrNoRows,
+			wantCall: 1,
+		},
+		{
+			name:     "zero policy",
+			errs:     []error{deadlock},
+			wantErr:  deadlock,
+			wantCall: 1,
+		},
+		{
+			name: "custom retryable",
+			policy: RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool {
+				return errors.Is(err, ErrNoRows)
+			}},
+			errs:     []error{ErrNoRows, nil},
+			wantCall: 2,
+		},
+		{
+			// 等不到下一次重试
+			name:   "deadline",
+			policy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Second)
+			},
+			errs:     []error{deadlock},
+			wantErr:  deadlock,
+			wantCall: 1,
+		},
+		{
+			name:   "canceled",
+			policy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute},
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			errs:     []error{deadlock},
+			wantErr:  deadlock,
+			wantCall: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tc.ctx != nil {
+				ctx, cancel = tc.ctx()
+			}
+			defer cancel()
+			calls := 0
+			err := tc.policy.Do(ctx, func(ctx context.Context) error {
+				err := tc.errs[calls]
+				calls++
+				return err
+			})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCall, calls)
+		})
+	}
+}
+
+func TestDB_DoTx(t *testing.T) {
+	ctx := context.Background()
+	deadlock := &deadlockError{Number: 1213}
+	testCases := []struct {
+		name     string
+		mock     func(mock sqlmock.Sqlmock)
+		fn       func(ctx context.Context, tx *Tx) error
+		wantErr  error
+		wantCall int
+	}{
+		{
+			name: "commit",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `test_model`;").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantCall: 1,
+		},
+		{
+			name: "retry deadlock",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `test_model`;").WillReturnError(deadlock)
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `test_model`;").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantCall: 2,
+		},
+		{
+			name: "fn error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, tx *Tx) error {
+				return errors.New("biz error")
+			},
+			wantErr:  errors.New("biz error"),
+			wantCall: 1,
+		},
+		{
+			// 不知道事务有没有提交成功，不能重试
+			name: "commit connection error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `test_model`;").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(driver.ErrBadConn)
+			},
+			wantErr:  ErrConnection,
+			wantCall: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock := newMock(t)
+			db, err := OpenDB(mockDB, DBWithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+			require.NoError(t, err)
+			tc.mock(mock)
+			calls := 0
+			err = db.DoTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+				calls++
+				if tc.fn != nil {
+					return tc.fn(ctx, tx)
+				}
+				_, err := NewDeleter[TestModel](tx).Exec(ctx)
+				return err
+			})
+			if tc.wantErr == ErrConnection {
+				assert.True(t, errors.Is(err, ErrConnection))
+			} else {
+				assert.Equal(t, tc.wantErr, err)
+			}
+			assert.Equal(t, tc.wantCall, calls)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_DoTxPanic(t *testing.T) {
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	assert.Panics(t, func() {
+		_ = db.DoTx(context.Background(), nil, func(ctx context.Context, tx *Tx) error {
+			panic("panic in tx")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "deadlock",
+			err:  ErrDeadlock,
+			want: true,
+		},
+		{
+			name: "bad conn",
+			err:  errs.WrapDriverError(driver.ErrBadConn),
+			want: true,
+		},
+		{
+			// 调用者已经放弃了，不能重试
+			name: "deadline exceeded",
+			err:  errs.WrapDriverError(context.DeadlineExceeded),
+		},
+		{
+			name: "canceled",
+			err:  errs.WrapDriverError(context.Canceled),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsRetryable(tc.err))
+		})
+	}
+}
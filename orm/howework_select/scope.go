@@ -0,0 +1,24 @@
+package orm
+
+// Scope 可以复用的查询片段，例如
+//
+//	func Active(s *Selector[User]) *Selector[User] {
+//		return s.Where(C("Status").EQ("active"), Not(C("Banned").EQ(true)))
+//	}
+//
+//	func Adult(s *Selector[User]) *Selector[User] {
+//		return s.Where(C("Age").GT(18))
+//	}
+//
+//	NewSelector[User](db).Scopes(Active, Adult).OrderBy(Desc("Id"))
+//
+// Where, Having, GroupBy 和 OrderBy 都是追加的，所以多个 Scope 可以组合
+type Scope[T any] func(s *Selector[T]) *Selector[T]
+
+// Scopes 按照顺序应用 scopes
+func (s *Selector[T]) Scopes(scopes ...Scope[T]) *Selector[T] {
+	for _, scope := range scopes {
+		s = scope(s)
+	}
+	return s
+}
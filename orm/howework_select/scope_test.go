@@ -0,0 +1,77 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func adult(s *Selector[TestModel]) *Selector[TestModel] {
+	return s.Where(C("Age").GT(18))
+}
+
+func named(name string) Scope[TestModel] {
+	return func(s *Selector[TestModel]) *Selector[TestModel] {
+		return s.WhereIf(name != "", C("FirstName").EQ(name))
+	}
+}
+
+func TestSelector_Scopes(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "scopes",
+			q:    NewSelector[TestModel](db).Scopes(adult, named("Tom")).Where(C("Id").LT(100)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE ((`age` > ?) AND (`first_name` = ?)) AND (`id` < ?);",
+				Args: []any{18, "Tom", 100},
+			},
+		},
+		{
+			name: "where if false",
+			q:    NewSelector[TestModel](db).Scopes(named("")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model`;",
+			},
+		},
+		{
+			name: "append",
+			q: NewSelector[TestModel](db).Select(C("Age"), C("FirstName")).
+				GroupBy(C("Age")).GroupBy(C("FirstName")).
+				Having(CountAll().GT(1)).Having(AvgOf(C("Id")).LT(10)).
+				OrderBy(Asc("Age")).OrderBy(Desc("FirstName")),
+			wantQuery: &Query{
+				SQL: "SELECT `age`,`first_name` FROM `test_model` GROUP BY `age`,`first_name` " +
+					"HAVING (COUNT(*) > ?) AND (AVG(`id`) < ?) ORDER BY `age` ASC,`first_name` DESC;",
+				Args: []any{1, 10},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestDeleter_WhereIf(t *testing.T) {
+	db := memoryDB(t)
+	q, err := NewDeleter[TestModel](db).Where(C("Age").GT(18)).
+		WhereIf(false, C("Id").EQ(1)).WhereIf(true, C("FirstName").EQ("Tom")).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  "DELETE FROM `test_model` WHERE (`age` > ?) AND (`first_name` = ?);",
+		Args: []any{18, "Tom"},
+	}, q)
+}
@@ -1,21 +1,19 @@
 package orm
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
-	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
 )
 
 // Selector 用于构造 SELECT 语句
 type Selector[T any] struct {
-	db   *DB
-	sb   strings.Builder
-	args []any
-
-	model *model.Model
+	builder
+	sess Session
 
+	ctes     []cte
+	distinct bool
 	selects  []Selectable
 	table    string
 	where    []Predicate
@@ -24,6 +22,7 @@ type Selector[T any] struct {
 	orderBys []OrderBy
 	offset   int
 	limit    int
+	lock     lock
 }
 
 func (s *Selector[T]) Select(cols ...Selectable) *Selector[T] {
@@ -31,6 +30,32 @@ func (s *Selector[T]) Select(cols ...Selectable) *Selector[T] {
 	return s
 }
 
+// Distinct 构造 SELECT DISTINCT
+func (s *Selector[T]) Distinct() *Selector[T] {
+	s.distinct = true
+	return s
+}
+
+// cte 公用表表达式，也就是 WITH name AS (sub)
+type cte struct {
+	name      string
+	sub       QueryBuilder
+	recursive bool
+}
+
+// With 定义一个公用表表达式，之后可以通过 From 或者 RawExpr 引用 name
+func (s *Selector[T]) With(name string, sub QueryBuilder) *Selector[T] {
+	s.ctes = append(s.ctes, cte{name: name, sub: sub})
+	return s
+}
+
+// WithRecursive 定义一个递归的公用表表达式，sub 一般是 UNION ALL 组合起来的查询
+// 只要有一个是递归的，就会使用 WITH RECURSIVE
+func (s *Selector[T]) WithRecursive(name string, sub QueryBuilder) *Selector[T] {
+	s.ctes = append(s.ctes, cte{name: name, sub: sub, recursive: true})
+	return s
+}
+
 // From 指定表名，如果是空字符串，那么将会使用默认表名
 func (s *Selector[T]) From(tbl string) *Selector[T] {
 	s.table = tbl
@@ -38,13 +63,30 @@ func (s *Selector[T]) From(tbl string) *Selector[T] {
 }
 
 func (s *Selector[T]) Build() (*Query, error) {
-	m, err := s.db.r.Register(new(T))
+	return s.buildLimit(s.limit)
+}
+
+// buildLimit 使用 limit 代替 s.limit，Get 用它加上 LIMIT 1，而不用修改 Selector 本身
+func (s *Selector[T]) buildLimit(limit int) (*Query, error) {
+	m, err := s.r.Get(new(T))
 	if err != nil {
 		return nil, err
 	}
 	s.model = m
+	return s.build(s.table, limit, s.offset)
+}
+
+// build 构造 SQL，分库分表的时候每个分片会使用不同的表名，LIMIT 和 OFFSET
+func (s *Selector[T]) build(table string, limit, offset int) (*Query, error) {
+	s.reset()
+	if err := s.buildCTEs(); err != nil {
+		return nil, err
+	}
 	// select columns
 	s.sb.WriteString(`SELECT `)
+	if s.distinct {
+		s.sb.WriteString(`DISTINCT `)
+	}
 	if len(s.selects) == 0 {
 		s.sb.WriteByte('*')
 	} else {
@@ -68,6 +110,27 @@ func (s *Selector[T]) Build() (*Query, error) {
 				if typ.alias != "" {
 					s.buildAlias(typ.alias)
 				}
+			case MathExpr:
+				if err := s.buildExpression(typ); err != nil {
+					return nil, err
+				}
+				if typ.alias != "" {
+					s.buildAlias(typ.alias)
+				}
+			case OverExpr:
+				if err := s.buildOver(typ); err != nil {
+					return nil, err
+				}
+				if typ.alias != "" {
+					s.buildAlias(typ.alias)
+				}
+			case CaseExpr:
+				if err := s.buildCase(typ); err != nil {
+					return nil, err
+				}
+				if typ.alias != "" {
+					s.buildAlias(typ.alias)
+				}
 			case RawExpr:
 				s.sb.WriteString(typ.raw)
 			}
@@ -76,22 +139,11 @@ func (s *Selector[T]) Build() (*Query, error) {
 	}
 
 	s.sb.WriteString(` FROM `)
-	if s.table == "" {
-		s.sb.WriteByte('`')
-		s.sb.WriteString(s.model.TableName)
-		s.sb.WriteByte('`')
-	} else {
-		s.sb.WriteString(s.table)
-	}
+	s.buildTable(table)
 
-	if len(s.where) > 0 {
-		s.args = make([]any, 0, 4)
+	if where := s.tenantWhere(s.where); len(where) > 0 {
 		s.sb.WriteString(` WHERE `)
-		p := s.where[0]
-		for i := 1; i < len(s.where); i++ {
-			p = p.And(s.where[i])
-		}
-		if err := s.buildWhereExpr(p); err != nil {
+		if err := s.buildPredicates(where); err != nil {
 			return nil, err
 		}
 	}
@@ -100,58 +152,47 @@ func (s *Selector[T]) Build() (*Query, error) {
 	if len(s.groupBy) > 0 {
 		s.sb.WriteString(` GROUP BY `)
 		for i, col := range s.groupBy {
-			fd, ok := s.model.FieldMap[col.name]
-			if !ok {
-				return nil, errs.NewErrUnknownField(col.name)
-			}
 			if i > 0 {
 				s.sb.WriteByte(',')
 			}
-			s.sb.WriteByte('`')
-			s.sb.WriteString(fd.ColName)
-			s.sb.WriteByte('`')
+			if err := s.buildColumn(col); err != nil {
+				return nil, err
+			}
 		}
 	}
 
+	// HAVING 和 ORDER BY 可以引用 SELECT 里面的别名
+	s.aliases = selectAliases(s.selects)
+
 	// having
 	if len(s.having) > 0 {
 		s.sb.WriteString(` HAVING `)
-		p := s.having[0]
-		for i := 1; i < len(s.having); i++ {
-			p = p.And(s.having[i])
-		}
-		if err := s.buildWhereExpr(p); err != nil {
+		if err := s.buildPredicates(s.having); err != nil {
 			return nil, err
 		}
 	}
 
 	// order by
 	if len(s.orderBys) > 0 {
-		s.sb.WriteString(` ORDER BY `)
-		for i, ob := range s.orderBys {
-			fd, ok := s.model.FieldMap[ob.col]
-			if !ok {
-				return nil, errs.NewErrUnknownField(ob.col)
-			}
-			if i > 0 {
-				s.sb.WriteByte(',')
-			}
-			s.sb.WriteByte('`')
-			s.sb.WriteString(fd.ColName)
-			s.sb.WriteString("` ")
-			s.sb.WriteString(ob.order)
+		s.sb.WriteByte(' ')
+		if err := s.buildOrderBys(s.orderBys); err != nil {
+			return nil, err
 		}
 	}
 
 	// limit
-	if s.limit > 0 {
+	if limit > 0 {
 		s.sb.WriteString(" LIMIT ?")
-		s.args = append(s.args, s.limit)
+		s.args = append(s.args, limit)
 	}
 	// offset
-	if s.offset > 0 {
+	if offset > 0 {
 		s.sb.WriteString(" OFFSET ?")
-		s.args = append(s.args, s.offset)
+		s.args = append(s.args, offset)
+	}
+
+	if err := s.buildLock(); err != nil {
+		return nil, err
 	}
 
 	s.sb.WriteByte(';')
@@ -161,26 +202,102 @@ func (s *Selector[T]) Build() (*Query, error) {
 	}, nil
 }
 
-func (s *Selector[T]) buildAlias(a string) {
-	s.sb.WriteString(" AS `")
-	s.sb.WriteString(a)
-	s.sb.WriteByte('`')
+func (s *Selector[T]) buildLock() error {
+	if s.lock.conflict || (s.lock.mode == "" && s.lock.wait != "") {
+		return errs.ErrInvalidLockOption
+	}
+	if s.lock.mode == "" {
+		return nil
+	}
+	return s.dialect.buildLock(&s.builder, s.lock)
+}
+
+func (s *Selector[T]) buildCTEs() error {
+	if len(s.ctes) == 0 {
+		return nil
+	}
+	s.sb.WriteString("WITH ")
+	for _, c := range s.ctes {
+		if c.recursive {
+			s.sb.WriteString("RECURSIVE ")
+			break
+		}
+	}
+	for i, c := range s.ctes {
+		if i > 0 {
+			s.sb.WriteByte(',')
+		}
+		q, err := c.sub.Build()
+		if err != nil {
+			return err
+		}
+		s.quote(c.name)
+		s.sb.WriteString(" AS (")
+		s.sb.WriteString(strings.TrimSuffix(q.SQL, ";"))
+		s.sb.WriteByte(')')
+		s.args = append(s.args, q.Args...)
+	}
+	s.sb.WriteByte(' ')
+	return nil
+}
+
+// ForUpdate 加上 FOR UPDATE，只能在事务中使用
+func (s *Selector[T]) ForUpdate() *Selector[T] {
+	s.lock.mode = lockForUpdate
+	return s
+}
+
+// ForShare 加上 FOR SHARE，只能在事务中使用
+func (s *Selector[T]) ForShare() *Selector[T] {
+	s.lock.mode = lockForShare
+	return s
+}
+
+// SkipLocked 跳过已经被锁住的行，一般用于多个消费者从任务表里面取任务
+func (s *Selector[T]) SkipLocked() *Selector[T] {
+	s.setLockWait(lockSkipLocked)
+	return s
+}
+
+// NoWait 行已经被锁住的时候直接返回错误，而不是等待
+func (s *Selector[T]) NoWait() *Selector[T] {
+	s.setLockWait(lockNoWait)
+	return s
+}
+
+func (s *Selector[T]) setLockWait(wait string) {
+	if s.lock.wait != "" && s.lock.wait != wait {
+		s.lock.conflict = true
+	}
+	s.lock.wait = wait
 }
 
 // Where 用于构造 WHERE 查询条件。如果 ps 长度为 0，那么不会构造 WHERE 部分
+// 多次调用的条件会用 AND 连接起来，这样 Scope 之间可以组合
 func (s *Selector[T]) Where(ps ...Predicate) *Selector[T] {
-	s.where = ps
+	s.where = append(s.where, ps...)
 	return s
 }
 
-// GroupBy 设置 group by 子句
+// WhereIf 只有 cond 为 true 的时候才加上 ps，一般用于可选的查询条件，例如
+//
+//	NewSelector[User](db).WhereIf(name != "", C("Name").EQ(name))
+func (s *Selector[T]) WhereIf(cond bool, ps ...Predicate) *Selector[T] {
+	if !cond {
+		return s
+	}
+	return s.Where(ps...)
+}
+
+// GroupBy 设置 group by 子句，多次调用会追加
 func (s *Selector[T]) GroupBy(cols ...Column) *Selector[T] {
-	s.groupBy = cols
+	s.groupBy = append(s.groupBy, cols...)
 	return s
 }
 
+// Having 多次调用的条件会用 AND 连接起来
 func (s *Selector[T]) Having(ps ...Predicate) *Selector[T] {
-	s.having = ps
+	s.having = append(s.having, ps...)
 	return s
 }
 
@@ -194,108 +311,100 @@ func (s *Selector[T]) Limit(limit int) *Selector[T] {
 	return s
 }
 
+// OrderBy 多次调用会追加，先设置的优先级更高
 func (s *Selector[T]) OrderBy(orderBys ...OrderBy) *Selector[T] {
-	s.orderBys = orderBys
+	s.orderBys = append(s.orderBys, orderBys...)
 	return s
 }
 
-func NewSelector[T any](db *DB) *Selector[T] {
+func NewSelector[T any](sess Session) *Selector[T] {
 	return &Selector[T]{
-		db: db,
+		builder: builder{core: sess.getCore()},
+		sess:    sess,
 	}
 }
 
-type Selectable interface {
-	selectable()
+// Get 只查询一条数据，这一次查询会使用 LIMIT 1，不影响之后的 Build 和 GetMulti
+func (s *Selector[T]) Get(ctx context.Context) (*T, error) {
+	res, err := s.getMulti(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNoRows
+	}
+	return res[0], nil
 }
 
-type OrderBy struct {
-	col   string
-	order string
+func (s *Selector[T]) GetMulti(ctx context.Context) ([]*T, error) {
+	return s.getMulti(ctx, s.limit)
 }
 
-func Asc(col string) OrderBy {
-	return OrderBy{
-		col: col, order: "ASC",
+func (s *Selector[T]) getMulti(ctx context.Context, limit int) ([]*T, error) {
+	q, err := s.buildLimit(limit)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func Desc(col string) OrderBy {
-	return OrderBy{
-		col: col, order: "DESC",
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
 	}
-}
-
-func (s *Selector[T]) buildWhereExpr(e Expression) error {
-	if e == nil {
-		return nil
+	if !inTx(s.sess) && s.lock.mode != "" {
+		return nil, errs.ErrLockOutsideTx
 	}
-	switch exp := e.(type) {
-	case Column:
-		if err := s.buildColumn(exp); err != nil {
-			return err
-		}
-	case value:
-		s.sb.WriteByte('?')
-		s.args = append(s.args, exp.val)
-	case Predicate:
-		_, lp := exp.left.(Predicate)
-		if lp {
-			s.sb.WriteByte('(')
-		}
-		if err := s.buildWhereExpr(exp.left); err != nil {
-			return err
-		}
-		if lp {
-			s.sb.WriteByte(')')
-		}
-
-		s.sb.WriteByte(' ')
-		s.sb.WriteString(exp.op.String())
-		s.sb.WriteByte(' ')
-
-		_, rp := exp.right.(Predicate)
-		if rp {
-			s.sb.WriteByte('(')
-		}
-		if err := s.buildWhereExpr(exp.right); err != nil {
-			return err
-		}
-		if rp {
-			s.sb.WriteByte(')')
-		}
-	case RawExpr:
-		s.sb.WriteString(exp.raw)
-		s.args = append(s.args, exp.args...)
-	case Aggregate:
-		if err := s.buildAggregate(exp); err != nil {
-			return err
+	qc := &QueryContext{
+		Type:    QueryTypeSelect,
+		Builder: s,
+		Model:   s.model,
+		Query:   q,
+		InTx:    inTx(s.sess),
+		Locked:  s.lock.mode != "",
+		Session: s.sess,
+	}
+	qr := s.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		var (
+			res []*T
+			err error
+		)
+		if s.model.Sharding != nil && s.table == "" {
+			res, err = s.getMultiSharding(ctx, limit)
+		} else {
+			res, err = getMulti[T](ctx, s.sess, qc.Query)
 		}
-	default:
-		return fmt.Errorf("orm: unsupported expression %v", exp)
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
 	}
-	return nil
+	return queryResult[[]*T](qr)
 }
 
-func (s *Selector[T]) buildColumn(col Column) error {
-	fd, ok := s.model.FieldMap[col.name]
-	if !ok {
-		return errs.NewErrUnknownField(col.name)
-	}
-	s.sb.WriteByte('`')
-	s.sb.WriteString(fd.ColName)
-	s.sb.WriteByte('`')
-	return nil
+type Selectable interface {
+	selectable()
 }
 
-func (s *Selector[T]) buildAggregate(exp Aggregate) error {
-	fd, ok := s.model.FieldMap[exp.arg]
-	if !ok {
-		return errs.NewErrUnknownField(exp.arg)
+func selectAliases(selects []Selectable) map[string]struct{} {
+	var res map[string]struct{}
+	for _, sel := range selects {
+		var alias string
+		switch typ := sel.(type) {
+		case Column:
+			alias = typ.alias
+		case Aggregate:
+			alias = typ.alias
+		case MathExpr:
+			alias = typ.alias
+		case OverExpr:
+			alias = typ.alias
+		case CaseExpr:
+			alias = typ.alias
+		}
+		if alias == "" {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]struct{}, len(selects))
+		}
+		res[alias] = struct{}{}
 	}
-	s.sb.WriteString(exp.fn)
-	s.sb.WriteString("(`")
-	s.sb.WriteString(fd.ColName)
-	s.sb.WriteString("`)")
-	return nil
+	return res
 }
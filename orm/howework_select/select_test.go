@@ -1,10 +1,15 @@
 package orm
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSelector_OrderBy(t *testing.T) {
@@ -29,11 +34,54 @@ func TestSelector_OrderBy(t *testing.T) {
 				SQL: "SELECT * FROM `test_model` ORDER BY `age` ASC,`id` DESC;",
 			},
 		},
+		{
+			name: "alias",
+			q: NewSelector[TestModel](db).Select(C("Age"), CountAll().As("cnt")).
+				GroupBy(C("Age")).OrderBy(Desc("cnt")),
+			wantQuery: &Query{
+				SQL: "SELECT `age`,COUNT(*) AS `cnt` FROM `test_model` GROUP BY `age` ORDER BY `cnt` DESC;",
+			},
+		},
+		{
+			name: "aggregate",
+			q: NewSelector[TestModel](db).Select(C("Age")).
+				GroupBy(C("Age")).OrderBy(CountAll().Desc(), C("Age").Asc()),
+			wantQuery: &Query{
+				SQL: "SELECT `age` FROM `test_model` GROUP BY `age` ORDER BY COUNT(*) DESC,`age` ASC;",
+			},
+		},
+		{
+			name: "expression",
+			q:    NewSelector[TestModel](db).OrderBy(DescOf(C("Age").Add(1)), AscOf(Raw("RANDOM()"))),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` ORDER BY `age` + ? DESC,RANDOM() ASC;",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "typed column",
+			q:    NewSelector[TestModel](db).OrderBy(TC[string]("FirstName").Desc()),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` ORDER BY `first_name` DESC;",
+			},
+		},
+		{
+			name: "nulls",
+			q:    NewSelector[TestModel](db).OrderBy(Asc("LastName").NullsLast(), Desc("Age").NullsFirst()),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` ORDER BY `last_name` ASC NULLS LAST,`age` DESC NULLS FIRST;",
+			},
+		},
 		{
 			name:    "invalid column",
 			q:       NewSelector[TestModel](db).OrderBy(Asc("Invalid")),
 			wantErr: errs.NewErrUnknownField("Invalid"),
 		},
+		{
+			name:    "invalid expression",
+			q:       NewSelector[TestModel](db).OrderBy(AscOf(C("Invalid").Add(1))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -130,6 +178,32 @@ func TestSelector_Having(t *testing.T) {
 				Args: []any{"Deng", "Ming"},
 			},
 		},
+		{
+			// 引用 SELECT 里面的别名
+			name: "alias",
+			q: NewSelector[TestModel](db).Select(C("Age"), Avg("Id").As("avg_id")).
+				GroupBy(C("Age")).Having(C("avg_id").LT(10)),
+			wantQuery: &Query{
+				SQL:  "SELECT `age`,AVG(`id`) AS `avg_id` FROM `test_model` GROUP BY `age` HAVING `avg_id` < ?;",
+				Args: []any{10},
+			},
+		},
+		{
+			name: "arithmetic",
+			q: NewSelector[TestModel](db).GroupBy(C("Age")).
+				Having(Sum("Id").Sub(Min("Id")).GT(100)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` GROUP BY `age` HAVING SUM(`id`) - MIN(`id`) > ?;",
+				Args: []any{100},
+			},
+		},
+		{
+			// 别名不能在 WHERE 里面使用
+			name: "alias in where",
+			q: NewSelector[TestModel](db).Select(Avg("Id").As("avg_id")).
+				Where(C("avg_id").LT(10)),
+			wantErr: errs.NewErrUnknownField("avg_id"),
+		},
 		{
 			// 聚合函数
 			name: "avg",
@@ -239,6 +313,48 @@ func TestSelector_Select(t *testing.T) {
 				SQL: "SELECT AVG(`age`) FROM `test_model`;",
 			},
 		},
+		{
+			name: "count all",
+			q:    NewSelector[TestModel](db).Select(CountAll()),
+			wantQuery: &Query{
+				SQL: "SELECT COUNT(*) FROM `test_model`;",
+			},
+		},
+		{
+			name: "count distinct",
+			q:    NewSelector[TestModel](db).Select(Count("FirstName").Distinct().As("cnt")),
+			wantQuery: &Query{
+				SQL: "SELECT COUNT(DISTINCT `first_name`) AS `cnt` FROM `test_model`;",
+			},
+		},
+		{
+			name: "sum of expression",
+			q:    NewSelector[TestModel](db).Select(SumOf(C("Age").Mul(C("Id")))),
+			wantQuery: &Query{
+				SQL: "SELECT SUM(`age` * `id`) FROM `test_model`;",
+			},
+		},
+		{
+			name: "arithmetic",
+			q: NewSelector[TestModel](db).Select(C("Id"),
+				C("Age").Add(1).Mul(2).As("double_age")),
+			wantQuery: &Query{
+				SQL:  "SELECT `id`,(`age` + ?) * ? AS `double_age` FROM `test_model`;",
+				Args: []any{1, 2},
+			},
+		},
+		{
+			name: "aggregate arithmetic",
+			q:    NewSelector[TestModel](db).Select(Sum("Age").Div(CountAll()).As("avg_age")),
+			wantQuery: &Query{
+				SQL: "SELECT SUM(`age`) / COUNT(*) AS `avg_age` FROM `test_model`;",
+			},
+		},
+		{
+			name:    "invalid arithmetic column",
+			q:       NewSelector[TestModel](db).Select(C("Age").Sub(C("Invalid"))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
 		{
 			name: "raw expression",
 			q:    NewSelector[TestModel](db).Select(Raw("COUNT(DISTINCT `first_name`)")),
@@ -266,6 +382,25 @@ func TestSelector_Select(t *testing.T) {
 				Args: []any{100},
 			},
 		},
+		{
+			name: "like",
+			q: NewSelector[TestModel](db).
+				Where(C("FirstName").Like("%tom%")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `first_name` LIKE ?;",
+				Args: []any{"%tom%"},
+			},
+		},
+		{
+			// 通配符和转义字符本身都会被转义
+			name: "contains",
+			q: NewSelector[TestModel](db).
+				Where(C("FirstName").Contains(`50%_\`)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `first_name` LIKE ? ESCAPE '\\';",
+				Args: []any{`%50\%\_\\%`},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -398,3 +533,129 @@ func TestSelector_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestSelector_Get(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mockDB.Close() }()
+	db, err := OpenDB(mockDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 无效查询
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+	// 没有数据
+	mock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// 有数据
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	mock.ExpectQuery("SELECT .* FROM `test_model` WHERE `id` = \\? LIMIT \\?;").
+		WithArgs(1, 1).WillReturnRows(rows)
+
+	testCases := []struct {
+		name    string
+		s       *Selector[TestModel]
+		wantErr error
+		wantRes *TestModel
+	}{
+		{
+			name:    "invalid column",
+			s:       NewSelector[TestModel](db).Where(C("Invalid").EQ(1)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+		{
+			name:    "query error",
+			s:       NewSelector[TestModel](db),
+			wantErr: errors.New("query error"),
+		},
+		{
+			name:    "no rows",
+			s:       NewSelector[TestModel](db),
+			wantErr: ErrNoRows,
+		},
+		{
+			name: "data",
+			s:    NewSelector[TestModel](db).Where(C("Id").EQ(1)),
+			wantRes: &TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{String: "Jerry", Valid: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.s.Get(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+// Get 的 LIMIT 1 只对这一次查询生效
+func TestSelector_GetKeepsLimit(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	db, _, _ := newShardingDB(t, DBWithRecorder(r))
+
+	s := NewSelector[TestModel](db).Where(C("Age").GT(18))
+	_, _ = s.Get(ctx)
+	_, err := s.GetMulti(ctx)
+	require.NoError(t, err)
+
+	set := NewSelector[TestModel](db).Union(NewSelector[TestModel](db))
+	_, _ = set.Get(ctx)
+	_, err = set.GetMulti(ctx)
+	require.NoError(t, err)
+
+	sharding := NewSelector[ShardingOrder](db).Where(C("UserId").EQ(3))
+	_, _ = sharding.Get(ctx)
+	_, err = sharding.GetMulti(ctx)
+	require.NoError(t, err)
+
+	var sqls []string
+	for _, stmt := range r.Statements() {
+		sqls = append(sqls, stmt.SQL)
+	}
+	assert.Equal(t, []string{
+		"SELECT * FROM `test_model` WHERE `age` > ? LIMIT ?;",
+		"SELECT * FROM `test_model` WHERE `age` > ?;",
+		"SELECT * FROM `test_model` UNION SELECT * FROM `test_model` LIMIT ?;",
+		"SELECT * FROM `test_model` UNION SELECT * FROM `test_model`;",
+		"SELECT * FROM `order_03` WHERE `user_id` = ? LIMIT ?;",
+		"SELECT * FROM `order_03` WHERE `user_id` = ?;",
+	}, sqls)
+}
+
+func TestSelector_GetMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mockDB.Close() }()
+	db, err := OpenDB(mockDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "first_name"})
+	rows.AddRow("1", "Tom")
+	rows.AddRow("2", "Jerry")
+	mock.ExpectQuery("SELECT `id`,`first_name` FROM `test_model`;").WillReturnRows(rows)
+
+	res, err := NewSelector[TestModel](db).Select(C("Id"), C("FirstName")).
+		GetMulti(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []*TestModel{
+		{Id: 1, FirstName: "Tom"},
+		{Id: 2, FirstName: "Jerry"},
+	}, res)
+}
@@ -0,0 +1,69 @@
+package serializer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// AES 使用 AES-GCM 加密字段，适用于手机号、身份证号这一类敏感字段
+// 只支持 string 和 []byte 类型的字段
+// 存储的内容是 nonce + 密文
+type AES struct {
+	aead cipher.AEAD
+}
+
+// NewAES 创建一个 AES 序列化器
+// key 的长度必须是 16, 24 或者 32，分别对应 AES-128, AES-192 和 AES-256
+func NewAES(key []byte) (*AES, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AES{aead: aead}, nil
+}
+
+func (a *AES) Serialize(val any) ([]byte, error) {
+	var plain []byte
+	switch v := val.(type) {
+	case string:
+		plain = []byte(v)
+	case []byte:
+		plain = v
+	default:
+		return nil, errs.NewErrUnsupportedSerializeType(val)
+	}
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	// 把密文追加在 nonce 后面，解密的时候再拆开
+	return a.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (a *AES) Deserialize(data []byte, dst any) error {
+	size := a.aead.NonceSize()
+	if len(data) < size {
+		return errs.ErrInvalidCipherText
+	}
+	plain, err := a.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return err
+	}
+	switch d := dst.(type) {
+	case *string:
+		*d = string(plain)
+	case *[]byte:
+		*d = plain
+	default:
+		return errs.NewErrUnsupportedSerializeType(dst)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAES(t *testing.T) {
+	s, err := NewAES([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		val     any
+		dst     any
+		wantVal any
+		wantErr error
+	}{
+		{
+			name:    "string",
+			val:     "13800138000",
+			dst:     new(string),
+			wantVal: func() *string { s := "13800138000"; return &s }(),
+		},
+		{
+			name:    "bytes",
+			val:     []byte("hello"),
+			dst:     &[]byte{},
+			wantVal: &[]byte{'h', 'e', 'l', 'l', 'o'},
+		},
+		{
+			name:    "unsupported type",
+			val:     12,
+			wantErr: errs.NewErrUnsupportedSerializeType(12),
+		},
+		{
+			name:    "unsupported dst",
+			val:     "hello",
+			dst:     new(int),
+			wantErr: errs.NewErrUnsupportedSerializeType(new(int)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := s.Serialize(tc.val)
+			if err != nil {
+				assert.Equal(t, tc.wantErr, err)
+				return
+			}
+			// 密文不能包含明文
+			assert.NotContains(t, string(data), "hello")
+			err = s.Deserialize(data, tc.dst)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, tc.dst)
+		})
+	}
+}
+
+func TestAES_Deserialize(t *testing.T) {
+	s, err := NewAES([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 太短，连 nonce 都不够
+	err = s.Deserialize([]byte("abc"), new(string))
+	assert.Equal(t, errs.ErrInvalidCipherText, err)
+
+	// 换了一个密钥
+	data, err := s.Serialize("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewAES([]byte("fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Deserialize(data, new(string))
+	assert.NotNil(t, err)
+}
+
+func TestNewAES(t *testing.T) {
+	_, err := NewAES([]byte("short"))
+	assert.NotNil(t, err)
+}
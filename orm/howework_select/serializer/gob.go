@@ -0,0 +1,22 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Gob 基于 encoding/gob 的实现
+// 注意 gob 编码的结果和 Go 的类型定义强相关，不适合跨语言读取
+type Gob struct{}
+
+func (Gob) Serialize(val any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gob) Deserialize(data []byte, dst any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(dst)
+}
@@ -0,0 +1,14 @@
+package serializer
+
+import "encoding/json"
+
+// JSON 基于 encoding/json 的实现
+type JSON struct{}
+
+func (JSON) Serialize(val any) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+func (JSON) Deserialize(data []byte, dst any) error {
+	return json.Unmarshal(data, dst)
+}
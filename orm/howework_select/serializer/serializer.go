@@ -0,0 +1,19 @@
+// Package serializer 定义了字段序列化的抽象
+// 用于支持类似 map[string]any, []string 这种数据库无法直接存储的类型
+// 使用方式是在字段上打标签，例如 orm:"serializer=json"
+package serializer
+
+// Serializer 负责把字段的值编码成数据库中存储的字节，以及反过来解码
+type Serializer interface {
+	// Serialize 编码，val 是字段的值
+	Serialize(val any) ([]byte, error)
+	// Deserialize 解码，dst 是指向字段的指针
+	Deserialize(data []byte, dst any) error
+}
+
+// 内置的序列化器的名字，也就是标签里面的值
+const (
+	NameJSON = "json"
+	NameGob  = "gob"
+	NameAES  = "aes"
+)
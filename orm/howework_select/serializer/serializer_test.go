@@ -0,0 +1,70 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type status uint8
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		s    Serializer
+		val  any
+		// dst 是解码的目标，必须是指针
+		dst     any
+		wantVal any
+	}{
+		{
+			name:    "json slice",
+			s:       JSON{},
+			val:     []string{"a", "b"},
+			dst:     &[]string{},
+			wantVal: &[]string{"a", "b"},
+		},
+		{
+			name:    "json map",
+			s:       JSON{},
+			val:     map[string]any{"name": "Tom", "age": 18},
+			dst:     &map[string]any{},
+			wantVal: &map[string]any{"name": "Tom", "age": float64(18)},
+		},
+		{
+			name:    "json enum",
+			s:       JSON{},
+			val:     status(2),
+			dst:     new(status),
+			wantVal: func() *status { s := status(2); return &s }(),
+		},
+		{
+			name:    "gob slice",
+			s:       Gob{},
+			val:     []string{"a", "b"},
+			dst:     &[]string{},
+			wantVal: &[]string{"a", "b"},
+		},
+		{
+			name:    "gob enum",
+			s:       Gob{},
+			val:     status(2),
+			dst:     new(status),
+			wantVal: func() *status { s := status(2); return &s }(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.s.Serialize(tc.val)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tc.s.Deserialize(data, tc.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.wantVal, tc.dst)
+		})
+	}
+}
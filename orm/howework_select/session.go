@@ -0,0 +1,51 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/valuer"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+)
+
+// Session 代表一个可以执行查询的会话，也就是 DB 或者 Tx
+// 所有的 Selector, RawQuerier 都基于 Session 构造
+type Session interface {
+	getCore() core
+	queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	execContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// core 是 DB 和 Tx 共享的部分
+type core struct {
+	r          model.Registry
+	valCreator valuer.Creator
+	// valuers 通过 DBWithValuer 给某些类型指定的 valuer，优先于 valCreator
+	valuers map[reflect.Type]valuer.Creator
+	mdls    []Middleware
+	dialect Dialect
+	// maxPlaceholders 小于等于 0 的时候使用数据库方言的限制
+	maxPlaceholders int
+}
+
+func inTx(sess Session) bool {
+	_, ok := sess.(*Tx)
+	return ok
+}
+
+// newValue 创建 val 的 valuer，有 DBWithValuer 指定的就用它
+func (c core) newValue(val any, m *model.Model) valuer.Value {
+	if create, ok := c.valuers[reflect.TypeOf(val)]; ok {
+		return create(val, m)
+	}
+	return c.valCreator(val, m)
+}
+
+// placeholders 一条语句最多使用的参数个数
+func (c core) placeholders() int {
+	if c.maxPlaceholders > 0 {
+		return c.maxPlaceholders
+	}
+	return c.dialect.maxPlaceholders()
+}
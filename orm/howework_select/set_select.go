@@ -0,0 +1,208 @@
+package orm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// 集合操作
+const (
+	setUnion     = "UNION"
+	setUnionAll  = "UNION ALL"
+	setIntersect = "INTERSECT"
+	setExcept    = "EXCEPT"
+)
+
+// SetSelector 用 UNION, INTERSECT, EXCEPT 组合多个 Selector
+// ORDER BY, LIMIT 和 OFFSET 作用在组合之后的结果上，例如
+//
+//	NewSelector[User](db).Where(C("Age").LT(18)).
+//		UnionAll(NewSelector[User](db).Where(C("Age").GT(60))).
+//		OrderBy(Asc("Age")).Limit(10)
+type SetSelector[T any] struct {
+	builder
+	sess Session
+
+	parts    []setPart[T]
+	orderBys []OrderBy
+	offset   int
+	limit    int
+}
+
+type setPart[T any] struct {
+	// op 是和前一个子查询的组合方式，第一个子查询为空
+	op string
+	s  *Selector[T]
+}
+
+var _ Querier[any] = &SetSelector[any]{}
+
+func (s *Selector[T]) Union(other *Selector[T]) *SetSelector[T] {
+	return s.toSet().Union(other)
+}
+
+func (s *Selector[T]) UnionAll(other *Selector[T]) *SetSelector[T] {
+	return s.toSet().UnionAll(other)
+}
+
+func (s *Selector[T]) Intersect(other *Selector[T]) *SetSelector[T] {
+	return s.toSet().Intersect(other)
+}
+
+func (s *Selector[T]) Except(other *Selector[T]) *SetSelector[T] {
+	return s.toSet().Except(other)
+}
+
+func (s *Selector[T]) toSet() *SetSelector[T] {
+	return &SetSelector[T]{
+		builder: builder{core: s.core},
+		sess:    s.sess,
+		parts:   []setPart[T]{{s: s}},
+	}
+}
+
+func (s *SetSelector[T]) Union(other *Selector[T]) *SetSelector[T] {
+	return s.add(setUnion, other)
+}
+
+func (s *SetSelector[T]) UnionAll(other *Selector[T]) *SetSelector[T] {
+	return s.add(setUnionAll, other)
+}
+
+func (s *SetSelector[T]) Intersect(other *Selector[T]) *SetSelector[T] {
+	return s.add(setIntersect, other)
+}
+
+func (s *SetSelector[T]) Except(other *Selector[T]) *SetSelector[T] {
+	return s.add(setExcept, other)
+}
+
+func (s *SetSelector[T]) add(op string, other *Selector[T]) *SetSelector[T] {
+	s.parts = append(s.parts, setPart[T]{op: op, s: other})
+	return s
+}
+
+// OrderBy 多次调用会追加，先设置的优先级更高
+func (s *SetSelector[T]) OrderBy(orderBys ...OrderBy) *SetSelector[T] {
+	s.orderBys = append(s.orderBys, orderBys...)
+	return s
+}
+
+func (s *SetSelector[T]) Offset(offset int) *SetSelector[T] {
+	s.offset = offset
+	return s
+}
+
+func (s *SetSelector[T]) Limit(limit int) *SetSelector[T] {
+	s.limit = limit
+	return s
+}
+
+// Build 子查询不会加上括号，因为 SQLite 不支持
+// 所以子查询不能有自己的 ORDER BY, LIMIT, OFFSET 和锁子句
+// 第一个子查询的 With 会作用在整个语句上，后面的子查询不能使用 With
+func (s *SetSelector[T]) Build() (*Query, error) {
+	return s.buildLimit(s.limit)
+}
+
+// buildLimit 使用 limit 代替 s.limit，和 Selector.buildLimit 一样
+func (s *SetSelector[T]) buildLimit(limit int) (*Query, error) {
+	m, err := s.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	s.model = m
+	s.reset()
+	for i, p := range s.parts {
+		if len(p.s.orderBys) > 0 || p.s.limit > 0 || p.s.offset > 0 {
+			return nil, errs.ErrCompoundOperand
+		}
+		// 子查询只会发送到主库，分库分表的模型必须指定物理表
+		if m.Sharding != nil && p.s.table == "" {
+			return nil, errs.ErrCompoundSharding
+		}
+		if p.s.lock.mode != "" {
+			return nil, errs.ErrCompoundLock
+		}
+		if i > 0 && len(p.s.ctes) > 0 {
+			return nil, errs.ErrCompoundCTE
+		}
+		q, err := p.s.Build()
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			s.sb.WriteByte(' ')
+			s.sb.WriteString(p.op)
+			s.sb.WriteByte(' ')
+		}
+		s.sb.WriteString(strings.TrimSuffix(q.SQL, ";"))
+		s.args = append(s.args, q.Args...)
+	}
+
+	// 集合操作的列名由第一个子查询决定
+	s.aliases = selectAliases(s.parts[0].s.selects)
+	if len(s.orderBys) > 0 {
+		s.sb.WriteByte(' ')
+		if err := s.buildOrderBys(s.orderBys); err != nil {
+			return nil, err
+		}
+	}
+	if limit > 0 {
+		s.sb.WriteString(" LIMIT ?")
+		s.args = append(s.args, limit)
+	}
+	if s.offset > 0 {
+		s.sb.WriteString(" OFFSET ?")
+		s.args = append(s.args, s.offset)
+	}
+	s.sb.WriteByte(';')
+	return &Query{
+		SQL:  s.sb.String(),
+		Args: s.args,
+	}, nil
+}
+
+// Get 只查询一条数据，这一次查询会使用 LIMIT 1，不影响之后的 Build 和 GetMulti
+func (s *SetSelector[T]) Get(ctx context.Context) (*T, error) {
+	res, err := s.getMulti(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNoRows
+	}
+	return res[0], nil
+}
+
+func (s *SetSelector[T]) GetMulti(ctx context.Context) ([]*T, error) {
+	return s.getMulti(ctx, s.limit)
+}
+
+func (s *SetSelector[T]) getMulti(ctx context.Context, limit int) ([]*T, error) {
+	q, err := s.buildLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	if q, err = bindTenant(ctx, q); err != nil {
+		return nil, err
+	}
+	qc := &QueryContext{
+		Type:    QueryTypeSelect,
+		Builder: s,
+		Model:   s.model,
+		Query:   q,
+		InTx:    inTx(s.sess),
+		Session: s.sess,
+	}
+	qr := s.handle(ctx, qc, func(ctx context.Context, qc *QueryContext) *QueryResult {
+		res, err := getMulti[T](ctx, s.sess, qc.Query)
+		return &QueryResult{Result: res, Err: err}
+	})
+	if qr.Err != nil {
+		return nil, qr.Err
+	}
+	return queryResult[[]*T](qr)
+}
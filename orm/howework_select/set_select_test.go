@@ -0,0 +1,219 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Distinct(t *testing.T) {
+	db := memoryDB(t)
+	q, err := NewSelector[TestModel](db).Distinct().Select(C("Age")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{SQL: "SELECT DISTINCT `age` FROM `test_model`;"}, q)
+}
+
+func TestSetSelector_Build(t *testing.T) {
+	db := memoryDB(t)
+	shardingDB, _, _ := newShardingDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "union",
+			q: NewSelector[TestModel](db).Where(C("Age").LT(18)).
+				Union(NewSelector[TestModel](db).Where(C("Age").GT(60))),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `age` < ? UNION SELECT * FROM `test_model` WHERE `age` > ?;",
+				Args: []any{18, 60},
+			},
+		},
+		{
+			name: "chain",
+			q: NewSelector[TestModel](db).Select(C("Id")).
+				UnionAll(NewSelector[TestModel](db).Select(C("Id"))).
+				Intersect(NewSelector[TestModel](db).Select(C("Id")).Where(C("Age").GT(18))).
+				Except(NewSelector[TestModel](db).Select(C("Id")).Where(C("FirstName").EQ("Tom"))),
+			wantQuery: &Query{
+				SQL: "SELECT `id` FROM `test_model` UNION ALL SELECT `id` FROM `test_model` " +
+					"INTERSECT SELECT `id` FROM `test_model` WHERE `age` > ? " +
+					"EXCEPT SELECT `id` FROM `test_model` WHERE `first_name` = ?;",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "order by limit",
+			q: NewSelector[TestModel](db).Select(C("Age").As("a")).
+				Union(NewSelector[TestModel](db).Select(C("Age"))).
+				OrderBy(Desc("a")).Limit(10).Offset(5),
+			wantQuery: &Query{
+				SQL:  "SELECT `age` AS `a` FROM `test_model` UNION SELECT `age` FROM `test_model` ORDER BY `a` DESC LIMIT ? OFFSET ?;",
+				Args: []any{10, 5},
+			},
+		},
+		{
+			name: "operand with limit",
+			q: NewSelector[TestModel](db).
+				Union(NewSelector[TestModel](db).Limit(1)),
+			wantErr: errs.ErrCompoundOperand,
+		},
+		{
+			name: "invalid operand",
+			q: NewSelector[TestModel](db).
+				Union(NewSelector[TestModel](db).Where(C("Invalid").EQ(1))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+		{
+			name: "operand with lock",
+			q: NewSelector[TestModel](db).ForUpdate().
+				Union(NewSelector[TestModel](db)),
+			wantErr: errs.ErrCompoundLock,
+		},
+		{
+			// 第一个子查询的 WITH 作用在整个语句上
+			name: "first operand with cte",
+			q: NewSelector[TestModel](db).
+				With("adult", NewSelector[TestModel](db).Where(C("Age").GT(18))).From("`adult`").
+				Union(NewSelector[TestModel](db).Where(C("Age").LT(6))),
+			wantQuery: &Query{
+				SQL:  "WITH `adult` AS (SELECT * FROM `test_model` WHERE `age` > ?) SELECT * FROM `adult` UNION SELECT * FROM `test_model` WHERE `age` < ?;",
+				Args: []any{18, 6},
+			},
+		},
+		{
+			// 否则 WITH 会出现在 UNION 中间
+			name: "operand with cte",
+			q: NewSelector[TestModel](db).
+				Union(NewSelector[TestModel](db).
+					With("adult", NewSelector[TestModel](db).Where(C("Age").GT(18))).From("`adult`")),
+			wantErr: errs.ErrCompoundCTE,
+		},
+		{
+			name: "sharding",
+			q: NewSelector[ShardingOrder](shardingDB).Where(C("UserId").EQ(3)).
+				Union(NewSelector[ShardingOrder](shardingDB).Where(C("UserId").EQ(4))),
+			wantErr: errs.ErrCompoundSharding,
+		},
+		{
+			name: "sharding from table",
+			q: NewSelector[ShardingOrder](shardingDB).From("`order_03`").
+				Union(NewSelector[ShardingOrder](shardingDB).From("`order_04`")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `order_03` UNION SELECT * FROM `order_04`;",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestSelector_With(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "with",
+			q: NewSelector[TestModel](db).
+				With("adult", NewSelector[TestModel](db).Where(C("Age").GT(18))).
+				From("`adult`").Where(C("FirstName").EQ("Tom")),
+			wantQuery: &Query{
+				SQL:  "WITH `adult` AS (SELECT * FROM `test_model` WHERE `age` > ?) SELECT * FROM `adult` WHERE `first_name` = ?;",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "multiple",
+			q: NewSelector[TestModel](db).
+				With("a", NewSelector[TestModel](db).Where(C("Age").GT(18))).
+				WithRecursive("b", RawQuery[TestModel](db, "SELECT 1 UNION ALL SELECT 1 FROM `b`")).
+				From("`a`"),
+			wantQuery: &Query{
+				SQL:  "WITH RECURSIVE `a` AS (SELECT * FROM `test_model` WHERE `age` > ?),`b` AS (SELECT 1 UNION ALL SELECT 1 FROM `b`) SELECT * FROM `a`;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "invalid sub query",
+			q: NewSelector[TestModel](db).
+				With("a", NewSelector[TestModel](db).Where(C("Invalid").GT(18))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+type Category struct {
+	Id       int64
+	ParentId int64
+	Name     string
+}
+
+// TestSelector_WithRecursive 用 SQLite 执行递归查询，找出一个分类下面的所有子分类
+func TestSelector_WithRecursive(t *testing.T) {
+	db := memoryDBWithDB("category", t)
+	ctx := context.Background()
+	_, err := RawQuery[any](db, "CREATE TABLE IF NOT EXISTS `category`(`id` INTEGER PRIMARY KEY, `parent_id` INTEGER, `name` TEXT)").Exec(ctx)
+	require.NoError(t, err)
+	_, err = RawQuery[any](db, "INSERT INTO `category` VALUES (1, 0, 'root'), (2, 1, 'a'), (3, 2, 'b'), (4, 0, 'other')").Exec(ctx)
+	require.NoError(t, err)
+
+	tree := NewSelector[Category](db).Where(C("Id").EQ(1)).
+		UnionAll(NewSelector[Category](db).Select(Raw("`c`.*")).
+			From("`category` AS `c` JOIN `tree` ON `c`.`parent_id` = `tree`.`id`"))
+	res, err := NewSelector[Category](db).WithRecursive("tree", tree).From("`tree`").GetMulti(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Category{
+		{Id: 1, ParentId: 0, Name: "root"},
+		{Id: 2, ParentId: 1, Name: "a"},
+		{Id: 3, ParentId: 2, Name: "b"},
+	}, res)
+}
+
+func TestSetSelector_Get(t *testing.T) {
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT \\* FROM `test_model` WHERE `age` < \\? UNION SELECT \\* FROM `test_model` WHERE `age` > \\? LIMIT \\?;").
+		WithArgs(18, 60, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "age"}).AddRow(1, 10))
+	mock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	m, err := NewSelector[TestModel](db).Where(C("Age").LT(18)).
+		Union(NewSelector[TestModel](db).Where(C("Age").GT(60))).Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &TestModel{Id: 1, Age: 10}, m)
+
+	_, err = NewSelector[TestModel](db).Union(NewSelector[TestModel](db)).Get(context.Background())
+	assert.Equal(t, ErrNoRows, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
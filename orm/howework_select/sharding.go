@@ -0,0 +1,461 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
+)
+
+// shardingQuery 是发送到某个分片的查询
+type shardingQuery struct {
+	dst sharding.Dst
+	q   *Query
+}
+
+// findDsts 根据 WHERE 条件找到需要查询的分片
+// 只有分片键上的 EQ 和 IN 条件能够缩小范围，其它条件都需要查询全部分片
+func (db *DB) findDsts(m *model.Model, where []Predicate) ([]sharding.Dst, error) {
+	alg := m.Sharding
+	if len(where) == 0 {
+		return db.broadcast(alg)
+	}
+	p := where[0]
+	for i := 1; i < len(where); i++ {
+		p = p.And(where[i])
+	}
+	dsts, broadcast, err := route(alg, p)
+	if err != nil {
+		return nil, err
+	}
+	if broadcast {
+		return db.broadcast(alg)
+	}
+	return dsts, nil
+}
+
+func (db *DB) broadcast(alg sharding.Algorithm) ([]sharding.Dst, error) {
+	if db.strictSharding {
+		return nil, errs.ErrShardingScatter
+	}
+	return alg.Broadcast(), nil
+}
+
+// route 返回表达式命中的分片，broadcast 为 true 说明无法确定分片
+func route(alg sharding.Algorithm, e Expression) (dsts []sharding.Dst, broadcast bool, err error) {
+	p, ok := e.(Predicate)
+	if !ok {
+		return nil, true, nil
+	}
+	switch p.op {
+	case opAND:
+		l, lb, err := route(alg, p.left)
+		if err != nil {
+			return nil, false, err
+		}
+		r, rb, err := route(alg, p.right)
+		if err != nil {
+			return nil, false, err
+		}
+		switch {
+		case lb:
+			return r, rb, nil
+		case rb:
+			return l, false, nil
+		default:
+			return intersect(l, r), false, nil
+		}
+	case opOR:
+		l, lb, err := route(alg, p.left)
+		if err != nil {
+			return nil, false, err
+		}
+		r, rb, err := route(alg, p.right)
+		if err != nil {
+			return nil, false, err
+		}
+		if lb || rb {
+			return nil, true, nil
+		}
+		return union(l, r), false, nil
+	case opEQ:
+		if !isShardingKey(alg, p.left) {
+			return nil, true, nil
+		}
+		val, ok := p.right.(value)
+		if !ok {
+			return nil, true, nil
+		}
+		dst, err := alg.Sharding(val.val)
+		if err != nil {
+			return nil, false, err
+		}
+		return []sharding.Dst{dst}, false, nil
+	case opIN:
+		if !isShardingKey(alg, p.left) {
+			return nil, true, nil
+		}
+		vals := p.right.(values)
+		res := make([]sharding.Dst, 0, len(vals.vals))
+		for _, val := range vals.vals {
+			dst, err := alg.Sharding(val)
+			if err != nil {
+				return nil, false, err
+			}
+			res = union(res, []sharding.Dst{dst})
+		}
+		return res, false, nil
+	default:
+		return nil, true, nil
+	}
+}
+
+func isShardingKey(alg sharding.Algorithm, e Expression) bool {
+	col, ok := e.(Column)
+	return ok && col.name == alg.ShardingKey()
+}
+
+func union(l, r []sharding.Dst) []sharding.Dst {
+	res := make([]sharding.Dst, 0, len(l)+len(r))
+	res = append(res, l...)
+	for _, dst := range r {
+		if !containsDst(res, dst) {
+			res = append(res, dst)
+		}
+	}
+	return res
+}
+
+func intersect(l, r []sharding.Dst) []sharding.Dst {
+	res := make([]sharding.Dst, 0, len(l))
+	for _, dst := range l {
+		if containsDst(r, dst) {
+			res = append(res, dst)
+		}
+	}
+	return res
+}
+
+func containsDst(dsts []sharding.Dst, dst sharding.Dst) bool {
+	for _, d := range dsts {
+		if d == dst {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *DB) dataSource(name string) (*sql.DB, error) {
+	ds, ok := db.dataSources[name]
+	if !ok {
+		return nil, errs.NewErrUnknownDataSource(name)
+	}
+	return ds, nil
+}
+
+// getMultiSharding 把查询发送到命中的分片上，再合并结果，limit 的含义和 getMulti 一样
+// 多个分片的时候，每个分片都查询 OFFSET + LIMIT 条数据，
+// 然后在内存里面按照 ORDER BY 排序，最后再截取 OFFSET 和 LIMIT
+func (s *Selector[T]) getMultiSharding(ctx context.Context, limit int) ([]*T, error) {
+	db, ok := s.sess.(*DB)
+	if !ok {
+		return nil, errs.ErrShardingInTx
+	}
+	dsts, err := db.findDsts(s.model, s.where)
+	if err != nil {
+		return nil, err
+	}
+	switch len(dsts) {
+	case 0:
+		return []*T{}, nil
+	case 1:
+		q, err := s.build(s.quoteTable(dsts[0].Table), limit, s.offset)
+		if err != nil {
+			return nil, err
+		}
+		if q, err = bindTenant(ctx, q); err != nil {
+			return nil, err
+		}
+		return queryDataSource[T](ctx, db, shardingQuery{dst: dsts[0], q: q})
+	}
+
+	if len(s.groupBy) > 0 || len(s.having) > 0 || s.distinct || hasAggregate(s.selects) {
+		return nil, errs.ErrShardingAggregate
+	}
+	if err = checkOrderBySelected(s.selects, s.orderBys); err != nil {
+		return nil, err
+	}
+	shardLimit := 0
+	if limit > 0 {
+		shardLimit = limit + s.offset
+	}
+	qs := make([]shardingQuery, 0, len(dsts))
+	for _, dst := range dsts {
+		q, err := s.build(s.quoteTable(dst.Table), shardLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+		if q, err = bindTenant(ctx, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, shardingQuery{dst: dst, q: q})
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		res   []*T
+		qErr  error
+	)
+	for _, sq := range qs {
+		wg.Add(1)
+		go func(sq shardingQuery) {
+			defer wg.Done()
+			ts, err := queryDataSource[T](ctx, db, sq)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if qErr == nil {
+					qErr = err
+				}
+				return
+			}
+			res = append(res, ts...)
+		}(sq)
+	}
+	wg.Wait()
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	if len(s.orderBys) > 0 {
+		if err = sortByOrderBys(res, s.orderBys); err != nil {
+			return nil, err
+		}
+	}
+	if s.offset >= len(res) {
+		return []*T{}, nil
+	}
+	res = res[s.offset:]
+	if limit > 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func queryDataSource[T any](ctx context.Context, db *DB, sq shardingQuery) ([]*T, error) {
+	ds, err := db.dataSource(sq.dst.DB)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.stmts.queryContext(ctx, ds, db.dialect.rebind(sq.q.SQL), sq.q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMulti[T](db.core, rows)
+}
+
+func (c core) quoteTable(table string) string {
+	q := string(c.dialect.quote())
+	return q + table + q
+}
+
+func hasAggregate(selects []Selectable) bool {
+	for _, s := range selects {
+		if e, ok := s.(Expression); ok && containsAggregate(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAggregate(e Expression) bool {
+	switch exp := e.(type) {
+	case Aggregate, OverExpr:
+		return true
+	case MathExpr:
+		return containsAggregate(exp.left) || containsAggregate(exp.right)
+	default:
+		return false
+	}
+}
+
+// checkOrderBySelected 合并的时候从结构体里面读取排序的字段，没有查询的列都是零值
+func checkOrderBySelected(selects []Selectable, orderBys []OrderBy) error {
+	if len(selects) == 0 {
+		return nil
+	}
+	for _, ob := range orderBys {
+		col, ok := ob.expr.(Column)
+		if !ok {
+			// sortByOrderBys 会返回错误
+			continue
+		}
+		if !isSelected(selects, col.name) {
+			return errs.NewErrShardingOrderBy(col.name)
+		}
+	}
+	return nil
+}
+
+func isSelected(selects []Selectable, field string) bool {
+	for _, sel := range selects {
+		if col, ok := sel.(Column); ok && col.name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// sortByOrderBys 按照 ORDER BY 的字段对合并之后的结果排序
+// 因为每个分片内部已经有序，这里使用稳定排序
+func sortByOrderBys[T any](ts []*T, orderBys []OrderBy) error {
+	var err error
+	sort.SliceStable(ts, func(i, j int) bool {
+		vi := reflect.ValueOf(ts[i]).Elem()
+		vj := reflect.ValueOf(ts[j]).Elem()
+		for _, ob := range orderBys {
+			col, ok := ob.expr.(Column)
+			if !ok {
+				// 聚合函数和 RawExpr 之类的没有办法在内存里面计算
+				err = errs.NewErrUnsupportedExpressionType(ob.expr)
+				return false
+			}
+			fi, fj := vi.FieldByName(col.name), vj.FieldByName(col.name)
+			if !fi.IsValid() {
+				// 别名之类的不是字段，没有办法在内存里面排序
+				err = errs.NewErrUnknownField(col.name)
+				return false
+			}
+			if ob.nulls != "" {
+				ni, nj := isNullValue(fi), isNullValue(fj)
+				if ni != nj {
+					// 显式指定了 NULL 的位置，和升序还是降序无关
+					return ni == (ob.nulls == nullsFirst)
+				}
+			}
+			c, cErr := compareValue(fi, fj)
+			if cErr != nil {
+				err = cErr
+				return false
+			}
+			if c == 0 {
+				continue
+			}
+			if ob.order == orderDesc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return err
+}
+
+// compareValue 比较两个字段的值，NULL 被认为是最小的
+func compareValue(a, b reflect.Value) (int, error) {
+	va, err := sortValue(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := sortValue(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case va == nil && vb == nil:
+		return 0, nil
+	case va == nil:
+		return -1, nil
+	case vb == nil:
+		return 1, nil
+	}
+	switch x := va.(type) {
+	case int64:
+		return compareOrdered(x, vb.(int64)), nil
+	case uint64:
+		return compareOrdered(x, vb.(uint64)), nil
+	case float64:
+		return compareOrdered(x, vb.(float64)), nil
+	case string:
+		return strings.Compare(x, vb.(string)), nil
+	case []byte:
+		return strings.Compare(string(x), string(vb.([]byte))), nil
+	case bool:
+		return compareOrdered(boolToInt(x), boolToInt(vb.(bool))), nil
+	case time.Time:
+		y := vb.(time.Time)
+		switch {
+		case x.Before(y):
+			return -1, nil
+		case x.After(y):
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	default:
+		return 0, errs.NewErrUnsupportedCompareType(va)
+	}
+}
+
+func isNullValue(v reflect.Value) bool {
+	val, err := sortValue(v)
+	return err == nil && val == nil
+}
+
+// sortValue 把字段的值转化为可以比较的基本类型
+// 实现了 driver.Valuer 的类型，例如 sql.NullString，会使用它的 Value 方法
+func sortValue(v reflect.Value) (any, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return sortValue(v.Elem())
+	}
+	if vr, ok := v.Interface().(driver.Valuer); ok {
+		val, err := vr.Value()
+		if err != nil || val == nil {
+			return nil, err
+		}
+		return sortValue(reflect.ValueOf(val))
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	}
+	return v.Interface(), nil
+}
+
+func compareOrdered[V int64 | uint64 | float64 | int](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package sharding
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Mod 按照分片键取余，分片键必须是整数
+// 第 i 张表放在第 i % DBCount 个库里面，例如
+//
+//	Mod{Key: "UserId", DBPattern: "order_db_%d", TablePattern: "order_%02d", DBCount: 4, TableCount: 64}
+//
+// UserId 为 69 的数据在 order_db_1 的 order_05 表里面
+type Mod struct {
+	Key          string
+	DBPattern    string
+	TablePattern string
+	DBCount      int
+	TableCount   int
+}
+
+var _ Algorithm = Mod{}
+
+func (m Mod) ShardingKey() string {
+	return m.Key
+}
+
+// Validate DBCount 和 TableCount 必须大于 0，否则取余的时候会 panic
+func (m Mod) Validate() error {
+	if m.DBCount <= 0 || m.TableCount <= 0 {
+		return errs.NewErrInvalidShardingConfig("Mod", "DBCount 和 TableCount 必须大于 0")
+	}
+	return nil
+}
+
+func (m Mod) Sharding(val any) (Dst, error) {
+	if err := m.Validate(); err != nil {
+		return Dst{}, err
+	}
+	v, ok := toUint64(val)
+	if !ok {
+		return Dst{}, errs.NewErrInvalidShardingValue(m.Key, val)
+	}
+	return m.dst(int(v % uint64(m.TableCount))), nil
+}
+
+// Broadcast 配置不合法的时候返回 nil，model.WithSharding 已经校验过了
+func (m Mod) Broadcast() []Dst {
+	if m.Validate() != nil {
+		return nil
+	}
+	res := make([]Dst, 0, m.TableCount)
+	for i := 0; i < m.TableCount; i++ {
+		res = append(res, m.dst(i))
+	}
+	return res
+}
+
+func (m Mod) dst(tableIdx int) Dst {
+	return Dst{
+		DB:    fmt.Sprintf(m.DBPattern, tableIdx%m.DBCount),
+		Table: fmt.Sprintf(m.TablePattern, tableIdx),
+	}
+}
+
+// Hash 先计算分片键的 FNV-1a 哈希，再按照 Mod 的规则分片
+// 适用于字符串之类的分片键
+type Hash Mod
+
+var _ Algorithm = Hash{}
+
+func (h Hash) ShardingKey() string {
+	return h.Key
+}
+
+// Validate 和 Mod 一样，DBCount 和 TableCount 必须大于 0
+func (h Hash) Validate() error {
+	if h.DBCount <= 0 || h.TableCount <= 0 {
+		return errs.NewErrInvalidShardingConfig("Hash", "DBCount 和 TableCount 必须大于 0")
+	}
+	return nil
+}
+
+func (h Hash) Sharding(val any) (Dst, error) {
+	if err := h.Validate(); err != nil {
+		return Dst{}, err
+	}
+	hs := fnv.New64a()
+	_, _ = fmt.Fprint(hs, val)
+	return Mod(h).dst(int(hs.Sum64() % uint64(h.TableCount))), nil
+}
+
+func (h Hash) Broadcast() []Dst {
+	return Mod(h).Broadcast()
+}
+
+func toUint64(val any) (uint64, bool) {
+	switch v := val.(type) {
+	case int:
+		return uint64(v), v >= 0
+	case int8:
+		return uint64(v), v >= 0
+	case int16:
+		return uint64(v), v >= 0
+	case int32:
+		return uint64(v), v >= 0
+	case int64:
+		return uint64(v), v >= 0
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
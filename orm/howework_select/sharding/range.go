@@ -0,0 +1,57 @@
+package sharding
+
+import (
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Range 按照分片键的范围分片，分片键必须是整数
+// 例如按照创建时间的月份分表
+type Range struct {
+	Key string
+	// Ranges 必须按照 Upper 从小到大排列
+	Ranges []RangeShard
+}
+
+// RangeShard 分片键小于 Upper，并且不小于上一个 Upper 的数据都在 Dst 里
+type RangeShard struct {
+	Upper uint64
+	Dst   Dst
+}
+
+var _ Algorithm = Range{}
+
+func (r Range) ShardingKey() string {
+	return r.Key
+}
+
+// Validate Ranges 不能为空
+func (r Range) Validate() error {
+	if len(r.Ranges) == 0 {
+		return errs.NewErrInvalidShardingConfig("Range", "Ranges 不能为空")
+	}
+	return nil
+}
+
+func (r Range) Sharding(val any) (Dst, error) {
+	if err := r.Validate(); err != nil {
+		return Dst{}, err
+	}
+	v, ok := toUint64(val)
+	if !ok {
+		return Dst{}, errs.NewErrInvalidShardingValue(r.Key, val)
+	}
+	for _, rs := range r.Ranges {
+		if v < rs.Upper {
+			return rs.Dst, nil
+		}
+	}
+	return Dst{}, errs.NewErrInvalidShardingValue(r.Key, val)
+}
+
+func (r Range) Broadcast() []Dst {
+	res := make([]Dst, 0, len(r.Ranges))
+	for _, rs := range r.Ranges {
+		res = append(res, rs.Dst)
+	}
+	return res
+}
@@ -0,0 +1,27 @@
+// Package sharding 定义了分库分表的算法
+// 模型通过 model.WithSharding 声明自己的分片算法，
+// 之后 Selector 和 Deleter 会根据 WHERE 里面分片键的条件决定发送到哪些库哪些表
+package sharding
+
+// Dst 分片的目标
+type Dst struct {
+	// DB 数据源的名字，对应 orm.DBWithDataSources 里面的 key
+	DB string
+	// Table 表名
+	Table string
+}
+
+// Algorithm 分片算法
+type Algorithm interface {
+	// ShardingKey 分片键，是 Go 的字段名
+	ShardingKey() string
+	// Sharding 根据分片键的值计算目标
+	Sharding(val any) (Dst, error)
+	// Broadcast 返回全部的目标，用于无法确定分片的查询
+	Broadcast() []Dst
+}
+
+// Validator 可以由 Algorithm 实现，model.WithSharding 注册的时候会校验配置
+type Validator interface {
+	Validate() error
+}
@@ -0,0 +1,159 @@
+package sharding
+
+import (
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMod_Sharding(t *testing.T) {
+	alg := Mod{Key: "UserId", DBPattern: "order_db_%d", TablePattern: "order_%02d", DBCount: 4, TableCount: 64}
+	testCases := []struct {
+		name    string
+		val     any
+		wantDst Dst
+		wantErr error
+	}{
+		{
+			name:    "int",
+			val:     69,
+			wantDst: Dst{DB: "order_db_1", Table: "order_05"},
+		},
+		{
+			name:    "uint8",
+			val:     uint8(63),
+			wantDst: Dst{DB: "order_db_3", Table: "order_63"},
+		},
+		{
+			name:    "negative",
+			val:     -1,
+			wantErr: errs.NewErrInvalidShardingValue("UserId", -1),
+		},
+		{
+			name:    "string",
+			val:     "69",
+			wantErr: errs.NewErrInvalidShardingValue("UserId", "69"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst, err := alg.Sharding(tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantDst, dst)
+		})
+	}
+}
+
+func TestMod_Broadcast(t *testing.T) {
+	alg := Mod{Key: "UserId", DBPattern: "order_db_%d", TablePattern: "order_%02d", DBCount: 2, TableCount: 4}
+	assert.Equal(t, []Dst{
+		{DB: "order_db_0", Table: "order_00"},
+		{DB: "order_db_1", Table: "order_01"},
+		{DB: "order_db_0", Table: "order_02"},
+		{DB: "order_db_1", Table: "order_03"},
+	}, alg.Broadcast())
+}
+
+func TestHash_Sharding(t *testing.T) {
+	alg := Hash{Key: "OrderSn", DBPattern: "order_db_%d", TablePattern: "order_%02d", DBCount: 4, TableCount: 64}
+	dst, err := alg.Sharding("sn-123")
+	assert.NoError(t, err)
+	// 同样的值总是落在同一个分片
+	again, err := alg.Sharding("sn-123")
+	assert.NoError(t, err)
+	assert.Equal(t, dst, again)
+	assert.Contains(t, alg.Broadcast(), dst)
+}
+
+func TestRange_Sharding(t *testing.T) {
+	alg := Range{
+		Key: "CreateTime",
+		Ranges: []RangeShard{
+			{Upper: 100, Dst: Dst{DB: "db_0", Table: "order_0"}},
+			{Upper: 200, Dst: Dst{DB: "db_1", Table: "order_1"}},
+		},
+	}
+	testCases := []struct {
+		name    string
+		val     any
+		wantDst Dst
+		wantErr error
+	}{
+		{
+			name:    "first",
+			val:     int64(0),
+			wantDst: Dst{DB: "db_0", Table: "order_0"},
+		},
+		{
+			name:    "boundary",
+			val:     100,
+			wantDst: Dst{DB: "db_1", Table: "order_1"},
+		},
+		{
+			name:    "out of range",
+			val:     200,
+			wantErr: errs.NewErrInvalidShardingValue("CreateTime", 200),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst, err := alg.Sharding(tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantDst, dst)
+		})
+	}
+	assert.Equal(t, []Dst{{DB: "db_0", Table: "order_0"}, {DB: "db_1", Table: "order_1"}}, alg.Broadcast())
+}
+
+func TestAlgorithm_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		alg     Algorithm
+		wantErr error
+	}{
+		{
+			name: "mod",
+			alg:  Mod{Key: "UserId", DBCount: 2, TableCount: 4},
+		},
+		{
+			// 零值取余会 panic
+			name:    "zero mod",
+			alg:     Mod{},
+			wantErr: errs.NewErrInvalidShardingConfig("Mod", "DBCount 和 TableCount 必须大于 0"),
+		},
+		{
+			name:    "zero table count",
+			alg:     Mod{Key: "UserId", DBCount: 2},
+			wantErr: errs.NewErrInvalidShardingConfig("Mod", "DBCount 和 TableCount 必须大于 0"),
+		},
+		{
+			name:    "zero hash",
+			alg:     Hash{},
+			wantErr: errs.NewErrInvalidShardingConfig("Hash", "DBCount 和 TableCount 必须大于 0"),
+		},
+		{
+			name:    "empty range",
+			alg:     Range{Key: "CreateTime"},
+			wantErr: errs.NewErrInvalidShardingConfig("Range", "Ranges 不能为空"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantErr, tc.alg.(Validator).Validate())
+			if tc.wantErr == nil {
+				return
+			}
+			// 不会 panic
+			_, err := tc.alg.Sharding(1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Empty(t, tc.alg.Broadcast())
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/model"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ShardingOrder struct {
+	Id     int64
+	UserId int64
+	Amount *sql.NullInt64
+}
+
+var orderSharding = sharding.Mod{
+	Key:          "UserId",
+	DBPattern:    "order_db_%d",
+	TablePattern: "order_%02d",
+	DBCount:      2,
+	TableCount:   4,
+}
+
+// newShardingDB 返回一个 ShardingOrder 分成两个库四张表的 DB
+func newShardingDB(t *testing.T, opts ...DBOption) (*DB, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	master, _ := newMock(t)
+	ds0, mock0 := newMock(t)
+	ds1, mock1 := newMock(t)
+	opts = append(opts, DBWithDataSources(map[string]*sql.DB{
+		"order_db_0": ds0,
+		"order_db_1": ds1,
+	}))
+	db, err := OpenDB(master, opts...)
+	require.NoError(t, err)
+	_, err = db.r.Register(&ShardingOrder{}, model.WithSharding(orderSharding))
+	require.NoError(t, err)
+	return db, mock0, mock1
+}
+
+func TestDB_findDsts(t *testing.T) {
+	db, _, _ := newShardingDB(t)
+	strictDB, _, _ := newShardingDB(t, DBWithStrictSharding())
+	testCases := []struct {
+		name     string
+		db       *DB
+		where    []Predicate
+		wantDsts []sharding.Dst
+		wantErr  error
+	}{
+		{
+			name:     "no where",
+			db:       db,
+			wantDsts: orderSharding.Broadcast(),
+		},
+		{
+			name:     "eq",
+			db:       db,
+			where:    []Predicate{C("UserId").EQ(5)},
+			wantDsts: []sharding.Dst{{DB: "order_db_1", Table: "order_01"}},
+		},
+		{
+			name:     "typed eq",
+			db:       db,
+			where:    []Predicate{TC[int64]("UserId").EQ(6)},
+			wantDsts: []sharding.Dst{{DB: "order_db_0", Table: "order_02"}},
+		},
+		{
+			name:  "in",
+			db:    db,
+			where: []Predicate{C("UserId").In(1, 2, 5)},
+			wantDsts: []sharding.Dst{
+				{DB: "order_db_1", Table: "order_01"},
+				{DB: "order_db_0", Table: "order_02"},
+			},
+		},
+		{
+			name:     "empty in",
+			db:       db,
+			where:    []Predicate{C("UserId").In()},
+			wantDsts: []sharding.Dst{},
+		},
+		{
+			name:     "and other column",
+			db:       db,
+			where:    []Predicate{C("Id").GT(3), C("UserId").EQ(1)},
+			wantDsts: []sharding.Dst{{DB: "order_db_1", Table: "order_01"}},
+		},
+		{
+			name:     "and intersect",
+			db:       db,
+			where:    []Predicate{C("UserId").In(1, 2), C("UserId").EQ(2)},
+			wantDsts: []sharding.Dst{{DB: "order_db_0", Table: "order_02"}},
+		},
+		{
+			name:     "and no intersect",
+			db:       db,
+			where:    []Predicate{C("UserId").EQ(1), C("UserId").EQ(2)},
+			wantDsts: []sharding.Dst{},
+		},
+		{
+			name:  "or",
+			db:    db,
+			where: []Predicate{C("UserId").EQ(1).Or(C("UserId").EQ(3))},
+			wantDsts: []sharding.Dst{
+				{DB: "order_db_1", Table: "order_01"},
+				{DB: "order_db_1", Table: "order_03"},
+			},
+		},
+		{
+			name:     "or other column",
+			db:       db,
+			where:    []Predicate{C("UserId").EQ(1).Or(C("Id").EQ(3))},
+			wantDsts: orderSharding.Broadcast(),
+		},
+		{
+			name:     "not",
+			db:       db,
+			where:    []Predicate{Not(C("UserId").EQ(1))},
+			wantDsts: orderSharding.Broadcast(),
+		},
+		{
+			name:     "range",
+			db:       db,
+			where:    []Predicate{C("UserId").GT(1)},
+			wantDsts: orderSharding.Broadcast(),
+		},
+		{
+			name:    "invalid value",
+			db:      db,
+			where:   []Predicate{C("UserId").EQ("abc")},
+			wantErr: errs.NewErrInvalidShardingValue("UserId", "abc"),
+		},
+		{
+			name:     "strict eq",
+			db:       strictDB,
+			where:    []Predicate{C("UserId").EQ(5)},
+			wantDsts: []sharding.Dst{{DB: "order_db_1", Table: "order_01"}},
+		},
+		{
+			name:    "strict scatter",
+			db:      strictDB,
+			where:   []Predicate{C("Id").EQ(5)},
+			wantErr: errs.ErrShardingScatter,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := tc.db.r.Get(&ShardingOrder{})
+			require.NoError(t, err)
+			dsts, err := tc.db.findDsts(m, tc.where)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantDsts, dsts)
+		})
+	}
+}
+
+func TestSelector_GetMultiSharding(t *testing.T) {
+	testCases := []struct {
+		name    string
+		s       func(db *DB) *Selector[ShardingOrder]
+		mock    func(mock0, mock1 sqlmock.Sqlmock)
+		wantRes []*ShardingOrder
+		wantErr error
+	}{
+		{
+			name: "single shard",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").EQ(5)).Limit(10).Offset(20)
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "amount"}).AddRow(1, 5, 100)
+				mock1.ExpectQuery("SELECT \\* FROM `order_01` WHERE `user_id` = \\? LIMIT \\? OFFSET \\?;").
+					WithArgs(5, 10, 20).WillReturnRows(rows)
+			},
+			wantRes: []*ShardingOrder{
+				{Id: 1, UserId: 5, Amount: &sql.NullInt64{Int64: 100, Valid: true}},
+			},
+		},
+		{
+			name: "merge order by limit",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).
+					OrderBy(Desc("Amount"), Asc("Id")).Limit(2).Offset(1)
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				rows1 := sqlmock.NewRows([]string{"id", "user_id", "amount"}).
+					AddRow(1, 1, 300).AddRow(3, 1, 100).AddRow(4, 1, nil)
+				mock1.ExpectQuery("SELECT \\* FROM `order_01` WHERE `user_id` IN \\(\\?,\\?\\) ORDER BY `amount` DESC,`id` ASC LIMIT \\?;").
+					WithArgs(1, 2, 3).WillReturnRows(rows1)
+				rows0 := sqlmock.NewRows([]string{"id", "user_id", "amount"}).
+					AddRow(2, 2, 200).AddRow(5, 2, 100)
+				mock0.ExpectQuery("SELECT \\* FROM `order_02` WHERE `user_id` IN \\(\\?,\\?\\) ORDER BY `amount` DESC,`id` ASC LIMIT \\?;").
+					WithArgs(1, 2, 3).WillReturnRows(rows0)
+			},
+			wantRes: []*ShardingOrder{
+				{Id: 2, UserId: 2, Amount: &sql.NullInt64{Int64: 200, Valid: true}},
+				{Id: 3, UserId: 1, Amount: &sql.NullInt64{Int64: 100, Valid: true}},
+			},
+		},
+		{
+			name: "merge nulls last",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).
+					OrderBy(Asc("Amount").NullsLast())
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectQuery("SELECT \\* FROM `order_01` WHERE `user_id` IN \\(\\?,\\?\\) ORDER BY `amount` IS NULL ASC,`amount` ASC;").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}).
+						AddRow(1, 1, 300).AddRow(3, 1, nil))
+				mock0.ExpectQuery("SELECT \\* FROM `order_02`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}).
+						AddRow(2, 2, 200))
+			},
+			wantRes: []*ShardingOrder{
+				{Id: 2, UserId: 2, Amount: &sql.NullInt64{Int64: 200, Valid: true}},
+				{Id: 1, UserId: 1, Amount: &sql.NullInt64{Int64: 300, Valid: true}},
+				{Id: 3, UserId: 1},
+			},
+		},
+		{
+			name: "merge order by expression",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).
+					OrderBy(AscOf(Raw("RAND()")))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectQuery("SELECT .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}).AddRow(1, 1, 300))
+				mock0.ExpectQuery("SELECT .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}).AddRow(2, 2, 200))
+			},
+			wantErr: errs.NewErrUnsupportedExpressionType(Raw("RAND()")),
+		},
+		{
+			name: "offset out of range",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).Offset(5)
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectQuery("SELECT \\* FROM `order_01`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}).AddRow(1, 1, 300))
+				mock0.ExpectQuery("SELECT \\* FROM `order_02`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}))
+			},
+			wantRes: []*ShardingOrder{},
+		},
+		{
+			name: "query error",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+				mock0.ExpectQuery("SELECT .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount"}))
+			},
+			wantErr: errors.New("query error"),
+		},
+		{
+			name: "aggregate",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Select(Count("Id"))
+			},
+			mock:    func(mock0, mock1 sqlmock.Sqlmock) {},
+			wantErr: errs.ErrShardingAggregate,
+		},
+		{
+			// 合并的时候读不到没有查询的列，排序的结果是错的
+			name: "order by not selected",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Select(C("Id")).
+					Where(C("UserId").In(1, 2)).OrderBy(Desc("Amount"))
+			},
+			mock:    func(mock0, mock1 sqlmock.Sqlmock) {},
+			wantErr: errs.NewErrShardingOrderBy("Amount"),
+		},
+		{
+			name: "order by selected",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Select(C("Id"), C("Amount")).
+					Where(C("UserId").In(1, 2)).OrderBy(Desc("Amount"))
+			},
+			mock: func(mock0, mock1 sqlmock.Sqlmock) {
+				mock1.ExpectQuery("SELECT `id`,`amount` FROM `order_01`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(1, 100))
+				mock0.ExpectQuery("SELECT `id`,`amount` FROM `order_02`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, 200))
+			},
+			wantRes: []*ShardingOrder{
+				{Id: 2, Amount: &sql.NullInt64{Int64: 200, Valid: true}},
+				{Id: 1, Amount: &sql.NullInt64{Int64: 100, Valid: true}},
+			},
+		},
+		{
+			name: "no shard",
+			s: func(db *DB) *Selector[ShardingOrder] {
+				return NewSelector[ShardingOrder](db).Where(C("UserId").EQ(1), C("UserId").EQ(2))
+			},
+			mock:    func(mock0, mock1 sqlmock.Sqlmock) {},
+			wantRes: []*ShardingOrder{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock0, mock1 := newShardingDB(t)
+			tc.mock(mock0, mock1)
+			res, err := tc.s(db).GetMulti(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+			assert.NoError(t, mock0.ExpectationsWereMet())
+			assert.NoError(t, mock1.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSelector_GetMultiShardingInTx(t *testing.T) {
+	master, mock := newMock(t)
+	mock.ExpectBegin()
+	db, err := OpenDB(master)
+	require.NoError(t, err)
+	_, err = db.r.Register(&ShardingOrder{}, model.WithSharding(orderSharding))
+	require.NoError(t, err)
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = NewSelector[ShardingOrder](tx).Where(C("UserId").EQ(1)).GetMulti(context.Background())
+	assert.Equal(t, errs.ErrShardingInTx, err)
+	_, err = NewDeleter[ShardingOrder](tx).Where(C("UserId").EQ(1)).Exec(context.Background())
+	assert.Equal(t, errs.ErrShardingInTx, err)
+}
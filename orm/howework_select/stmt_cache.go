@@ -0,0 +1,225 @@
+package orm
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// DBWithStmtCache 开启预编译语句的缓存，最多缓存 capacity 条语句
+// 主库、从库和分库分表的数据源各自缓存自己的语句
+// sql.Stmt 会在每个连接上按需重新预编译，所以不需要关心语句具体在哪个连接上
+// capacity 小于等于 0 的时候不开启缓存，和没有使用这个选项一样，方便直接从配置里面读取
+func DBWithStmtCache(capacity int) DBOption {
+	return func(db *DB) {
+		if capacity <= 0 {
+			db.stmts = nil
+			return
+		}
+		db.stmts = newStmtCache(capacity)
+	}
+}
+
+type noStmtCacheKey struct{}
+
+// NoStmtCache 标记 ctx，使用这个 ctx 的查询不使用预编译语句的缓存
+// 一般用于只会执行一次的语句，避免把常用的语句挤出缓存
+func NoStmtCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStmtCacheKey{}, true)
+}
+
+func isNoStmtCache(ctx context.Context) bool {
+	val, _ := ctx.Value(noStmtCacheKey{}).(bool)
+	return val
+}
+
+// StmtCacheStats 预编译语句缓存的统计数据
+type StmtCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions 因为超过容量被关闭的语句数量
+	Evictions uint64
+	// Size 当前缓存的语句数量
+	Size int
+}
+
+// HitRatio 命中率，没有任何查询的时候返回 0
+func (s StmtCacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// StmtCacheStats 返回预编译语句缓存的统计数据，没有开启缓存的时候返回零值
+func (db *DB) StmtCacheStats() StmtCacheStats {
+	if db.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return db.stmts.stats()
+}
+
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+// stmtEntry 记录语句正在被多少个查询使用
+// 被淘汰的时候如果还有查询在使用，就等到最后一个查询结束再关闭
+type stmtEntry struct {
+	key     stmtKey
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// stmtCache 是按照 LRU 淘汰的预编译语句缓存
+type stmtCache struct {
+	mutex    sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[stmtKey]*list.Element
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newStmtCache(capacity int) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[stmtKey]*list.Element, capacity),
+	}
+}
+
+// lookup 只查找已经缓存的语句，不会预编译，找到的时候必须调用 release
+func (c *stmtCache) lookup(db *sql.DB, query string) (*sql.Stmt, func(), bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ele, ok := c.items[stmtKey{db: db, query: query}]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, nil, false
+	}
+	c.ll.MoveToFront(ele)
+	en := ele.Value.(*stmtEntry)
+	en.refs++
+	atomic.AddUint64(&c.hits, 1)
+	return en.stmt, c.releaseFunc(en), true
+}
+
+// get 返回 db 上 query 对应的预编译语句，没有的话预编译一个，用完之后必须调用 release
+func (c *stmtCache) get(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, func(), error) {
+	if stmt, release, ok := c.lookup(db, query); ok {
+		return stmt, release, nil
+	}
+	key := stmtKey{db: db, query: query}
+
+	// 预编译需要和数据库交互，不能持有锁
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if ele, ok := c.items[key]; ok {
+		// 别的查询已经预编译好了，用它的
+		_ = stmt.Close()
+		c.ll.MoveToFront(ele)
+		en := ele.Value.(*stmtEntry)
+		en.refs++
+		return en.stmt, c.releaseFunc(en), nil
+	}
+	en := &stmtEntry{key: key, stmt: stmt, refs: 1}
+	c.items[key] = c.ll.PushFront(en)
+	for c.ll.Len() > c.capacity {
+		c.evict(c.ll.Back())
+	}
+	return stmt, c.releaseFunc(en), nil
+}
+
+func (c *stmtCache) releaseFunc(en *stmtEntry) func() {
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		en.refs--
+		if en.evicted && en.refs == 0 {
+			_ = en.stmt.Close()
+		}
+	}
+}
+
+func (c *stmtCache) evict(ele *list.Element) {
+	en := ele.Value.(*stmtEntry)
+	c.ll.Remove(ele)
+	delete(c.items, en.key)
+	en.evicted = true
+	c.evictions++
+	if en.refs == 0 {
+		_ = en.stmt.Close()
+	}
+}
+
+func (c *stmtCache) stats() StmtCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return StmtCacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: c.evictions,
+		Size:      c.ll.Len(),
+	}
+}
+
+// close 关闭所有缓存的语句
+func (c *stmtCache) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.ll.Len() > 0 {
+		c.evict(c.ll.Back())
+	}
+}
+
+// queryContext 在 db 上执行查询，开启了缓存的时候使用预编译语句
+// 主库、从库和分片上的查询都经过这里，所以驱动错误也在这里归类
+func (c *stmtCache) queryContext(ctx context.Context, db *sql.DB, query string, args ...any) (*sql.Rows, error) {
+	rows, err := c.query(ctx, db, query, args...)
+	return rows, errs.WrapDriverError(err)
+}
+
+func (c *stmtCache) query(ctx context.Context, db *sql.DB, query string, args ...any) (*sql.Rows, error) {
+	if c == nil || isNoStmtCache(ctx) {
+		return db.QueryContext(ctx, query, args...)
+	}
+	stmt, release, err := c.get(ctx, db, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return stmt.QueryContext(ctx, args...)
+}
+
+// execContext 在 db 上执行写操作，开启了缓存的时候使用预编译语句
+func (c *stmtCache) execContext(ctx context.Context, db *sql.DB, query string, args ...any) (sql.Result, error) {
+	res, err := c.exec(ctx, db, query, args...)
+	return res, errs.WrapDriverError(err)
+}
+
+func (c *stmtCache) exec(ctx context.Context, db *sql.DB, query string, args ...any) (sql.Result, error) {
+	if c == nil || isNoStmtCache(ctx) {
+		return db.ExecContext(ctx, query, args...)
+	}
+	stmt, release, err := c.get(ctx, db, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return stmt.ExecContext(ctx, args...)
+}
@@ -0,0 +1,106 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBWithStmtCache(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB, DBWithStmtCache(1))
+	require.NoError(t, err)
+
+	prep := mock.ExpectPrepare("SELECT \\* FROM `test_model` WHERE `id` = \\?;")
+	prep.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	prep.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	// 超过容量，第一条语句被关闭
+	prep.WillBeClosed()
+	mock.ExpectPrepare("DELETE FROM `test_model`;").
+		ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	// 不使用缓存
+	mock.ExpectQuery("SELECT \\* FROM `test_model`;").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	for _, id := range []int{1, 2} {
+		res, err := NewSelector[TestModel](db).Where(C("Id").EQ(id)).GetMulti(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestModel{{Id: int64(id)}}, res)
+	}
+	_, err = NewDeleter[TestModel](db).Exec(ctx)
+	require.NoError(t, err)
+	_, err = NewSelector[TestModel](db).GetMulti(NoStmtCache(ctx))
+	require.NoError(t, err)
+
+	stats := db.StmtCacheStats()
+	assert.Equal(t, StmtCacheStats{Hits: 1, Misses: 2, Evictions: 1, Size: 1}, stats)
+	assert.InDelta(t, 1.0/3, stats.HitRatio(), 1e-9)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBWithStmtCache_disabled(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		mockDB, mock := newMock(t)
+		db, err := OpenDB(mockDB, DBWithStmtCache(capacity))
+		require.NoError(t, err)
+		// 没有 Prepare，直接执行
+		mock.ExpectQuery("SELECT \\* FROM `test_model`;").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("DELETE FROM `test_model`;").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err = NewSelector[TestModel](db).GetMulti(context.Background())
+		require.NoError(t, err)
+		_, err = NewDeleter[TestModel](db).Exec(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, StmtCacheStats{}, db.StmtCacheStats())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestDBWithStmtCache_prepareError(t *testing.T) {
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB, DBWithStmtCache(8))
+	require.NoError(t, err)
+	mock.ExpectPrepare("SELECT .*").WillReturnError(errors.New("prepare error"))
+
+	_, err = NewSelector[TestModel](db).GetMulti(context.Background())
+	assert.Equal(t, errors.New("prepare error"), err)
+	assert.Equal(t, StmtCacheStats{Misses: 1}, db.StmtCacheStats())
+}
+
+func TestTx_stmtCache(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock := newMock(t)
+	db, err := OpenDB(mockDB, DBWithStmtCache(8))
+	require.NoError(t, err)
+
+	mock.ExpectPrepare("SELECT \\* FROM `test_model`;").
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectBegin()
+	// 已经缓存的语句绑定到事务上，这个连接上已经预编译过，不会再预编译
+	mock.ExpectQuery("SELECT \\* FROM `test_model`;").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	// 没有缓存的语句直接执行
+	mock.ExpectExec("DELETE FROM `test_model`;").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err = NewSelector[TestModel](db).GetMulti(ctx)
+	require.NoError(t, err)
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	res, err := NewSelector[TestModel](tx).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 2}}, res)
+	_, err = NewDeleter[TestModel](tx).Exec(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, StmtCacheStats{Hits: 1, Misses: 2, Size: 1}, db.StmtCacheStats())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStmtCacheStats_HitRatio(t *testing.T) {
+	assert.Equal(t, float64(0), StmtCacheStats{}.HitRatio())
+	assert.Equal(t, 0.75, StmtCacheStats{Hits: 3, Misses: 1}.HitRatio())
+}
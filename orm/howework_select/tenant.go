@@ -0,0 +1,68 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+type tenantKey struct{}
+
+// WithTenant 返回带有租户 ID 的 ctx
+// 多租户的模型，也就是有字段打上了 orm:"tenant=true" 标签的模型，
+// 使用这个 ctx 执行 Selector, Deleter 和 UpdateEntity 的时候，WHERE 里面会自动加上 tenant_id = ?
+// InsertEntity 会把租户 ID 写入租户字段
+func WithTenant(ctx context.Context, tenantID any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom 返回 ctx 里面的租户 ID
+func TenantFrom(ctx context.Context) (any, bool) {
+	val := ctx.Value(tenantKey{})
+	return val, val != nil
+}
+
+// tenantArg 是租户 ID 的占位符，构造 SQL 的时候还不知道租户是谁，执行的时候再从 ctx 里面取
+// 这样 PreparedSelector 和分库分表重新构造的 SQL 也能正确处理
+type tenantArg struct{}
+
+func (tenantArg) expr() {}
+
+// Value 没有经过 bindTenant 就直接发送给驱动的时候返回错误，避免查询到所有租户的数据
+func (tenantArg) Value() (driver.Value, error) {
+	return nil, errs.ErrMissingTenant
+}
+
+// tenantWhere 多租户的模型在 where 后面加上租户条件
+func (b *builder) tenantWhere(where []Predicate) []Predicate {
+	if b.model.TenantField == nil {
+		return where
+	}
+	res := make([]Predicate, 0, len(where)+1)
+	res = append(res, where...)
+	return append(res, C(b.model.TenantField.GoName).EQ(tenantArg{}))
+}
+
+// bindTenant 用 ctx 里面的租户 ID 替换 tenantArg，ctx 里面没有租户的时候返回 ErrMissingTenant
+func bindTenant(ctx context.Context, q *Query) (*Query, error) {
+	var args []any
+	for i, arg := range q.Args {
+		if _, ok := arg.(tenantArg); !ok {
+			continue
+		}
+		tenantID, ok := TenantFrom(ctx)
+		if !ok {
+			return nil, errs.ErrMissingTenant
+		}
+		if args == nil {
+			args = make([]any, len(q.Args))
+			copy(args, q.Args)
+		}
+		args[i] = tenantID
+	}
+	if args == nil {
+		return q, nil
+	}
+	return &Query{SQL: q.SQL, Args: args}, nil
+}
@@ -0,0 +1,178 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TenantOrder struct {
+	Id       int64
+	TenantId int64 `orm:"tenant=true"`
+	Amount   int64
+}
+
+func TestTenant_Build(t *testing.T) {
+	mockDB, _ := newMock(t)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+	}{
+		{
+			name: "select",
+			q:    NewSelector[TenantOrder](db),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `tenant_order` WHERE `tenant_id` = ?;",
+				Args: []any{tenantArg{}},
+			},
+		},
+		{
+			name: "select where",
+			q:    NewSelector[TenantOrder](db).Where(C("Amount").GT(10).Or(C("Id").EQ(1))),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `tenant_order` WHERE ((`amount` > ?) OR (`id` = ?)) AND (`tenant_id` = ?);",
+				Args: []any{10, 1, tenantArg{}},
+			},
+		},
+		{
+			name: "delete",
+			q:    NewDeleter[TenantOrder](db).Where(C("Id").EQ(1)),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `tenant_order` WHERE (`id` = ?) AND (`tenant_id` = ?);",
+				Args: []any{1, tenantArg{}},
+			},
+		},
+		{
+			name: "not tenant model",
+			q:    NewSelector[TestModel](db),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model`;",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestTenant_Exec(t *testing.T) {
+	tenantCtx := WithTenant(context.Background(), int64(7))
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		mock    func(mock sqlmock.Sqlmock)
+		do      func(ctx context.Context, db *DB) error
+		wantErr error
+	}{
+		{
+			name: "select",
+			ctx:  tenantCtx,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `tenant_order` WHERE \\(`amount` > \\?\\) AND \\(`tenant_id` = \\?\\);").
+					WithArgs(10, int64(7)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "amount"}).AddRow(1, 7, 20))
+			},
+			do: func(ctx context.Context, db *DB) error {
+				res, err := NewSelector[TenantOrder](db).Where(C("Amount").GT(10)).GetMulti(ctx)
+				if err == nil {
+					assert.Equal(t, []*TenantOrder{{Id: 1, TenantId: 7, Amount: 20}}, res)
+				}
+				return err
+			},
+		},
+		{
+			name: "select without tenant",
+			ctx:  context.Background(),
+			mock: func(mock sqlmock.Sqlmock) {},
+			do: func(ctx context.Context, db *DB) error {
+				_, err := NewSelector[TenantOrder](db).GetMulti(ctx)
+				return err
+			},
+			wantErr: errs.ErrMissingTenant,
+		},
+		{
+			name: "delete",
+			ctx:  tenantCtx,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM `tenant_order` WHERE \\(`id` = \\?\\) AND \\(`tenant_id` = \\?\\);").
+					WithArgs(1, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			do: func(ctx context.Context, db *DB) error {
+				_, err := NewDeleter[TenantOrder](db).Where(C("Id").EQ(1)).Exec(ctx)
+				return err
+			},
+		},
+		{
+			name: "delete without tenant",
+			ctx:  context.Background(),
+			mock: func(mock sqlmock.Sqlmock) {},
+			do: func(ctx context.Context, db *DB) error {
+				_, err := NewDeleter[TenantOrder](db).Exec(ctx)
+				return err
+			},
+			wantErr: errs.ErrMissingTenant,
+		},
+		{
+			name: "union",
+			ctx:  tenantCtx,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `tenant_order` WHERE \\(`id` = \\?\\) AND \\(`tenant_id` = \\?\\) "+
+					"UNION SELECT \\* FROM `tenant_order` WHERE \\(`id` = \\?\\) AND \\(`tenant_id` = \\?\\);").
+					WithArgs(1, int64(7), 2, int64(7)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "amount"}))
+			},
+			do: func(ctx context.Context, db *DB) error {
+				_, err := NewSelector[TenantOrder](db).Where(C("Id").EQ(1)).
+					Union(NewSelector[TenantOrder](db).Where(C("Id").EQ(2))).GetMulti(ctx)
+				return err
+			},
+		},
+		{
+			name: "prepared",
+			ctx:  tenantCtx,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `tenant_order` WHERE \\(`id` = \\?\\) AND \\(`tenant_id` = \\?\\);").
+					WithArgs(3, int64(7)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "amount"}))
+			},
+			do: func(ctx context.Context, db *DB) error {
+				ps, err := NewSelector[TenantOrder](db).Where(C("Id").EQ(Param("id"))).Prepare()
+				require.NoError(t, err)
+				_, err = ps.GetMulti(ctx, map[string]any{"id": 3})
+				return err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock := newMock(t)
+			db, err := OpenDB(mockDB)
+			require.NoError(t, err)
+			tc.mock(mock)
+			err = tc.do(tc.ctx, db)
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTenantFrom(t *testing.T) {
+	_, ok := TenantFrom(context.Background())
+	assert.False(t, ok)
+	id, ok := TenantFrom(WithTenant(context.Background(), "acme"))
+	assert.True(t, ok)
+	assert.Equal(t, "acme", id)
+}
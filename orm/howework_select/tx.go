@@ -0,0 +1,133 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+)
+
+// Tx 事务，事务里面的读写都会发送到主库
+type Tx struct {
+	core
+	tx *sql.Tx
+
+	// db 是开启事务的主库，stmts 是主库的预编译语句缓存
+	db    *sql.DB
+	stmts *stmtCache
+
+	// onCommit 事务提交成功之后执行，回滚的时候丢弃
+	onCommit []func()
+}
+
+var _ Session = &Tx{}
+
+func (t *Tx) getCore() core {
+	return t.core
+}
+
+// queryContext 开启了预编译语句缓存的时候，如果语句已经缓存了，就把它绑定到事务上执行
+// 事务里面不会预编译新的语句，因为那需要从连接池里面再拿一个连接
+// 事务里面的语句在事务结束的时候由 database/sql 关闭
+func (t *Tx) queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := t.query(ctx, t.dialect.rebind(query), args...)
+	return rows, errs.WrapDriverError(err)
+}
+
+func (t *Tx) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if t.stmts == nil || isNoStmtCache(ctx) {
+		return t.tx.QueryContext(ctx, query, args...)
+	}
+	stmt, release, ok := t.stmts.lookup(t.db, query)
+	if !ok {
+		return t.tx.QueryContext(ctx, query, args...)
+	}
+	defer release()
+	return t.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)
+}
+
+func (t *Tx) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := t.exec(ctx, t.dialect.rebind(query), args...)
+	return res, errs.WrapDriverError(err)
+}
+
+func (t *Tx) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if t.stmts == nil || isNoStmtCache(ctx) {
+		return t.tx.ExecContext(ctx, query, args...)
+	}
+	stmt, release, ok := t.stmts.lookup(t.db, query)
+	if !ok {
+		return t.tx.ExecContext(ctx, query, args...)
+	}
+	defer release()
+	txStmt := t.tx.StmtContext(ctx, stmt)
+	defer func() { _ = txStmt.Close() }()
+	return txStmt.ExecContext(ctx, args...)
+}
+
+// OnCommit 注册一个事务提交成功之后执行的回调，例如让缓存失效
+// 事务回滚或者提交失败的时候不会执行。和 Tx 一样，它不是线程安全的
+func (t *Tx) OnCommit(fn func()) {
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// Commit 提交事务，死锁之类的错误可能在提交的时候才返回
+func (t *Tx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		t.onCommit = nil
+		return errs.WrapDriverError(err)
+	}
+	fns := t.onCommit
+	t.onCommit = nil
+	for _, fn := range fns {
+		fn()
+	}
+	return nil
+}
+
+func (t *Tx) Rollback() error {
+	t.onCommit = nil
+	return errs.WrapDriverError(t.tx.Rollback())
+}
+
+// DoTx 在事务中执行 fn，fn 返回 error 或者 panic 的时候回滚，否则提交
+// 设置了 DBWithRetryPolicy 的时候，死锁之类的错误会重试整个事务，所以 fn 可能会执行多次，
+// 不要在 fn 里面做事务之外的、不能重复的事情，例如发消息
+// 提交的时候出现连接错误不会重试，因为没有办法知道事务到底有没有提交
+func (db *DB) DoTx(ctx context.Context, opts *sql.TxOptions,
+	fn func(ctx context.Context, tx *Tx) error) error {
+	var committing bool
+	p := db.retry
+	p.Retryable = func(err error) bool {
+		if committing && errors.Is(err, ErrConnection) {
+			return false
+		}
+		return db.retry.retryable(err)
+	}
+	return p.Do(ctx, func(ctx context.Context) error {
+		committing = false
+		return db.doTx(ctx, opts, fn, &committing)
+	})
+}
+
+func (db *DB) doTx(ctx context.Context, opts *sql.TxOptions,
+	fn func(ctx context.Context, tx *Tx) error, committing *bool) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	err = fn(ctx, tx)
+	panicked = false
+	if err != nil {
+		return err
+	}
+	*committing = true
+	return tx.Commit()
+}
@@ -0,0 +1,89 @@
+package orm
+
+import "strconv"
+
+// Window 窗口的定义，也就是 OVER 后面括号里面的部分
+type Window struct {
+	partitionBy []Expression
+	orderBys    []OrderBy
+}
+
+// NewWindow 创建一个空窗口，也就是 OVER ()
+func NewWindow() Window {
+	return Window{}
+}
+
+func (w Window) PartitionBy(exprs ...Expression) Window {
+	w.partitionBy = exprs
+	return w
+}
+
+func (w Window) OrderBy(orderBys ...OrderBy) Window {
+	w.orderBys = orderBys
+	return w
+}
+
+// WindowFunc 只能和 OVER 一起使用的函数，例如 ROW_NUMBER()
+type WindowFunc struct {
+	fn   string
+	args []Expression
+}
+
+func RowNumber() WindowFunc {
+	return WindowFunc{fn: "ROW_NUMBER"}
+}
+
+func Rank() WindowFunc {
+	return WindowFunc{fn: "RANK"}
+}
+
+func DenseRank() WindowFunc {
+	return WindowFunc{fn: "DENSE_RANK"}
+}
+
+// Lag 取前面第 offset 行的 c，def 是没有这一行的时候的默认值
+// MySQL 要求 offset 是字面量，所以它不会作为参数传递
+func Lag(c string, offset int, def ...any) WindowFunc {
+	return offsetFunc("LAG", c, offset, def)
+}
+
+// Lead 取后面第 offset 行的 c，def 是没有这一行的时候的默认值
+func Lead(c string, offset int, def ...any) WindowFunc {
+	return offsetFunc("LEAD", c, offset, def)
+}
+
+func offsetFunc(fn string, c string, offset int, def []any) WindowFunc {
+	args := []Expression{C(c), RawExpr{raw: strconv.Itoa(offset)}}
+	if len(def) > 0 {
+		args = append(args, exprOf(def[0]))
+	}
+	return WindowFunc{fn: fn, args: args}
+}
+
+func (f WindowFunc) expr() {}
+
+func (f WindowFunc) Over(w Window) OverExpr {
+	return OverExpr{fn: f, window: w}
+}
+
+// Over 把聚合函数作为窗口函数使用，例如 Sum("Amount").Over(NewWindow().PartitionBy(C("UserId")))
+func (a Aggregate) Over(w Window) OverExpr {
+	return OverExpr{fn: a, window: w}
+}
+
+// OverExpr 代表 fn OVER (...)，它只能出现在 SELECT 和 ORDER BY 里面
+type OverExpr struct {
+	// fn 是 WindowFunc 或者 Aggregate
+	fn     Expression
+	window Window
+	alias  string
+}
+
+func (OverExpr) expr() {}
+
+func (OverExpr) selectable() {}
+
+func (o OverExpr) As(alias string) OverExpr {
+	o.alias = alias
+	return o
+}
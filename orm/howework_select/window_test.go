@@ -0,0 +1,87 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/oreo0725/geektime-go-camp/orm/howework_select/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector_Window(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "empty window",
+			q:    NewSelector[TestModel](db).Select(C("Id"), RowNumber().Over(NewWindow())),
+			wantQuery: &Query{
+				SQL: "SELECT `id`,ROW_NUMBER() OVER () FROM `test_model`;",
+			},
+		},
+		{
+			name: "row number",
+			q: NewSelector[TestModel](db).Select(C("Id"),
+				RowNumber().Over(NewWindow().PartitionBy(C("Age")).OrderBy(Desc("Id"))).As("rn")),
+			wantQuery: &Query{
+				SQL: "SELECT `id`,ROW_NUMBER() OVER (PARTITION BY `age` ORDER BY `id` DESC) AS `rn` FROM `test_model`;",
+			},
+		},
+		{
+			name: "rank order by alias",
+			q: NewSelector[TestModel](db).Select(C("Id"),
+				Rank().Over(NewWindow().OrderBy(Asc("Age"))).As("r"),
+				DenseRank().Over(NewWindow().PartitionBy(C("FirstName"), C("LastName")))).
+				OrderBy(Asc("r")),
+			wantQuery: &Query{
+				SQL: "SELECT `id`,RANK() OVER (ORDER BY `age` ASC) AS `r`," +
+					"DENSE_RANK() OVER (PARTITION BY `first_name`,`last_name`) FROM `test_model` ORDER BY `r` ASC;",
+			},
+		},
+		{
+			name: "lag lead",
+			q: NewSelector[TestModel](db).Where(C("Age").GT(18)).Select(
+				Lag("Age", 1).Over(NewWindow().OrderBy(Asc("Id"))),
+				Lead("Age", 2, 0).Over(NewWindow().OrderBy(Asc("Id")))),
+			wantQuery: &Query{
+				SQL: "SELECT LAG(`age`, 1) OVER (ORDER BY `id` ASC)," +
+					"LEAD(`age`, 2, ?) OVER (ORDER BY `id` ASC) FROM `test_model` WHERE `age` > ?;",
+				Args: []any{0, 18},
+			},
+		},
+		{
+			name: "aggregate over",
+			q: NewSelector[TestModel](db).Select(
+				Sum("Age").Over(NewWindow().PartitionBy(C("FirstName"))).As("total")),
+			wantQuery: &Query{
+				SQL: "SELECT SUM(`age`) OVER (PARTITION BY `first_name`) AS `total` FROM `test_model`;",
+			},
+		},
+		{
+			name: "invalid partition",
+			q: NewSelector[TestModel](db).Select(
+				RowNumber().Over(NewWindow().PartitionBy(C("Invalid")))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+		{
+			name: "invalid order by",
+			q: NewSelector[TestModel](db).Select(
+				RowNumber().Over(NewWindow().OrderBy(Asc("Invalid")))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}